@@ -10,6 +10,7 @@ import (
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
 	"github.com/redis/go-redis/v9"
+	"github.com/rovilay/ecommerce-service/common/events"
 	"github.com/rovilay/ecommerce-service/config"
 	"github.com/rovilay/ecommerce-service/domains/auth"
 	"github.com/rovilay/ecommerce-service/domains/cart/repository"
@@ -69,9 +70,31 @@ func main() {
 		}
 	}()
 
+	// connect to rabbitmq
+	conn, err := events.ConnectRabbit(c.RABBITMQ_URL)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to connect to rabbitMq")
+	}
+
+	rabbitClient, err := events.NewRabbitClient(conn, events.Product)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to create rabbit client")
+	}
+
+	logger.Info().Msg("Connected to rabbit client")
+
+	defer rabbitClient.Close()
+
 	repo := repository.NewPostgresCartRepository(ctx, db, &logger)
 	autService := auth.NewAuthService(cache, c.AuthSecret, time.Hour*10)
-	service := service.NewCartService(repo, autService, &logger)
+	service := service.NewCartService(repo, autService, rabbitClient, &logger)
+
+	// listen for events
+	go func() {
+		if err := service.Listen(ctx, events.Product, "cart.product", events.ProductDeleted); err != nil {
+			logger.Err(err).Msg("failed to listen for product events")
+		}
+	}()
 	app := cartHttp.NewCartApp(service, &c, &logger)
 
 	if err = app.Start(ctx); err != nil {
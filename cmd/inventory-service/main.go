@@ -5,11 +5,14 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"time"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
+	"github.com/redis/go-redis/v9"
 	"github.com/rovilay/ecommerce-service/common/events"
 	"github.com/rovilay/ecommerce-service/config"
+	"github.com/rovilay/ecommerce-service/domains/auth"
 	"github.com/rovilay/ecommerce-service/domains/inventory/repository"
 	"github.com/rovilay/ecommerce-service/domains/inventory/service"
 	inventoryHttp "github.com/rovilay/ecommerce-service/internal/http/chi/inventory"
@@ -40,7 +43,7 @@ func main() {
 	// }
 
 	// load the config
-	c := config.LoadInventoryConfig()
+	c := config.LoadInventoryConfig(&logger)
 
 	// connect to DB
 	db, err := sqlx.Connect("pgx", c.DBURL)
@@ -53,6 +56,20 @@ func main() {
 		}
 	}()
 
+	// connect to redis
+	cache := redis.NewClient(&redis.Options{
+		Addr: c.RedisURL,
+	})
+	err = cache.Ping(ctx).Err()
+	if err != nil {
+		logger.Fatal().Err(err).Msgf("failed to connect to redis: %s", c.RedisURL)
+	}
+	defer func() {
+		if err := cache.Close(); err != nil {
+			logger.Err(err).Msg("failed to close redis")
+		}
+	}()
+
 	// connect to rabbitmq
 	// conn, err := events.ConnectRabbit(c.RABBITMQ_USER, c.RABBITMQ_PASSWORD, c.RABBITMQ_HOST, c.RABBITMQ_PORT)
 	conn, err := events.ConnectRabbit(c.RABBITMQ_URL)
@@ -70,17 +87,35 @@ func main() {
 	defer rabbitClient.Close()
 
 	repo := repository.NewPostgresInventoryRepository(ctx, db, &logger)
-	service, err := service.NewInventoryService(repo, rabbitClient, &logger)
+	service, err := service.NewInventoryService(repo, rabbitClient, c.ReservationTTL, &logger)
 	if err != nil {
 		logger.Fatal().Err(err).Msg("service.NewInventoryService: something went wrong")
 	}
 
 	// listen for events
 	go func() {
-		service.Listen(ctx, events.Product, events.ProductCreated)
+		err := service.Listen(ctx, events.Product, "inventory.product",
+			events.ProductCreated, events.ProductUpdated, events.ProductDeleted,
+		)
+		if err != nil {
+			logger.Err(err).Msg("failed to listen for product events")
+		}
+
+		err = service.Listen(ctx, events.Order, "inventory.order", events.OrderCancelled, events.OrderRefunded)
+		if err != nil {
+			logger.Err(err).Msg("failed to listen for order events")
+		}
 	}()
 
-	app := inventoryHttp.NewInventoryApp(service, &c, &logger)
+	// publish inventory events written to the outbox
+	outboxRelay := events.NewOutboxRelay(events.NewPostgresOutboxStore(db, &logger), rabbitClient, c.OutboxBatchSize, &logger)
+	go outboxRelay.Run(ctx, c.OutboxPollInterval)
+
+	// expire stale stock reservations
+	go service.SweepExpiredReservations(ctx, c.ReservationSweepInterval)
+
+	authService := auth.NewAuthService(cache, c.AuthSecret, time.Hour*10)
+	app := inventoryHttp.NewInventoryApp(service, authService, &c, &logger)
 
 	if err = app.Start(ctx); err != nil {
 		logger.Fatal().Err(err).Msg("failed to start app")
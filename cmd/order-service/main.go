@@ -10,11 +10,18 @@ import (
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
 	"github.com/redis/go-redis/v9"
+	"github.com/rovilay/ecommerce-service/common/events"
+	"github.com/rovilay/ecommerce-service/common/httpclient"
+	"github.com/rovilay/ecommerce-service/common/utils"
 	"github.com/rovilay/ecommerce-service/config"
 	"github.com/rovilay/ecommerce-service/domains/auth"
 	externalservices "github.com/rovilay/ecommerce-service/domains/order/external-services"
 	"github.com/rovilay/ecommerce-service/domains/order/repository"
 	"github.com/rovilay/ecommerce-service/domains/order/service"
+	"github.com/rovilay/ecommerce-service/domains/payment"
+	"github.com/rovilay/ecommerce-service/domains/promotion"
+	"github.com/rovilay/ecommerce-service/domains/shipping"
+	"github.com/rovilay/ecommerce-service/domains/tax"
 	httpOrder "github.com/rovilay/ecommerce-service/internal/http/chi/order"
 	"github.com/rs/zerolog"
 )
@@ -70,14 +77,60 @@ func main() {
 		}
 	}()
 
+	// connect to rabbitmq
+	conn, err := events.ConnectRabbit(c.RABBITMQ_URL)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to connect to rabbitMq")
+	}
+
+	rabbitClient, err := events.NewRabbitClient(conn, events.Order)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to create rabbit client")
+	}
+
+	logger.Info().Msg("Connected to rabbit client")
+
+	defer rabbitClient.Close()
+
+	// publish order lifecycle events written to the outbox
+	outboxRelay := events.NewOutboxRelay(events.NewPostgresOutboxStore(db, &logger), rabbitClient, c.OutboxBatchSize, &logger)
+	go outboxRelay.Run(ctx, c.OutboxPollInterval)
+
 	repo := repository.NewPostgresOrderRepository(ctx, db, &logger)
 	authService := auth.NewAuthService(cache, c.AuthSecret, time.Hour*10)
-	inventoryService := externalservices.NewHTTPInventoryService(c.InventoryHttpBaseURL)
-	prdService := externalservices.NewHTTPProductService(c.ProdHttpBaseURL)
-	cartService := externalservices.NewHTTPCartService(c.CartHttpBaseURL)
-	service := service.NewOrderService(repo, authService, inventoryService, prdService, cartService, &logger)
-	app := httpOrder.NewOrderApp(service, &c, &logger)
+	signer := utils.NewServiceTokenSigner(utils.ServiceOrder, []byte(c.ServiceAuthSecret), c.ServiceTokenTTL)
+	clientConfig := httpclient.Config{
+		Timeout:          c.HTTPClientTimeout,
+		MaxRetries:       c.HTTPClientMaxRetries,
+		RetryBackoff:     c.HTTPClientRetryBackoff,
+		MaxRetryBackoff:  c.HTTPClientMaxRetryBackoff,
+		BreakerThreshold: c.HTTPClientBreakerThreshold,
+		BreakerCooldown:  c.HTTPClientBreakerCooldown,
+	}
+	inventoryService := externalservices.NewHTTPInventoryService(c.InventoryHttpBaseURL, httpclient.New("inventory", clientConfig, &logger), signer)
+	prdService := externalservices.NewHTTPProductService(c.ProdHttpBaseURL, httpclient.New("product", clientConfig, &logger), signer)
+	cartService := externalservices.NewHTTPCartService(c.CartHttpBaseURL, httpclient.New("cart", clientConfig, &logger), signer)
+	promotionService := promotion.NewService(promotion.NewPostgresRepository(ctx, db, logger), &logger)
+	taxRepo := tax.NewPostgresRepository(ctx, db, logger)
+	taxService := tax.NewService(taxRepo, &logger)
+	shippingService := shipping.NewService(shipping.NewPostgresRepository(ctx, db, logger), &logger)
+	paymentService := payment.NewService(payment.NewPostgresRepository(ctx, db, logger), newPaymentGateway(c), c.PaymentWebhookSecret, &logger)
+	service := service.NewOrderService(repo, repo, authService, inventoryService, prdService, cartService, promotionService,
+		tax.NewTableCalculator(taxRepo), shippingService, paymentService, &logger)
+
+	// finish order sagas interrupted by a shutdown of this or another instance
+	go service.ResumeOrderSagas(ctx, c.SagaResumeInterval, c.SagaStaleAfter)
+	// retry payment settlements the gateway did not take when the order status changed
+	go service.SettlePayments(ctx, c.PaymentSettleInterval, c.PaymentSettleRetryAfter)
+
+	app := httpOrder.NewOrderApp(service, promotionService, taxService, shippingService, authService, &c, &logger)
 	if err = app.Start(ctx); err != nil {
 		logger.Fatal().Err(err).Msg("failed to start app")
 	}
 }
+
+// newPaymentGateway returns the gateway PAYMENT_GATEWAY names. Only the in-process fake
+// gateway is available so far.
+func newPaymentGateway(c config.OrderConfig) payment.PaymentGateway {
+	return payment.NewFakeGateway()
+}
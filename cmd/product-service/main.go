@@ -5,11 +5,15 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"time"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
+	"github.com/redis/go-redis/v9"
+	"github.com/rovilay/ecommerce-service/common/blobstore"
 	"github.com/rovilay/ecommerce-service/common/events"
 	"github.com/rovilay/ecommerce-service/config"
+	"github.com/rovilay/ecommerce-service/domains/auth"
 	"github.com/rovilay/ecommerce-service/domains/product"
 	productHttp "github.com/rovilay/ecommerce-service/internal/http/chi/product"
 	"github.com/rs/zerolog"
@@ -39,7 +43,7 @@ func main() {
 	// }
 
 	// load config
-	c := config.LoadProductConfig()
+	c := config.LoadProductConfig(&logger)
 
 	// connect to DB
 	db, err := sqlx.Connect("pgx", c.DBURL)
@@ -52,6 +56,20 @@ func main() {
 		}
 	}()
 
+	// connect to redis
+	cache := redis.NewClient(&redis.Options{
+		Addr: c.RedisURL,
+	})
+	err = cache.Ping(ctx).Err()
+	if err != nil {
+		logger.Fatal().Err(err).Msgf("failed to connect to redis: %s", c.RedisURL)
+	}
+	defer func() {
+		if err := cache.Close(); err != nil {
+			logger.Err(err).Msg("failed to close redis")
+		}
+	}()
+
 	// connect to rabbitmq
 	// conn, err := events.ConnectRabbit(c.RABBITMQ_USER, c.RABBITMQ_PASSWORD, c.RABBITMQ_HOST, c.RABBITMQ_PORT)
 	conn, err := events.ConnectRabbit(c.RABBITMQ_URL)
@@ -68,15 +86,45 @@ func main() {
 
 	defer rabbitClient.Close()
 
+	blobs, err := newBlobStore(c)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to create blob store")
+	}
+
 	postgresRepo := product.NewPostgresRepository(ctx, db, logger)
-	productService, err := product.NewService(postgresRepo, rabbitClient, &logger)
+	productService, err := product.NewService(postgresRepo, blobs, c.MediaBaseURL, &logger)
 	if err != nil {
 		logger.Fatal().Err(err).Msg("product.NewService: something went wrong")
 	}
 
-	app := productHttp.NewProductApp(productService, &c, &logger)
+	// publish product events written to the outbox
+	outboxRelay := events.NewOutboxRelay(events.NewPostgresOutboxStore(db, &logger), rabbitClient, c.OutboxBatchSize, &logger)
+	go outboxRelay.Run(ctx, c.OutboxPollInterval)
+
+	// hard-delete products that have been in the trash for longer than the retention window
+	go productService.PurgeDeletedProducts(ctx, c.PurgeInterval, c.PurgeRetention)
+
+	// activate scheduled prices and sales, and revert sales that ended
+	go productService.ApplyScheduledPrices(ctx, c.PriceScheduleInterval)
+
+	authService := auth.NewAuthService(cache, c.AuthSecret, time.Hour*10)
+	app := productHttp.NewProductApp(productService, authService, &c, &logger)
 
 	if err = app.Start(ctx); err != nil {
 		logger.Fatal().Err(err).Msg("failed to start app")
 	}
 }
+
+func newBlobStore(c config.ProductConfig) (blobstore.BlobStore, error) {
+	if c.BlobStore == "s3" {
+		return blobstore.NewS3Store(blobstore.S3Config{
+			Endpoint:        c.S3Endpoint,
+			Region:          c.S3Region,
+			Bucket:          c.S3Bucket,
+			AccessKeyID:     c.S3AccessKey,
+			SecretAccessKey: c.S3SecretKey,
+		}, nil)
+	}
+
+	return blobstore.NewLocalStore(c.BlobLocalDir)
+}
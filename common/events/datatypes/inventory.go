@@ -0,0 +1,7 @@
+package eventdatatypes
+
+type Inventory struct {
+	ProductID int `json:"product_id"`
+	VariantID int `json:"variant_id"`
+	Quantity  int `json:"quantity"`
+}
@@ -0,0 +1,30 @@
+package eventdatatypes
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rovilay/ecommerce-service/common/money"
+)
+
+type Order struct {
+	ID         int         `json:"id"`
+	UserID     uuid.UUID   `json:"user_id"`
+	Status     string      `json:"status"`
+	TotalPrice money.Money `json:"total_price"`
+	OrderItems []OrderItem `json:"order_items"`
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+}
+
+type OrderItem struct {
+	ID        int         `json:"id"`
+	OrderID   int         `json:"order_id"`
+	ProductID int         `json:"product_id"`
+	VariantID int         `json:"variant_id"`
+	Quantity  int         `json:"quantity"`
+	Price     money.Money `json:"price"`
+
+	ProductName string `json:"product_name"`
+	ProductSKU  string `json:"product_sku"`
+}
@@ -1,16 +1,57 @@
 package eventdatatypes
 
-import "time"
+import (
+	"time"
+
+	"github.com/rovilay/ecommerce-service/common/money"
+)
 
 type Product struct {
-	ID          int       `json:"id"`
-	Name        string    `json:"name" validate:"required"`
-	Description string    `json:"description"`
-	Price       float32   `json:"price" db:"price" validate:"gt=0"`
-	SKU         string    `json:"sku" db:"sku" validate:"required,len=5"`
-	ImageURL    string    `json:"image_url" db:"image_url"`
-	CategoryID  int       `json:"category_id" db:"category_id" validate:"required"`
-	CreatedAt   time.Time `json:"created_at,omitempty" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at,omitempty" db:"updated_at"`
-	DeletedAt   string    `json:"deleted_at,omitempty" db:"deleted_at"`
+	ID          int         `json:"id"`
+	Name        string      `json:"name" validate:"required"`
+	Description string      `json:"description"`
+	Price       money.Money `json:"price" db:"price"`
+	SKU         string      `json:"sku" db:"sku" validate:"required,len=5"`
+	ImageURL    string      `json:"image_url" db:"image_url"`
+	CategoryID  int         `json:"category_id" db:"category_id" validate:"required"`
+	WeightGrams int         `json:"weight_grams" db:"weight_grams"`
+	CreatedAt   time.Time   `json:"created_at,omitempty" db:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at,omitempty" db:"updated_at"`
+	DeletedAt   *time.Time  `json:"deleted_at,omitempty" db:"deleted_at"`
+
+	Variants []ProductVariant `json:"variants,omitempty"`
+}
+
+// ProductVariant is a live variant of a product. Price is only set when it overrides the product price.
+type ProductVariant struct {
+	ID      int               `json:"id"`
+	SKU     string            `json:"sku"`
+	Options map[string]string `json:"options"`
+	Price   *money.Money      `json:"price,omitempty"`
+}
+
+type ProductDeleted struct {
+	ID        int       `json:"id"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// PriceChangeReason tells why a product's price changed.
+type PriceChangeReason string
+
+const (
+	// PriceActivated means a scheduled price or sale took effect.
+	PriceActivated PriceChangeReason = "activated"
+	// PriceReverted means a sale ended and an earlier price applies again.
+	PriceReverted PriceChangeReason = "reverted"
+)
+
+// ProductPriceChanged is emitted when the price scheduler changes a product's price. PriceID
+// is the product price now in effect.
+type ProductPriceChanged struct {
+	ProductID int               `json:"product_id"`
+	PriceID   int               `json:"price_id"`
+	OldPrice  money.Money       `json:"old_price"`
+	NewPrice  money.Money       `json:"new_price"`
+	Reason    PriceChangeReason `json:"reason"`
+	ChangedAt time.Time         `json:"changed_at"`
 }
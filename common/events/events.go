@@ -3,6 +3,8 @@ package events
 import (
 	"encoding/json"
 	"time"
+
+	"github.com/rovilay/ecommerce-service/common/money"
 )
 
 type EventData struct {
@@ -11,14 +13,14 @@ type EventData struct {
 }
 
 type ProductCreatedEvent struct {
-	ID          int       `json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	Price       float32   `json:"price"`
-	SKU         string    `json:"sku" db:"sku"`
-	ImageURL    string    `json:"image_url"`
-	CategoryID  int       `json:"category_id"`
-	CreatedAt   time.Time `json:"created_at,omitempty"`
-	UpdatedAt   time.Time `json:"updated_at,omitempty"`
-	DeletedAt   string    `json:"deleted_at,omitempty"`
+	ID          int         `json:"id"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Price       money.Money `json:"price"`
+	SKU         string      `json:"sku" db:"sku"`
+	ImageURL    string      `json:"image_url"`
+	CategoryID  int         `json:"category_id"`
+	CreatedAt   time.Time   `json:"created_at,omitempty"`
+	UpdatedAt   time.Time   `json:"updated_at,omitempty"`
+	DeletedAt   *time.Time  `json:"deleted_at,omitempty"`
 }
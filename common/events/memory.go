@@ -0,0 +1,160 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+type PublishedEvent struct {
+	Exchange   Topic
+	RoutingKey RoutingKey
+	Event      EventData
+}
+
+// InMemoryPublisher records published events instead of sending them to a broker.
+// Setting Err makes every Publish call fail with it.
+type InMemoryPublisher struct {
+	mu     sync.Mutex
+	events []PublishedEvent
+	Err    error
+}
+
+func NewInMemoryPublisher() *InMemoryPublisher {
+	return &InMemoryPublisher{}
+}
+
+func (p *InMemoryPublisher) Publish(ctx context.Context, exchange Topic, routingKey RoutingKey, event EventData) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.Err != nil {
+		return p.Err
+	}
+
+	p.events = append(p.events, PublishedEvent{Exchange: exchange, RoutingKey: routingKey, Event: event})
+
+	return nil
+}
+
+// Events returns a copy of the events published so far.
+func (p *InMemoryPublisher) Events() []PublishedEvent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]PublishedEvent(nil), p.events...)
+}
+
+type InMemoryOutboxEntry struct {
+	OutboxMessage
+	DispatchedAt  *time.Time
+	FailedAt      *time.Time
+	NextAttemptAt time.Time
+	LockedUntil   time.Time
+	LastError     string
+}
+
+// InMemoryOutboxStore keeps the outbox in memory and follows the same rules as the Postgres
+// store: claimed messages are leased while they are handled, failed messages wait OutboxBackoff
+// and are marked failed after MaxOutboxAttempts.
+type InMemoryOutboxStore struct {
+	mu      sync.Mutex
+	entries []*InMemoryOutboxEntry
+	// Now is the store's clock; it defaults to time.Now.
+	Now func() time.Time
+}
+
+func NewInMemoryOutboxStore() *InMemoryOutboxStore {
+	return &InMemoryOutboxStore{Now: time.Now}
+}
+
+// Write adds an event to the outbox, like WriteOutbox does inside a transaction.
+func (s *InMemoryOutboxStore) Write(topic Topic, key RoutingKey, data any) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(EventData{Event: key, Data: b})
+	if err != nil {
+		return err
+	}
+
+	s.writePayload(topic, key, payload)
+
+	return nil
+}
+
+func (s *InMemoryOutboxStore) writePayload(topic Topic, key RoutingKey, payload []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries = append(s.entries, &InMemoryOutboxEntry{
+		OutboxMessage: OutboxMessage{ID: int64(len(s.entries) + 1), Topic: topic, RoutingKey: key, Payload: payload},
+		NextAttemptAt: s.Now(),
+	})
+}
+
+func (s *InMemoryOutboxStore) ProcessPending(ctx context.Context, limit int, handle func(ctx context.Context, msg *OutboxMessage) error) (int, error) {
+	claimed := s.claim(limit)
+
+	dispatched := 0
+	for _, e := range claimed {
+		msg := e.OutboxMessage
+		err := handle(ctx, &msg)
+
+		s.mu.Lock()
+		now := s.Now()
+		e.Attempts++
+		e.LockedUntil = time.Time{}
+		if err != nil {
+			e.LastError = err.Error()
+			e.NextAttemptAt = now.Add(OutboxBackoff(e.Attempts))
+			if e.Attempts >= MaxOutboxAttempts {
+				e.FailedAt = &now
+			}
+		} else {
+			e.DispatchedAt = &now
+			e.LastError = ""
+			dispatched++
+		}
+		s.mu.Unlock()
+	}
+
+	return dispatched, nil
+}
+
+func (s *InMemoryOutboxStore) claim(limit int) []*InMemoryOutboxEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.Now()
+	var claimed []*InMemoryOutboxEntry
+	for _, e := range s.entries {
+		if len(claimed) >= limit {
+			break
+		}
+		if e.DispatchedAt != nil || e.FailedAt != nil || e.NextAttemptAt.After(now) || e.LockedUntil.After(now) {
+			continue
+		}
+
+		e.LockedUntil = now.Add(outboxLease)
+		claimed = append(claimed, e)
+	}
+
+	return claimed
+}
+
+// Entries returns a copy of the messages in the outbox, oldest first.
+func (s *InMemoryOutboxStore) Entries() []InMemoryOutboxEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := make([]InMemoryOutboxEntry, len(s.entries))
+	for i, e := range s.entries {
+		entries[i] = *e
+	}
+
+	return entries
+}
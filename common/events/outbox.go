@@ -0,0 +1,211 @@
+package events
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog"
+)
+
+// MaxOutboxAttempts is how many times the relay tries to publish a message before giving up on
+// it. Messages that fail every attempt are marked failed, logged as errors and counted in the
+// "outbox" expvar map.
+const MaxOutboxAttempts = 10
+
+// outboxLease is how long a relay has to publish the messages it claimed before other relays
+// may claim them again.
+const outboxLease = time.Minute
+
+var outboxMetrics = expvar.NewMap("outbox")
+
+// Publisher sends an event to an exchange. RabbitClient satisfies it.
+type Publisher interface {
+	Publish(ctx context.Context, exchange Topic, routingKey RoutingKey, event EventData) error
+}
+
+type OutboxMessage struct {
+	ID         int64      `db:"id"`
+	Topic      Topic      `db:"topic"`
+	RoutingKey RoutingKey `db:"routing_key"`
+	Payload    []byte     `db:"payload"`
+	Attempts   int        `db:"attempts"`
+}
+
+// OutboxStore hands pending messages to handle and records whether each was dispatched.
+type OutboxStore interface {
+	ProcessPending(ctx context.Context, limit int, handle func(ctx context.Context, msg *OutboxMessage) error) (int, error)
+}
+
+// WriteOutbox stores an event in the outbox using tx, so it is only published if tx commits.
+func WriteOutbox(ctx context.Context, tx sqlx.ExecerContext, topic Topic, key RoutingKey, data any) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(EventData{Event: key, Data: b})
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO outbox (topic, routing_key, payload) VALUES ($1, $2, $3)`
+	_, err = tx.ExecContext(ctx, query, string(topic), string(key), payload)
+
+	return err
+}
+
+// OutboxBackoff is the delay before a message that failed attempts times is retried.
+func OutboxBackoff(attempts int) time.Duration {
+	if attempts > 8 {
+		return time.Minute * 5
+	}
+
+	backoff := time.Second << attempts
+	if backoff > time.Minute*5 {
+		return time.Minute * 5
+	}
+
+	return backoff
+}
+
+type postgresOutboxStore struct {
+	db  *sqlx.DB
+	log *zerolog.Logger
+}
+
+func NewPostgresOutboxStore(db *sqlx.DB, log *zerolog.Logger) *postgresOutboxStore {
+	logger := log.With().Str("store", "postgresOutboxStore").Logger()
+
+	return &postgresOutboxStore{
+		db:  db,
+		log: &logger,
+	}
+}
+
+// ProcessPending leases up to limit pending messages in a short transaction, then hands them to
+// handle one by one and records each outcome. No lock is held while handle runs; the lease keeps
+// other relays off the messages until outboxLease has passed.
+func (s *postgresOutboxStore) ProcessPending(ctx context.Context, limit int, handle func(ctx context.Context, msg *OutboxMessage) error) (int, error) {
+	// SKIP LOCKED lets several relays claim from the table at once without claiming a message twice
+	query := `
+		UPDATE outbox SET locked_until = now() + $2 * interval '1 millisecond'
+		WHERE id IN (
+			SELECT id FROM outbox
+			WHERE dispatched_at IS NULL AND failed_at IS NULL AND next_attempt_at <= now()
+				AND (locked_until IS NULL OR locked_until <= now())
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, topic, routing_key, payload, attempts
+	`
+
+	var msgs []*OutboxMessage
+	if err := s.db.SelectContext(ctx, &msgs, query, limit, outboxLease.Milliseconds()); err != nil {
+		return 0, err
+	}
+
+	slices.SortFunc(msgs, func(a, b *OutboxMessage) int { return cmp.Compare(a.ID, b.ID) })
+
+	dispatched := 0
+	for _, msg := range msgs {
+		if err := handle(ctx, msg); err != nil {
+			s.log.Err(err).Int64("id", msg.ID).Int("attempts", msg.Attempts+1).Msg("failed to dispatch outbox message")
+
+			// the last attempt moves the message out of the way for good
+			query := `
+				UPDATE outbox
+				SET attempts = attempts + 1, last_error = $1, next_attempt_at = now() + $2 * interval '1 millisecond',
+					locked_until = NULL, failed_at = CASE WHEN attempts + 1 >= $4 THEN now() END
+				WHERE id = $3
+			`
+			backoff := OutboxBackoff(msg.Attempts + 1).Milliseconds()
+			if _, err := s.db.ExecContext(ctx, query, err.Error(), backoff, msg.ID, MaxOutboxAttempts); err != nil {
+				return dispatched, err
+			}
+			continue
+		}
+
+		query := `UPDATE outbox SET dispatched_at = now(), attempts = attempts + 1, last_error = NULL, locked_until = NULL WHERE id = $1`
+		if _, err := s.db.ExecContext(ctx, query, msg.ID); err != nil {
+			return dispatched, err
+		}
+		dispatched++
+	}
+
+	return dispatched, nil
+}
+
+// OutboxRelay publishes the messages written to the outbox.
+type OutboxRelay struct {
+	store     OutboxStore
+	publisher Publisher
+	batchSize int
+	log       *zerolog.Logger
+}
+
+func NewOutboxRelay(store OutboxStore, p Publisher, batchSize int, l *zerolog.Logger) *OutboxRelay {
+	logger := l.With().Str("component", "OutboxRelay").Logger()
+
+	return &OutboxRelay{
+		store:     store,
+		publisher: p,
+		batchSize: batchSize,
+		log:       &logger,
+	}
+}
+
+// DispatchPending publishes one batch of pending messages and returns how many were dispatched.
+func (r *OutboxRelay) DispatchPending(ctx context.Context) (int, error) {
+	return r.store.ProcessPending(ctx, r.batchSize, func(ctx context.Context, msg *OutboxMessage) error {
+		err := r.publish(ctx, msg)
+		if err != nil && msg.Attempts+1 >= MaxOutboxAttempts {
+			outboxMetrics.Add("failed", 1)
+			r.log.Error().Err(err).Int64("id", msg.ID).Str("topic", string(msg.Topic)).Str("routing_key", string(msg.RoutingKey)).
+				Msg("outbox message failed its last attempt and will not be retried")
+		}
+
+		return err
+	})
+}
+
+func (r *OutboxRelay) publish(ctx context.Context, msg *OutboxMessage) error {
+	var e EventData
+	if err := json.Unmarshal(msg.Payload, &e); err != nil {
+		return fmt.Errorf("invalid outbox payload: %w", err)
+	}
+
+	return r.publisher.Publish(ctx, msg.Topic, msg.RoutingKey, e)
+}
+
+// Run dispatches pending messages every interval until ctx is done.
+func (r *OutboxRelay) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// drain full batches before waiting for the next tick
+			for {
+				n, err := r.DispatchPending(ctx)
+				if err != nil {
+					r.log.Err(err).Msg("failed to dispatch outbox")
+					break
+				}
+
+				if n < r.batchSize {
+					break
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func newTestRelay(batchSize int) (*OutboxRelay, *InMemoryOutboxStore, *InMemoryPublisher, *time.Time) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewInMemoryOutboxStore()
+	store.Now = func() time.Time { return now }
+
+	publisher := NewInMemoryPublisher()
+	logger := zerolog.Nop()
+
+	return NewOutboxRelay(store, publisher, batchSize, &logger), store, publisher, &now
+}
+
+func TestOutboxRelayDispatches(t *testing.T) {
+	relay, store, publisher, _ := newTestRelay(10)
+
+	if err := store.Write(Product, ProductCreated, ProductCreatedEvent{ID: 7}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Write(Order, OrderCreated, map[string]int{"id": 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := relay.DispatchPending(context.Background())
+	if err != nil || n != 2 {
+		t.Fatalf("DispatchPending() = %d, %v, want 2, nil", n, err)
+	}
+
+	events := publisher.Events()
+	if len(events) != 2 {
+		t.Fatalf("published %d events, want 2", len(events))
+	}
+	if events[0].Exchange != Product || events[0].RoutingKey != ProductCreated || events[0].Event.Event != ProductCreated {
+		t.Errorf("first event = %+v, want product.created on product", events[0])
+	}
+
+	var created ProductCreatedEvent
+	if err := json.Unmarshal(events[0].Event.Data, &created); err != nil || created.ID != 7 {
+		t.Errorf("first event data = %s, want product 7", events[0].Event.Data)
+	}
+
+	// dispatched messages are not published again
+	if n, _ := relay.DispatchPending(context.Background()); n != 0 {
+		t.Errorf("second DispatchPending() = %d, want 0", n)
+	}
+	if got := len(publisher.Events()); got != 2 {
+		t.Errorf("published %d events after second pass, want 2", got)
+	}
+}
+
+func TestOutboxRelayBatchSize(t *testing.T) {
+	relay, store, publisher, _ := newTestRelay(2)
+
+	for i := 0; i < 3; i++ {
+		if err := store.Write(Inventory, InventoryUpdated, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, want := range []int{2, 1, 0} {
+		if n, err := relay.DispatchPending(context.Background()); err != nil || n != want {
+			t.Fatalf("DispatchPending() = %d, %v, want %d, nil", n, err, want)
+		}
+	}
+	if got := len(publisher.Events()); got != 3 {
+		t.Errorf("published %d events, want 3", got)
+	}
+}
+
+func TestOutboxRelayRetries(t *testing.T) {
+	relay, store, publisher, now := newTestRelay(10)
+
+	if err := store.Write(Order, OrderStatusChanged, map[string]string{"status": "shipped"}); err != nil {
+		t.Fatal(err)
+	}
+
+	publisher.Err = errors.New("broker unavailable")
+
+	if n, err := relay.DispatchPending(context.Background()); err != nil || n != 0 {
+		t.Fatalf("DispatchPending() = %d, %v, want 0, nil", n, err)
+	}
+
+	entry := store.Entries()[0]
+	if entry.Attempts != 1 || entry.DispatchedAt != nil || entry.LastError != "broker unavailable" {
+		t.Fatalf("entry after failure = %+v, want one failed attempt", entry)
+	}
+	if want := now.Add(OutboxBackoff(1)); !entry.NextAttemptAt.Equal(want) {
+		t.Errorf("next attempt at %v, want %v", entry.NextAttemptAt, want)
+	}
+
+	// the message waits out its backoff even once the broker is back
+	publisher.Err = nil
+	if n, _ := relay.DispatchPending(context.Background()); n != 0 {
+		t.Fatalf("DispatchPending() during backoff = %d, want 0", n)
+	}
+
+	*now = now.Add(OutboxBackoff(1))
+	if n, err := relay.DispatchPending(context.Background()); err != nil || n != 1 {
+		t.Fatalf("DispatchPending() after backoff = %d, %v, want 1, nil", n, err)
+	}
+
+	entry = store.Entries()[0]
+	if entry.Attempts != 2 || entry.DispatchedAt == nil || entry.LastError != "" {
+		t.Errorf("entry after retry = %+v, want dispatched on the second attempt", entry)
+	}
+	if got := len(publisher.Events()); got != 1 {
+		t.Errorf("published %d events, want 1", got)
+	}
+}
+
+func TestOutboxRelayAttemptCap(t *testing.T) {
+	relay, store, publisher, now := newTestRelay(10)
+
+	if err := store.Write(Order, OrderCancelled, map[string]int{"id": 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	publisher.Err = errors.New("broker unavailable")
+	failedBefore := failedCount()
+
+	for i := 1; i <= MaxOutboxAttempts; i++ {
+		if _, err := relay.DispatchPending(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		entry := store.Entries()[0]
+		if entry.Attempts != i {
+			t.Fatalf("attempts = %d after %d passes", entry.Attempts, i)
+		}
+		if (entry.FailedAt != nil) != (i == MaxOutboxAttempts) {
+			t.Fatalf("failed at = %v after %d attempts", entry.FailedAt, i)
+		}
+		*now = entry.NextAttemptAt
+	}
+
+	entry := store.Entries()[0]
+	if entry.FailedAt == nil {
+		t.Fatalf("entry = %+v, want it marked failed after %d attempts", entry, MaxOutboxAttempts)
+	}
+	if got := outboxMetrics.Get("failed").String(); got != fmt.Sprint(failedBefore+1) {
+		t.Errorf("outbox.failed = %s, want %d", got, failedBefore+1)
+	}
+
+	// after MaxOutboxAttempts failures the message is left alone, even once publishing works
+	publisher.Err = nil
+	*now = now.Add(time.Hour)
+	if n, _ := relay.DispatchPending(context.Background()); n != 0 {
+		t.Errorf("DispatchPending() after the attempt cap = %d, want 0", n)
+	}
+
+	entry = store.Entries()[0]
+	if entry.Attempts != MaxOutboxAttempts || entry.DispatchedAt != nil {
+		t.Errorf("entry = %+v, want %d attempts and not dispatched", entry, MaxOutboxAttempts)
+	}
+	if got := len(publisher.Events()); got != 0 {
+		t.Errorf("published %d events, want 0", got)
+	}
+}
+
+func TestOutboxLease(t *testing.T) {
+	relay, store, publisher, now := newTestRelay(10)
+
+	if err := store.Write(Product, ProductDeleted, map[string]int{"id": 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	// a message being published is not handed to another relay
+	var nested int
+	n, err := store.ProcessPending(context.Background(), 10, func(ctx context.Context, msg *OutboxMessage) error {
+		nested, _ = relay.DispatchPending(ctx)
+		return nil
+	})
+	if err != nil || n != 1 || nested != 0 {
+		t.Fatalf("ProcessPending() = %d, %v with %d dispatched meanwhile, want 1, nil with 0", n, err, nested)
+	}
+	if got := len(publisher.Events()); got != 0 {
+		t.Errorf("published %d events while the message was leased, want 0", got)
+	}
+
+	// a relay that never reports back loses its lease
+	if err := store.Write(Product, ProductDeleted, map[string]int{"id": 2}); err != nil {
+		t.Fatal(err)
+	}
+	claimed := store.claim(10)
+	if len(claimed) != 1 {
+		t.Fatalf("claimed %d messages, want 1", len(claimed))
+	}
+	if n, _ := relay.DispatchPending(context.Background()); n != 0 {
+		t.Fatalf("DispatchPending() during the lease = %d, want 0", n)
+	}
+
+	*now = now.Add(outboxLease)
+	if n, _ := relay.DispatchPending(context.Background()); n != 1 {
+		t.Errorf("DispatchPending() after the lease = %d, want 1", n)
+	}
+}
+
+func failedCount() int64 {
+	if v, ok := outboxMetrics.Get("failed").(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
+
+func TestOutboxRelayInvalidPayload(t *testing.T) {
+	relay, store, publisher, _ := newTestRelay(10)
+
+	store.writePayload(Product, ProductUpdated, []byte("not json"))
+
+	if n, err := relay.DispatchPending(context.Background()); err != nil || n != 0 {
+		t.Fatalf("DispatchPending() = %d, %v, want 0, nil", n, err)
+	}
+
+	entry := store.Entries()[0]
+	if entry.Attempts != 1 || entry.DispatchedAt != nil || entry.LastError == "" {
+		t.Errorf("entry = %+v, want one failed attempt", entry)
+	}
+	if got := len(publisher.Events()); got != 0 {
+		t.Errorf("published %d events, want 0", got)
+	}
+}
+
+func TestOutboxBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, time.Second},
+		{1, 2 * time.Second},
+		{3, 8 * time.Second},
+		{8, 256 * time.Second},
+		{9, 5 * time.Minute},
+		{MaxOutboxAttempts, 5 * time.Minute},
+		{64, 5 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := OutboxBackoff(tt.attempts); got != tt.want {
+			t.Errorf("OutboxBackoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
@@ -94,30 +94,35 @@ func (rc *RabbitClient) Publish(ctx context.Context, exchange Topic, routingKey
 // autoAck is important to understand, if set to true, it will automatically Acknowledge that processing is done
 // This is good, but remember that if the Process fails before completion, then an ACK is already sent, making a message lost
 // if not handled properly
-func (rc *RabbitClient) Consume(ctx context.Context, consumer Topic, queue RoutingKey, autoAck bool) (<-chan amqp.Delivery, error) {
-	return rc.ch.ConsumeWithContext(ctx, string(queue), string(consumer), autoAck, false, false, false, nil)
+func (rc *RabbitClient) Consume(ctx context.Context, consumer string, queue string, autoAck bool) (<-chan amqp.Delivery, error) {
+	return rc.ch.ConsumeWithContext(ctx, queue, consumer, autoAck, false, false, false, nil)
 }
 
-func (rc *RabbitClient) Listen(ctx context.Context, consumer Topic, queue RoutingKey, autoAck bool) (<-chan amqp.Delivery, error) {
+// Listen declares a durable queue, binds it to every key on the exchange and consumes from it.
+// Each service should use its own queue name so that every service receives its own copy of an event.
+func (rc *RabbitClient) Listen(ctx context.Context, exchange Topic, queue string, autoAck bool, keys ...RoutingKey) (<-chan amqp.Delivery, error) {
 	// create exchange if it doesn't exist
-	err := rc.CreateExchange(consumer, true, false)
+	err := rc.CreateExchange(exchange, true, false)
 	if err != nil {
 		return nil, err
 	}
 
 	// create a queue
-	q, err := rc.CreateQueue(queue, true, false)
+	q, err := rc.ch.QueueDeclare(queue, true, false, false, false, nil)
 	if err != nil {
 		return nil, err
 	}
+
 	// bind the queue to the exchange
-	err = rc.CreateBinding(consumer, q.Name, queue)
-	if err != nil {
-		return nil, err
+	for _, key := range keys {
+		err = rc.CreateBinding(exchange, q.Name, key)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	// consume from the queue
-	return rc.Consume(ctx, consumer, queue, autoAck)
+	// consume from the queue, the queue name doubles as the consumer tag
+	return rc.Consume(ctx, q.Name, q.Name, autoAck)
 }
 
 // close the channel
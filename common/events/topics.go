@@ -6,9 +6,22 @@ type RoutingKey string
 const (
 	Product   Topic = "product"
 	Inventory Topic = "inventory"
+	Order     Topic = "order"
 )
 
 // routing key format <topic>.<action>
 const (
 	ProductCreated RoutingKey = "product.created"
+	ProductUpdated RoutingKey = "product.updated"
+	ProductDeleted RoutingKey = "product.deleted"
+	// ProductPriceChanged is emitted when a scheduled price or sale takes effect or ends.
+	ProductPriceChanged RoutingKey = "product.price_changed"
+
+	InventoryCreated RoutingKey = "inventory.created"
+	InventoryUpdated RoutingKey = "inventory.updated"
+
+	OrderCreated       RoutingKey = "order.created"
+	OrderStatusChanged RoutingKey = "order.status_changed"
+	OrderCancelled     RoutingKey = "order.cancelled"
+	OrderRefunded      RoutingKey = "order.refunded"
 )
@@ -0,0 +1,105 @@
+package httpclient
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerStateChanges(t *testing.T) {
+	var changes []BreakerState
+	b := NewCircuitBreaker(3, 20*time.Millisecond, func(s BreakerState) { changes = append(changes, s) })
+
+	call := func(success bool) error {
+		if err := b.Allow(); err != nil {
+			return err
+		}
+		b.Record(success)
+		return nil
+	}
+
+	// failures below the threshold, or broken up by a success, keep the breaker closed
+	for _, success := range []bool{false, false, true, false, false} {
+		if err := call(success); err != nil {
+			t.Fatalf("closed breaker rejected a call: %v", err)
+		}
+	}
+	if s := b.State(); s != BreakerClosed {
+		t.Fatalf("state = %s, want %s", s, BreakerClosed)
+	}
+
+	// the third consecutive failure opens it
+	call(false)
+	if s := b.State(); s != BreakerOpen {
+		t.Fatalf("state after threshold failures = %s, want %s", s, BreakerOpen)
+	}
+	if err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("open breaker Allow() = %v, want %v", err, ErrCircuitOpen)
+	}
+
+	// after the cooldown a single trial call is let through
+	time.Sleep(25 * time.Millisecond)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("Allow() after cooldown = %v, want nil", err)
+	}
+	if s := b.State(); s != BreakerHalfOpen {
+		t.Fatalf("state during the trial = %s, want %s", s, BreakerHalfOpen)
+	}
+	if err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("second Allow() during the trial = %v, want %v", err, ErrCircuitOpen)
+	}
+
+	// a failed trial reopens it straight away
+	b.Record(false)
+	if s := b.State(); s != BreakerOpen {
+		t.Fatalf("state after a failed trial = %s, want %s", s, BreakerOpen)
+	}
+	if err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("reopened breaker Allow() = %v, want %v", err, ErrCircuitOpen)
+	}
+
+	// a successful trial closes it
+	time.Sleep(25 * time.Millisecond)
+	if err := call(true); err != nil {
+		t.Fatalf("trial call rejected: %v", err)
+	}
+	if s := b.State(); s != BreakerClosed {
+		t.Fatalf("state after a successful trial = %s, want %s", s, BreakerClosed)
+	}
+
+	want := []BreakerState{BreakerOpen, BreakerHalfOpen, BreakerOpen, BreakerHalfOpen, BreakerClosed}
+	if !slices.Equal(changes, want) {
+		t.Errorf("state changes = %v, want %v", changes, want)
+	}
+}
+
+func TestCircuitBreakerRelease(t *testing.T) {
+	b := NewCircuitBreaker(1, 10*time.Millisecond, nil)
+
+	b.Allow()
+	b.Record(false)
+	time.Sleep(15 * time.Millisecond)
+
+	// a released trial does not count, and the next call gets to be the trial
+	if err := b.Allow(); err != nil {
+		t.Fatalf("Allow() after cooldown = %v, want nil", err)
+	}
+	b.Release()
+	if s := b.State(); s != BreakerHalfOpen {
+		t.Fatalf("state after a released trial = %s, want %s", s, BreakerHalfOpen)
+	}
+	if err := b.Allow(); err != nil {
+		t.Fatalf("Allow() after a released trial = %v, want nil", err)
+	}
+}
+
+func TestNewCircuitBreakerThreshold(t *testing.T) {
+	b := NewCircuitBreaker(0, time.Minute, nil)
+
+	b.Allow()
+	b.Record(false)
+	if s := b.State(); s != BreakerOpen {
+		t.Errorf("state after one failure with threshold 0 = %s, want %s", s, BreakerOpen)
+	}
+}
@@ -0,0 +1,216 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"io"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// metrics holds one map per upstream, served on /debug/vars.
+var metrics = expvar.NewMap("http_clients")
+
+type Config struct {
+	// Timeout bounds a single attempt, including reading the response body.
+	Timeout time.Duration
+	// MaxRetries is how many times an idempotent call is retried after a transient failure.
+	MaxRetries int
+	// RetryBackoff is the base delay of the jittered exponential backoff between retries.
+	RetryBackoff time.Duration
+	// MaxRetryBackoff caps the delay between retries.
+	MaxRetryBackoff time.Duration
+	// BreakerThreshold is how many consecutive failures open the circuit breaker.
+	BreakerThreshold int
+	// BreakerCooldown is how long the breaker stays open before a trial call is let through.
+	BreakerCooldown time.Duration
+}
+
+// Client wraps http.Client with per-attempt timeouts, retries for idempotent requests
+// and a circuit breaker for a single upstream.
+type Client struct {
+	name    string
+	http    *http.Client
+	config  Config
+	breaker *CircuitBreaker
+	stats   *expvar.Map
+	log     *zerolog.Logger
+}
+
+// New returns a client for the upstream called name. name is used in logs and metrics.
+func New(name string, c Config, l *zerolog.Logger) *Client {
+	logger := l.With().Str("component", "httpclient").Str("upstream", name).Logger()
+
+	stats := new(expvar.Map).Init()
+	state := new(expvar.String)
+	state.Set(string(BreakerClosed))
+	stats.Set("breaker_state", state)
+	metrics.Set(name, stats)
+
+	client := &Client{
+		name:   name,
+		http:   &http.Client{},
+		config: c,
+		stats:  stats,
+		log:    &logger,
+	}
+
+	client.breaker = NewCircuitBreaker(c.BreakerThreshold, c.BreakerCooldown, func(s BreakerState) {
+		state.Set(string(s))
+		stats.Add("breaker_transitions", 1)
+		logger.Warn().Str("state", string(s)).Msg("circuit breaker state changed")
+	})
+
+	return client
+}
+
+func (c *Client) BreakerState() BreakerState {
+	return c.breaker.State()
+}
+
+// Do sends req. GET, HEAD, OPTIONS and DELETE requests, and requests with an Idempotency-Key
+// header, are retried on network errors and 5xx responses. Calls fail fast with ErrCircuitOpen
+// while the upstream's breaker is open.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	attempts := 1
+	if isIdempotent(req) {
+		attempts += c.config.MaxRetries
+	}
+
+	var resp *http.Response
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			c.stats.Add("retries", 1)
+
+			if err := sleep(req.Context(), c.backoff(attempt)); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err = c.attempt(req)
+		if !retryable(resp, err) || req.Context().Err() != nil {
+			return resp, err
+		}
+
+		if attempt < attempts-1 && resp != nil {
+			// drain so the connection can be reused
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+	}
+
+	return resp, err
+}
+
+func (c *Client) attempt(req *http.Request) (*http.Response, error) {
+	if err := c.breaker.Allow(); err != nil {
+		c.stats.Add("rejected", 1)
+		return nil, err
+	}
+
+	c.stats.Add("requests", 1)
+
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if c.config.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(req.Context(), c.config.Timeout)
+	} else {
+		ctx, cancel = context.WithCancel(req.Context())
+	}
+
+	r := req.Clone(ctx)
+	if req.Body != nil && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			cancel()
+			c.breaker.Release()
+			return nil, err
+		}
+		r.Body = body
+	}
+
+	resp, err := c.http.Do(r)
+	if err != nil {
+		cancel()
+
+		// the caller giving up says nothing about the upstream's health
+		if req.Context().Err() != nil {
+			c.breaker.Release()
+			return nil, err
+		}
+
+		c.stats.Add("failures", 1)
+		c.breaker.Record(false)
+		return nil, err
+	}
+
+	success := resp.StatusCode < http.StatusInternalServerError
+	if !success {
+		c.stats.Add("failures", 1)
+	}
+	c.breaker.Record(success)
+
+	// the attempt's deadline keeps applying while the caller reads the body
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+
+	return resp, nil
+}
+
+// backoff is the delay before retry number attempt, with full jitter.
+func (c *Client) backoff(attempt int) time.Duration {
+	if c.config.RetryBackoff <= 0 {
+		return 0
+	}
+
+	backoff := c.config.RetryBackoff << (attempt - 1)
+	if c.config.MaxRetryBackoff > 0 && (backoff > c.config.MaxRetryBackoff || backoff <= 0) {
+		backoff = c.config.MaxRetryBackoff
+	}
+
+	return time.Duration(rand.Int63n(int64(backoff) + 1))
+}
+
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodDelete:
+		return true
+	}
+
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, ErrCircuitOpen)
+	}
+
+	return resp.StatusCode >= http.StatusInternalServerError
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
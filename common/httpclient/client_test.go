@@ -0,0 +1,134 @@
+package httpclient
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestClientCircuitBreaker(t *testing.T) {
+	var healthy atomic.Bool
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	logger := zerolog.Nop()
+	c := New("breaker-test", Config{Timeout: time.Second, BreakerThreshold: 2, BreakerCooldown: 20 * time.Millisecond}, &logger)
+
+	get := func() (*http.Response, error) {
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp, err := c.Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return resp, err
+	}
+
+	for i := 0; i < 2; i++ {
+		resp, err := get()
+		if err != nil || resp.StatusCode != http.StatusServiceUnavailable {
+			t.Fatalf("call %d = %v, %v, want a 503", i, resp, err)
+		}
+	}
+	if s := c.BreakerState(); s != BreakerOpen {
+		t.Fatalf("state after two 5xx = %s, want %s", s, BreakerOpen)
+	}
+
+	// an open breaker fails fast without calling the upstream
+	if _, err := get(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("call while open = %v, want %v", err, ErrCircuitOpen)
+	}
+	if n := hits.Load(); n != 2 {
+		t.Fatalf("upstream got %d calls, want 2", n)
+	}
+
+	// a failed trial reopens it
+	time.Sleep(25 * time.Millisecond)
+	if resp, err := get(); err != nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("trial call = %v, %v, want a 503", resp, err)
+	}
+	if s := c.BreakerState(); s != BreakerOpen {
+		t.Fatalf("state after a failed trial = %s, want %s", s, BreakerOpen)
+	}
+
+	// a successful trial closes it
+	healthy.Store(true)
+	time.Sleep(25 * time.Millisecond)
+	if resp, err := get(); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("trial call = %v, %v, want a 200", resp, err)
+	}
+	if s := c.BreakerState(); s != BreakerClosed {
+		t.Fatalf("state after a successful trial = %s, want %s", s, BreakerClosed)
+	}
+
+	stats := metrics.Get("breaker-test").String()
+	for _, want := range []string{`"breaker_state": "closed"`, `"breaker_transitions": 5`, `"rejected": 1`} {
+		if !strings.Contains(stats, want) {
+			t.Errorf("metrics %s do not contain %s", stats, want)
+		}
+	}
+}
+
+func TestClientRetriesIdempotentRequests(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	logger := zerolog.Nop()
+	c := New("retry-test", Config{Timeout: time.Second, MaxRetries: 2, RetryBackoff: time.Millisecond, BreakerThreshold: 5}, &logger)
+
+	tests := []struct {
+		name     string
+		method   string
+		key      string
+		wantHits int32
+		want     int
+	}{
+		{name: "get", method: http.MethodGet, wantHits: 3, want: http.StatusOK},
+		{name: "post", method: http.MethodPost, wantHits: 1, want: http.StatusBadGateway},
+		{name: "post with idempotency key", method: http.MethodPost, key: "order-1", wantHits: 3, want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits.Store(0)
+
+			req, err := http.NewRequest(tt.method, server.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.key != "" {
+				req.Header.Set("Idempotency-Key", tt.key)
+			}
+
+			resp, err := c.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.want || hits.Load() != tt.wantHits {
+				t.Errorf("Do() = %d after %d calls, want %d after %d", resp.StatusCode, hits.Load(), tt.want, tt.wantHits)
+			}
+		})
+	}
+}
@@ -0,0 +1,269 @@
+package money
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is used when a price is given without a currency.
+const DefaultCurrency = "USD"
+
+// supportedCurrencies are the ISO 4217 currencies accepted by the services. All of them have
+// two decimal places, which matches the DECIMAL(10,2) price columns.
+var supportedCurrencies = map[string]bool{
+	"USD": true,
+	"EUR": true,
+	"GBP": true,
+	"CAD": true,
+	"AUD": true,
+	"NGN": true,
+}
+
+var ErrInvalidAmount = errors.New("invalid money amount")
+var ErrInvalidCurrency = errors.New("invalid or unsupported currency")
+var ErrCurrencyMismatch = errors.New("currency mismatch")
+
+// Money is an amount in minor units (cents, kobo, ...) of a currency.
+//
+// It is stored in Postgres as a DECIMAL amount with the currency in its own column, and is
+// serialised to JSON as {"amount": "12.34", "currency": "USD"} so amounts never go through floats.
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+func New(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// Parse reads a decimal amount such as "12.34" in the given currency.
+func Parse(amount, currency string) (Money, error) {
+	minor, err := parseAmount(amount)
+	if err != nil {
+		return Money{}, err
+	}
+
+	return Money{Amount: minor, Currency: currency}, nil
+}
+
+func IsSupportedCurrency(currency string) bool {
+	return supportedCurrencies[currency]
+}
+
+// Validate checks that the currency is supported.
+func (m Money) Validate() error {
+	if !IsSupportedCurrency(m.Currency) {
+		return fmt.Errorf("%w: %s", ErrInvalidCurrency, m.Currency)
+	}
+
+	return nil
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) IsPositive() bool {
+	return m.Amount > 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// Add returns m + o. Both must be in the same currency.
+func (m Money) Add(o Money) (Money, error) {
+	if err := m.sameCurrency(o); err != nil {
+		return Money{}, err
+	}
+
+	return Money{Amount: m.Amount + o.Amount, Currency: m.Currency}, nil
+}
+
+// Sub returns m - o. Both must be in the same currency.
+func (m Money) Sub(o Money) (Money, error) {
+	if err := m.sameCurrency(o); err != nil {
+		return Money{}, err
+	}
+
+	return Money{Amount: m.Amount - o.Amount, Currency: m.Currency}, nil
+}
+
+// Mul returns m multiplied by n, e.g. a unit price by a quantity.
+func (m Money) Mul(n int) Money {
+	return Money{Amount: m.Amount * int64(n), Currency: m.Currency}
+}
+
+// Sum adds up amounts in currency. It returns zero in currency when there is nothing to add.
+func Sum(currency string, amounts ...Money) (Money, error) {
+	total := Money{Currency: currency}
+	for _, a := range amounts {
+		var err error
+		if total, err = total.Add(a); err != nil {
+			return Money{}, err
+		}
+	}
+
+	return total, nil
+}
+
+func (m Money) sameCurrency(o Money) error {
+	if m.Currency != o.Currency {
+		return fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, o.Currency)
+	}
+
+	return nil
+}
+
+// Decimal formats the amount with two decimal places, e.g. "12.34".
+func (m Money) Decimal() string {
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	return fmt.Sprintf("%s%d.%02d", sign, amount/100, amount%100)
+}
+
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+type moneyJSON struct {
+	Amount   json.Number `json:"amount"`
+	Currency string      `json:"currency"`
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount   string `json:"amount"`
+		Currency string `json:"currency"`
+	}{m.Decimal(), m.Currency})
+}
+
+// UnmarshalJSON accepts {"amount": "12.34", "currency": "USD"}, with the amount as a string or a
+// number. A bare amount ("12.34" or 12.34), as sent by older clients and stored in older payloads,
+// is read in DefaultCurrency, as is an object without a currency.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var v moneyJSON
+
+	trimmed := strings.TrimSpace(string(data))
+	if strings.HasPrefix(trimmed, "{") {
+		if err := json.Unmarshal(data, &v); err != nil {
+			return err
+		}
+	} else {
+		var amount json.Number
+		if err := json.Unmarshal(data, &amount); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidAmount, trimmed)
+		}
+		v.Amount = amount
+	}
+
+	if v.Currency == "" {
+		v.Currency = DefaultCurrency
+	}
+
+	parsed, err := Parse(v.Amount.String(), v.Currency)
+	if err != nil {
+		return err
+	}
+
+	*m = parsed
+	return nil
+}
+
+// Scan reads a DECIMAL amount and keeps the current currency, so the currency column can be
+// scanned into m.Currency separately. It also accepts an amount followed by its currency,
+// e.g. "12.34 USD", for queries that select both as a single column.
+func (m *Money) Scan(src any) error {
+	var s string
+	switch v := src.(type) {
+	case nil:
+		m.Amount = 0
+		return nil
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	case int64:
+		s = strconv.FormatInt(v, 10)
+	case float64:
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Errorf("%w: cannot scan %T", ErrInvalidAmount, src)
+	}
+
+	amount, currency, _ := strings.Cut(strings.TrimSpace(s), " ")
+	minor, err := parseAmount(amount)
+	if err != nil {
+		return err
+	}
+
+	m.Amount = minor
+	if currency != "" {
+		m.Currency = currency
+	}
+
+	return nil
+}
+
+// Value writes the amount as a decimal string; the currency is written to its own column.
+func (m Money) Value() (driver.Value, error) {
+	return m.Decimal(), nil
+}
+
+// parseAmount converts a decimal string into minor units. More than two decimal places are
+// rejected rather than rounded, and amounts that do not fit in an int64 of minor units are
+// rejected rather than wrapped.
+func parseAmount(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("%w: empty amount", ErrInvalidAmount)
+	}
+
+	negative := false
+	if s[0] == '-' || s[0] == '+' {
+		negative = s[0] == '-'
+		s = s[1:]
+	}
+
+	whole, frac, _ := strings.Cut(s, ".")
+	frac = strings.TrimRight(frac, "0")
+	if len(frac) > 2 {
+		return 0, fmt.Errorf("%w: %s has more than two decimal places", ErrInvalidAmount, s)
+	}
+	frac += strings.Repeat("0", 2-len(frac))
+
+	if whole == "" {
+		whole = "0"
+	}
+
+	units, err := strconv.ParseUint(whole, 10, 63)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidAmount, s)
+	}
+
+	cents, err := strconv.ParseUint(frac, 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidAmount, s)
+	}
+
+	if units > (math.MaxInt64-cents)/100 {
+		return 0, fmt.Errorf("%w: %s is too large", ErrInvalidAmount, s)
+	}
+
+	amount := int64(units)*100 + int64(cents)
+	if negative {
+		amount = -amount
+	}
+
+	return amount, nil
+}
@@ -0,0 +1,177 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr error
+	}{
+		{in: "12.34", want: 1234},
+		{in: "12.3", want: 1230},
+		{in: "12", want: 1200},
+		{in: ".5", want: 50},
+		{in: "0", want: 0},
+		{in: "12.300", want: 1230},
+		{in: " 7.05 ", want: 705},
+		{in: "+1.00", want: 100},
+		{in: "-12.34", want: -1234},
+		{in: "92233720368547758.07", want: 9223372036854775807},
+		{in: "-92233720368547758.07", want: -9223372036854775807},
+		{in: "", wantErr: ErrInvalidAmount},
+		{in: "abc", wantErr: ErrInvalidAmount},
+		{in: "1.2.3", wantErr: ErrInvalidAmount},
+		{in: "1.234", wantErr: ErrInvalidAmount},
+		{in: "1e3", wantErr: ErrInvalidAmount},
+		{in: "--1", wantErr: ErrInvalidAmount},
+		{in: "92233720368547758.08", wantErr: ErrInvalidAmount},
+		{in: "92233720368547759", wantErr: ErrInvalidAmount},
+		{in: "100000000000000000000", wantErr: ErrInvalidAmount},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := Parse(tt.in, "USD")
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Parse(%q) error = %v, want %v", tt.in, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q) unexpected error: %v", tt.in, err)
+			}
+			if got.Amount != tt.want || got.Currency != "USD" {
+				t.Errorf("Parse(%q) = %v, want %d USD", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecimal(t *testing.T) {
+	tests := []struct {
+		amount int64
+		want   string
+	}{
+		{0, "0.00"},
+		{5, "0.05"},
+		{1234, "12.34"},
+		{-1234, "-12.34"},
+		{-5, "-0.05"},
+	}
+
+	for _, tt := range tests {
+		if got := New(tt.amount, "USD").Decimal(); got != tt.want {
+			t.Errorf("New(%d).Decimal() = %q, want %q", tt.amount, got, tt.want)
+		}
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	usd := func(a int64) Money { return New(a, "USD") }
+
+	tests := []struct {
+		name    string
+		op      func() (Money, error)
+		want    Money
+		wantErr error
+	}{
+		{name: "add", op: func() (Money, error) { return usd(1050).Add(usd(250)) }, want: usd(1300)},
+		{name: "sub", op: func() (Money, error) { return usd(1050).Sub(usd(250)) }, want: usd(800)},
+		{name: "sub below zero", op: func() (Money, error) { return usd(250).Sub(usd(1050)) }, want: usd(-800)},
+		{name: "mul", op: func() (Money, error) { return usd(199).Mul(3), nil }, want: usd(597)},
+		{name: "sum", op: func() (Money, error) { return Sum("USD", usd(100), usd(200), usd(300)) }, want: usd(600)},
+		{name: "sum of nothing", op: func() (Money, error) { return Sum("EUR") }, want: New(0, "EUR")},
+		{name: "add mismatch", op: func() (Money, error) { return usd(100).Add(New(100, "EUR")) }, wantErr: ErrCurrencyMismatch},
+		{name: "sub mismatch", op: func() (Money, error) { return usd(100).Sub(New(100, "EUR")) }, wantErr: ErrCurrencyMismatch},
+		{name: "sum mismatch", op: func() (Money, error) { return Sum("USD", usd(100), New(100, "GBP")) }, wantErr: ErrCurrencyMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.op()
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSON(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Money
+		wantErr bool
+	}{
+		{in: `{"amount": "12.34", "currency": "EUR"}`, want: New(1234, "EUR")},
+		{in: `{"amount": 12.34, "currency": "GBP"}`, want: New(1234, "GBP")},
+		{in: `{"amount": "12.34"}`, want: New(1234, DefaultCurrency)},
+		{in: `"12.34"`, want: New(1234, DefaultCurrency)},
+		{in: `12.34`, want: New(1234, DefaultCurrency)},
+		{in: `{"amount": "1.234", "currency": "USD"}`, wantErr: true},
+		{in: `true`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			var got Money
+			err := json.Unmarshal([]byte(tt.in), &got)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Unmarshal(%s) = %v, want an error", tt.in, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unmarshal(%s) unexpected error: %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Errorf("Unmarshal(%s) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+
+	b, err := json.Marshal(New(-705, "NGN"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"amount":"-7.05","currency":"NGN"}`; string(b) != want {
+		t.Errorf("Marshal = %s, want %s", b, want)
+	}
+}
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		src  any
+		want Money
+	}{
+		{src: "12.34", want: New(1234, "USD")},
+		{src: []byte("12.34 EUR"), want: New(1234, "EUR")},
+		{src: int64(12), want: New(1200, "USD")},
+		{src: nil, want: New(0, "USD")},
+	}
+
+	for _, tt := range tests {
+		got := New(1, "USD")
+		if err := got.Scan(tt.src); err != nil {
+			t.Fatalf("Scan(%v) unexpected error: %v", tt.src, err)
+		}
+		if got != tt.want {
+			t.Errorf("Scan(%v) = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+}
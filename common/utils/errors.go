@@ -3,3 +3,7 @@ package utils
 import "errors"
 
 var ErrMissingAuthToken = errors.New("missing authorization token")
+var ErrInvalidAuthToken = errors.New("invalid authorization token")
+var ErrForbidden = errors.New("forbidden, missing required role")
+var ErrMissingServiceToken = errors.New("missing service token")
+var ErrInvalidServiceToken = errors.New("invalid service token")
@@ -4,10 +4,43 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/dgrijalva/jwt-go"
 )
 
+const (
+	RoleCustomer    = "customer"
+	RoleAdmin       = "admin"
+	RoleFulfillment = "fulfillment"
+)
+
+// Claims are the parts of a user token the services act on.
+type Claims struct {
+	UserID string   `json:"user_id"`
+	Roles  []string `json:"roles"`
+	// ExpiresAt is the token's "exp" in unix seconds, or 0 when it never expires.
+	ExpiresAt int64 `json:"exp,omitempty"`
+}
+
+// Expired reports whether the token the claims came from has expired by now.
+func (c *Claims) Expired(now time.Time) bool {
+	return c.ExpiresAt != 0 && now.Unix() > c.ExpiresAt
+}
+
+// HasRole reports whether the claims include any of roles.
+func (c *Claims) HasRole(roles ...string) bool {
+	for _, have := range c.Roles {
+		for _, want := range roles {
+			if have == want {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
 func ExtractToken(authString string) (string, error) {
 	parts := strings.Split(authString, " ")
 	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
@@ -17,7 +50,9 @@ func ExtractToken(authString string) (string, error) {
 	return parts[1], nil
 }
 
-func ValidateJWT(tokenString string, authSecret []byte) (string, error) {
+// ValidateJWT verifies tokenString and returns its claims. Roles are read from the "roles" claim,
+// or the single "role" claim; tokens carrying neither are treated as customer tokens.
+func ValidateJWT(tokenString string, authSecret []byte) (*Claims, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
@@ -26,13 +61,44 @@ func ValidateJWT(tokenString string, authSecret []byte) (string, error) {
 	})
 
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	mapClaims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, ErrInvalidAuthToken
+	}
+
+	userID, ok := mapClaims["user_id"].(string)
+	if !ok || userID == "" {
+		return nil, ErrInvalidAuthToken
+	}
+
+	claims := &Claims{UserID: userID}
+
+	// jwt-go has checked that a token with an exp has not expired yet
+	if exp, ok := mapClaims["exp"].(float64); ok {
+		claims.ExpiresAt = int64(exp)
 	}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		userID := claims["user_id"].(string)
-		return userID, nil
-	} else {
-		return "", err
+	switch roles := mapClaims["roles"].(type) {
+	case []interface{}:
+		for _, role := range roles {
+			if r, ok := role.(string); ok {
+				claims.Roles = append(claims.Roles, r)
+			}
+		}
+	case string:
+		claims.Roles = strings.Fields(roles)
+	}
+
+	if role, ok := mapClaims["role"].(string); ok && role != "" {
+		claims.Roles = append(claims.Roles, role)
 	}
+
+	if len(claims.Roles) == 0 {
+		claims.Roles = []string{RoleCustomer}
+	}
+
+	return claims, nil
 }
@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+func TestValidateJWT(t *testing.T) {
+	secret := []byte("jwt-secret")
+	sign := func(claims jwt.MapClaims) string {
+		s, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	exp := time.Now().Add(time.Hour).Unix()
+
+	tests := []struct {
+		name    string
+		token   string
+		want    Claims
+		wantErr bool
+	}{
+		{name: "customer", token: sign(jwt.MapClaims{"user_id": "u1"}), want: Claims{UserID: "u1", Roles: []string{RoleCustomer}}},
+		{name: "roles and exp", token: sign(jwt.MapClaims{"user_id": "u1", "roles": []string{RoleAdmin}, "exp": exp}), want: Claims{UserID: "u1", Roles: []string{RoleAdmin}, ExpiresAt: exp}},
+		{name: "single role", token: sign(jwt.MapClaims{"user_id": "u1", "role": RoleFulfillment}), want: Claims{UserID: "u1", Roles: []string{RoleFulfillment}}},
+		{name: "expired", token: sign(jwt.MapClaims{"user_id": "u1", "exp": time.Now().Add(-time.Minute).Unix()}), wantErr: true},
+		{name: "no user", token: sign(jwt.MapClaims{"roles": []string{RoleAdmin}}), wantErr: true},
+		{name: "wrong secret", token: func() string {
+			s, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user_id": "u1"}).SignedString([]byte("other"))
+			return s
+		}(), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidateJWT(tt.token, secret)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ValidateJWT() = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ValidateJWT() unexpected error: %v", err)
+			}
+			if got.UserID != tt.want.UserID || !slices.Equal(got.Roles, tt.want.Roles) || got.ExpiresAt != tt.want.ExpiresAt {
+				t.Errorf("ValidateJWT() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClaimsExpired(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		expiresAt int64
+		want      bool
+	}{
+		{0, false},
+		{now.Unix() + 1, false},
+		{now.Unix(), false},
+		{now.Unix() - 1, true},
+	}
+
+	for _, tt := range tests {
+		if got := (&Claims{ExpiresAt: tt.expiresAt}).Expired(now); got != tt.want {
+			t.Errorf("Claims{ExpiresAt: %d}.Expired() = %v, want %v", tt.expiresAt, got, tt.want)
+		}
+	}
+}
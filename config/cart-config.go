@@ -9,10 +9,12 @@ import (
 )
 
 type CartConfig struct {
-	ServerPort uint16
-	DBURL      string
-	AuthSecret string
-	RedisURL   string
+	ServerPort        uint16
+	DBURL             string
+	AuthSecret        string
+	ServiceAuthSecret string
+	RedisURL          string
+	RABBITMQ_URL      string
 }
 
 func LoadCartConfig(log *zerolog.Logger) CartConfig {
@@ -34,11 +36,21 @@ func LoadCartConfig(log *zerolog.Logger) CartConfig {
 		cfg.DBURL = url
 	}
 
+	if rabbitmqUrl, exists := os.LookupEnv("RABBITMQ_URL"); exists {
+		cfg.RABBITMQ_URL = rabbitmqUrl
+	}
+
 	if secret, exists := os.LookupEnv("USER_AUTH_SECRET"); exists {
 		cfg.AuthSecret = secret
 	} else {
 		log.Fatal().Err(errors.New("USER_AUTH_SECRET is required")).Msg("failed to load config")
 	}
 
+	if secret := os.Getenv("SERVICE_AUTH_SECRET"); secret != "" {
+		cfg.ServiceAuthSecret = secret
+	} else {
+		log.Fatal().Err(errors.New("SERVICE_AUTH_SECRET is required")).Msg("failed to load config")
+	}
+
 	return cfg
 }
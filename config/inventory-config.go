@@ -1,8 +1,12 @@
 package config
 
 import (
+	"errors"
 	"os"
 	"strconv"
+	"time"
+
+	"github.com/rs/zerolog"
 )
 
 type InventoryConfig struct {
@@ -13,11 +17,24 @@ type InventoryConfig struct {
 	RABBITMQ_PORT     uint16
 	RABBITMQ_HOST     string
 	RABBITMQ_URL      string
+	AuthSecret        string
+	RedisURL          string
+	ServiceAuthSecret string
+
+	OutboxPollInterval time.Duration
+	OutboxBatchSize    int
+
+	ReservationTTL           time.Duration
+	ReservationSweepInterval time.Duration
 }
 
-func LoadInventoryConfig() InventoryConfig {
+func LoadInventoryConfig(log *zerolog.Logger) InventoryConfig {
 	cfg := InventoryConfig{
-		ServerPort: 3000,
+		ServerPort:               3000,
+		OutboxPollInterval:       time.Second,
+		OutboxBatchSize:          100,
+		ReservationTTL:           time.Minute * 15,
+		ReservationSweepInterval: time.Minute,
 	}
 
 	if serverPort, exists := os.LookupEnv("INVENTORY_SERVER_PORT"); exists {
@@ -48,5 +65,43 @@ func LoadInventoryConfig() InventoryConfig {
 		cfg.DBURL = url
 	}
 
+	if url, exists := os.LookupEnv("REDIS_URL"); exists {
+		cfg.RedisURL = url
+	}
+
+	if secret, exists := os.LookupEnv("USER_AUTH_SECRET"); exists {
+		cfg.AuthSecret = secret
+	} else {
+		log.Fatal().Err(errors.New("USER_AUTH_SECRET is required")).Msg("failed to load config")
+	}
+
+	if secret := os.Getenv("SERVICE_AUTH_SECRET"); secret != "" {
+		cfg.ServiceAuthSecret = secret
+	} else {
+		log.Fatal().Err(errors.New("SERVICE_AUTH_SECRET is required")).Msg("failed to load config")
+	}
+
+	if interval, exists := os.LookupEnv("OUTBOX_POLL_INTERVAL"); exists {
+		if d, err := time.ParseDuration(interval); err == nil && d > 0 {
+			cfg.OutboxPollInterval = d
+		}
+	}
+	if batchSize, exists := os.LookupEnv("OUTBOX_BATCH_SIZE"); exists {
+		if size, err := strconv.Atoi(batchSize); err == nil && size > 0 {
+			cfg.OutboxBatchSize = size
+		}
+	}
+
+	if ttl, exists := os.LookupEnv("RESERVATION_TTL"); exists {
+		if d, err := time.ParseDuration(ttl); err == nil {
+			cfg.ReservationTTL = d
+		}
+	}
+	if interval, exists := os.LookupEnv("RESERVATION_SWEEP_INTERVAL"); exists {
+		if d, err := time.ParseDuration(interval); err == nil && d > 0 {
+			cfg.ReservationSweepInterval = d
+		}
+	}
+
 	return cfg
 }
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/rs/zerolog"
 )
@@ -15,12 +16,52 @@ type OrderConfig struct {
 	ProdHttpBaseURL      string
 	CartHttpBaseURL      string
 	AuthSecret           string
-	RedisURL             string
+	ServiceAuthSecret    string
+	ServiceTokenTTL      time.Duration
+
+	HTTPClientTimeout          time.Duration
+	HTTPClientMaxRetries       int
+	HTTPClientRetryBackoff     time.Duration
+	HTTPClientMaxRetryBackoff  time.Duration
+	HTTPClientBreakerThreshold int
+	HTTPClientBreakerCooldown  time.Duration
+	RedisURL                   string
+	RABBITMQ_URL               string
+	OutboxPollInterval         time.Duration
+	OutboxBatchSize            int
+
+	// unfinished order sagas that have not moved for SagaStaleAfter are resumed, looked for
+	// every SagaResumeInterval
+	SagaResumeInterval time.Duration
+	SagaStaleAfter     time.Duration
+
+	PaymentGateway       string
+	PaymentWebhookSecret string
+	// payment settlements that have not been carried out for PaymentSettleRetryAfter, e.g.
+	// because the gateway was down, are retried, looked for every PaymentSettleInterval
+	PaymentSettleInterval   time.Duration
+	PaymentSettleRetryAfter time.Duration
 }
 
 func LoadOrderConfig(log *zerolog.Logger) OrderConfig {
 	cfg := OrderConfig{
-		ServerPort: 3000,
+		ServerPort:         3000,
+		OutboxPollInterval: time.Second,
+		OutboxBatchSize:    100,
+		ServiceTokenTTL:    time.Minute,
+		SagaResumeInterval: time.Minute,
+		SagaStaleAfter:     time.Minute * 5,
+
+		HTTPClientTimeout:          time.Second * 5,
+		HTTPClientMaxRetries:       2,
+		HTTPClientRetryBackoff:     time.Millisecond * 100,
+		HTTPClientMaxRetryBackoff:  time.Second * 2,
+		HTTPClientBreakerThreshold: 5,
+		HTTPClientBreakerCooldown:  time.Second * 30,
+
+		PaymentGateway:          "fake",
+		PaymentSettleInterval:   time.Minute,
+		PaymentSettleRetryAfter: time.Minute * 5,
 	}
 
 	if serverPort, exists := os.LookupEnv("ORDER_SERVER_PORT"); exists {
@@ -37,12 +78,107 @@ func LoadOrderConfig(log *zerolog.Logger) OrderConfig {
 		cfg.DBURL = url
 	}
 
+	if rabbitmqUrl, exists := os.LookupEnv("RABBITMQ_URL"); exists {
+		cfg.RABBITMQ_URL = rabbitmqUrl
+	} else {
+		log.Fatal().Err(errors.New("RABBITMQ_URL is required")).Msg("failed to load config")
+	}
+
+	if interval, exists := os.LookupEnv("OUTBOX_POLL_INTERVAL"); exists {
+		if d, err := time.ParseDuration(interval); err == nil && d > 0 {
+			cfg.OutboxPollInterval = d
+		}
+	}
+	if batchSize, exists := os.LookupEnv("OUTBOX_BATCH_SIZE"); exists {
+		if size, err := strconv.Atoi(batchSize); err == nil && size > 0 {
+			cfg.OutboxBatchSize = size
+		}
+	}
+
+	if interval, exists := os.LookupEnv("ORDER_SAGA_RESUME_INTERVAL"); exists {
+		if d, err := time.ParseDuration(interval); err == nil && d > 0 {
+			cfg.SagaResumeInterval = d
+		}
+	}
+	if staleAfter, exists := os.LookupEnv("ORDER_SAGA_STALE_AFTER"); exists {
+		if d, err := time.ParseDuration(staleAfter); err == nil && d > 0 {
+			cfg.SagaStaleAfter = d
+		}
+	}
+
+	if timeout, exists := os.LookupEnv("HTTP_CLIENT_TIMEOUT"); exists {
+		if d, err := time.ParseDuration(timeout); err == nil && d > 0 {
+			cfg.HTTPClientTimeout = d
+		}
+	}
+	if retries, exists := os.LookupEnv("HTTP_CLIENT_MAX_RETRIES"); exists {
+		if n, err := strconv.Atoi(retries); err == nil && n >= 0 {
+			cfg.HTTPClientMaxRetries = n
+		}
+	}
+	if backoff, exists := os.LookupEnv("HTTP_CLIENT_RETRY_BACKOFF"); exists {
+		if d, err := time.ParseDuration(backoff); err == nil && d >= 0 {
+			cfg.HTTPClientRetryBackoff = d
+		}
+	}
+	if backoff, exists := os.LookupEnv("HTTP_CLIENT_MAX_RETRY_BACKOFF"); exists {
+		if d, err := time.ParseDuration(backoff); err == nil && d >= 0 {
+			cfg.HTTPClientMaxRetryBackoff = d
+		}
+	}
+	if threshold, exists := os.LookupEnv("HTTP_CLIENT_BREAKER_THRESHOLD"); exists {
+		if n, err := strconv.Atoi(threshold); err == nil && n > 0 {
+			cfg.HTTPClientBreakerThreshold = n
+		}
+	}
+	if cooldown, exists := os.LookupEnv("HTTP_CLIENT_BREAKER_COOLDOWN"); exists {
+		if d, err := time.ParseDuration(cooldown); err == nil && d > 0 {
+			cfg.HTTPClientBreakerCooldown = d
+		}
+	}
+
 	if secret, exists := os.LookupEnv("USER_AUTH_SECRET"); exists {
 		cfg.AuthSecret = secret
 	} else {
 		log.Fatal().Err(errors.New("USER_AUTH_SECRET is required")).Msg("failed to load config")
 	}
 
+	if secret := os.Getenv("SERVICE_AUTH_SECRET"); secret != "" {
+		cfg.ServiceAuthSecret = secret
+	} else {
+		log.Fatal().Err(errors.New("SERVICE_AUTH_SECRET is required")).Msg("failed to load config")
+	}
+	if ttl, exists := os.LookupEnv("SERVICE_TOKEN_TTL"); exists {
+		if d, err := time.ParseDuration(ttl); err == nil && d > 0 {
+			cfg.ServiceTokenTTL = d
+		}
+	}
+
+	if gateway, exists := os.LookupEnv("PAYMENT_GATEWAY"); exists {
+		switch gateway {
+		case "fake":
+			cfg.PaymentGateway = gateway
+		default:
+			log.Fatal().Err(errors.New("PAYMENT_GATEWAY must be fake")).Msg("failed to load config")
+		}
+	}
+	if secret := os.Getenv("PAYMENT_WEBHOOK_SECRET"); secret != "" {
+		cfg.PaymentWebhookSecret = secret
+	} else {
+		log.Fatal().Err(errors.New("PAYMENT_WEBHOOK_SECRET is required")).Msg("failed to load config")
+	}
+
+	if interval, exists := os.LookupEnv("PAYMENT_SETTLE_INTERVAL"); exists {
+		if d, err := time.ParseDuration(interval); err == nil && d > 0 {
+			cfg.PaymentSettleInterval = d
+		}
+	}
+	if retryAfter, exists := os.LookupEnv("PAYMENT_SETTLE_RETRY_AFTER"); exists {
+		if d, err := time.ParseDuration(retryAfter); err == nil && d > 0 {
+			cfg.PaymentSettleRetryAfter = d
+		}
+	}
+
 	if url, exists := os.LookupEnv("PRODUCT_BASE_URL"); exists {
 		cfg.ProdHttpBaseURL = url
 	} else {
@@ -1,8 +1,12 @@
 package config
 
 import (
+	"errors"
 	"os"
 	"strconv"
+	"time"
+
+	"github.com/rs/zerolog"
 )
 
 type ProductConfig struct {
@@ -13,11 +17,41 @@ type ProductConfig struct {
 	RABBITMQ_PORT     uint16
 	RABBITMQ_HOST     string
 	RABBITMQ_URL      string
+	AuthSecret        string
+	RedisURL          string
+
+	OutboxPollInterval time.Duration
+	OutboxBatchSize    int
+
+	// deleted products are hard-deleted once they have been in the trash for PurgeRetention
+	PurgeRetention time.Duration
+	PurgeInterval  time.Duration
+
+	// PriceScheduleInterval is how often scheduled prices and sales are activated and reverted.
+	PriceScheduleInterval time.Duration
+
+	// BlobStore selects where product images are kept: "local" or "s3".
+	BlobStore    string
+	BlobLocalDir string
+	MediaBaseURL string
+	S3Endpoint   string
+	S3Region     string
+	S3Bucket     string
+	S3AccessKey  string
+	S3SecretKey  string
 }
 
-func LoadProductConfig() ProductConfig {
+func LoadProductConfig(log *zerolog.Logger) ProductConfig {
 	cfg := ProductConfig{
-		ServerPort: 3000,
+		ServerPort:            3000,
+		OutboxPollInterval:    time.Second,
+		OutboxBatchSize:       100,
+		PurgeRetention:        30 * 24 * time.Hour,
+		PurgeInterval:         time.Hour,
+		PriceScheduleInterval: time.Minute,
+		BlobStore:             "local",
+		BlobLocalDir:          "./data/blobs",
+		MediaBaseURL:          "/api/v1/products/media",
 	}
 
 	if serverPort, exists := os.LookupEnv("PRODUCT_SERVER_PORT"); exists {
@@ -48,5 +82,73 @@ func LoadProductConfig() ProductConfig {
 		cfg.DBURL = url
 	}
 
+	if url, exists := os.LookupEnv("REDIS_URL"); exists {
+		cfg.RedisURL = url
+	}
+
+	if secret, exists := os.LookupEnv("USER_AUTH_SECRET"); exists {
+		cfg.AuthSecret = secret
+	} else {
+		log.Fatal().Err(errors.New("USER_AUTH_SECRET is required")).Msg("failed to load config")
+	}
+
+	if interval, exists := os.LookupEnv("OUTBOX_POLL_INTERVAL"); exists {
+		if d, err := time.ParseDuration(interval); err == nil && d > 0 {
+			cfg.OutboxPollInterval = d
+		}
+	}
+	if batchSize, exists := os.LookupEnv("OUTBOX_BATCH_SIZE"); exists {
+		if size, err := strconv.Atoi(batchSize); err == nil && size > 0 {
+			cfg.OutboxBatchSize = size
+		}
+	}
+
+	if retention, exists := os.LookupEnv("PRODUCT_PURGE_RETENTION"); exists {
+		if d, err := time.ParseDuration(retention); err == nil && d > 0 {
+			cfg.PurgeRetention = d
+		}
+	}
+	if interval, exists := os.LookupEnv("PRODUCT_PURGE_INTERVAL"); exists {
+		if d, err := time.ParseDuration(interval); err == nil && d > 0 {
+			cfg.PurgeInterval = d
+		}
+	}
+
+	if interval, exists := os.LookupEnv("PRICE_SCHEDULE_INTERVAL"); exists {
+		if d, err := time.ParseDuration(interval); err == nil && d > 0 {
+			cfg.PriceScheduleInterval = d
+		}
+	}
+
+	if store, exists := os.LookupEnv("BLOB_STORE"); exists {
+		switch store {
+		case "local", "s3":
+			cfg.BlobStore = store
+		default:
+			log.Fatal().Err(errors.New("BLOB_STORE must be local or s3")).Msg("failed to load config")
+		}
+	}
+	if dir, exists := os.LookupEnv("BLOB_LOCAL_DIR"); exists {
+		cfg.BlobLocalDir = dir
+	}
+	if url, exists := os.LookupEnv("MEDIA_BASE_URL"); exists {
+		cfg.MediaBaseURL = url
+	}
+	if endpoint, exists := os.LookupEnv("S3_ENDPOINT"); exists {
+		cfg.S3Endpoint = endpoint
+	}
+	if region, exists := os.LookupEnv("S3_REGION"); exists {
+		cfg.S3Region = region
+	}
+	if bucket, exists := os.LookupEnv("S3_BUCKET"); exists {
+		cfg.S3Bucket = bucket
+	}
+	if key, exists := os.LookupEnv("S3_ACCESS_KEY_ID"); exists {
+		cfg.S3AccessKey = key
+	}
+	if secret, exists := os.LookupEnv("S3_SECRET_ACCESS_KEY"); exists {
+		cfg.S3SecretKey = secret
+	}
+
 	return cfg
 }
@@ -0,0 +1,15 @@
+CREATE TYPE order_saga_status AS ENUM ('started', 'stock_reserved', 'order_created', 'completed', 'compensating', 'compensated');
+
+CREATE TABLE IF NOT EXISTS order_sagas (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    order_id INTEGER,
+    status order_saga_status NOT NULL DEFAULT 'started',
+    payload JSONB NOT NULL,
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS order_sagas_status_idx ON order_sagas (status);
@@ -0,0 +1,17 @@
+CREATE TYPE reservation_status AS ENUM ('held', 'committed', 'released', 'expired');
+
+CREATE TABLE IF NOT EXISTS inventory_reservations (
+    id SERIAL PRIMARY KEY,
+    reference VARCHAR(100) NOT NULL,
+    product_id INTEGER NOT NULL,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    status reservation_status NOT NULL DEFAULT 'held',
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (product_id) REFERENCES inventory_items(product_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS inventory_reservations_reference_idx ON inventory_reservations (reference);
+CREATE INDEX IF NOT EXISTS inventory_reservations_held_idx ON inventory_reservations (product_id, expires_at) WHERE status = 'held';
+CREATE UNIQUE INDEX IF NOT EXISTS inventory_reservations_held_unique_idx ON inventory_reservations (reference, product_id) WHERE status = 'held';
@@ -0,0 +1 @@
+ALTER TABLE inventory_items DROP COLUMN IF EXISTS active;
@@ -0,0 +1 @@
+ALTER TABLE inventory_items ADD COLUMN IF NOT EXISTS active BOOLEAN NOT NULL DEFAULT TRUE;
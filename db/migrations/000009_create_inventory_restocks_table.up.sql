@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS inventory_restocks (
+    order_id INTEGER PRIMARY KEY,
+    reason VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,5 @@
+CREATE INDEX IF NOT EXISTS products_created_at_id_idx ON products (created_at, id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS products_price_id_idx ON products (price, id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS products_name_id_idx ON products (name, id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS products_category_id_idx ON products (category_id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS products_sku_pattern_idx ON products (sku text_pattern_ops);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS categories_parent_category_id_idx;
+ALTER TABLE categories DROP CONSTRAINT IF EXISTS categories_parent_not_self;
+ALTER TABLE categories DROP COLUMN IF EXISTS parent_category_id;
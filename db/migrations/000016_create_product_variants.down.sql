@@ -0,0 +1,18 @@
+ALTER TABLE order_items DROP COLUMN IF EXISTS variant_id;
+ALTER TABLE cart_items DROP COLUMN IF EXISTS variant_id;
+
+DROP INDEX IF EXISTS inventory_reservations_held_unique_idx;
+DROP INDEX IF EXISTS inventory_reservations_held_idx;
+ALTER TABLE inventory_reservations DROP CONSTRAINT IF EXISTS inventory_reservations_product_variant_fkey;
+ALTER TABLE inventory_items DROP CONSTRAINT IF EXISTS inventory_items_product_variant_key;
+DELETE FROM inventory_reservations WHERE variant_id <> 0;
+DELETE FROM inventory_items WHERE variant_id <> 0;
+ALTER TABLE inventory_reservations DROP COLUMN IF EXISTS variant_id;
+ALTER TABLE inventory_items DROP COLUMN IF EXISTS variant_id;
+ALTER TABLE inventory_items ADD CONSTRAINT inventory_items_product_id_key UNIQUE (product_id);
+ALTER TABLE inventory_reservations ADD CONSTRAINT inventory_reservations_product_id_fkey
+    FOREIGN KEY (product_id) REFERENCES inventory_items(product_id) ON DELETE CASCADE;
+CREATE INDEX IF NOT EXISTS inventory_reservations_held_idx ON inventory_reservations (product_id, expires_at) WHERE status = 'held';
+CREATE UNIQUE INDEX IF NOT EXISTS inventory_reservations_held_unique_idx ON inventory_reservations (reference, product_id) WHERE status = 'held';
+
+DROP TABLE IF EXISTS product_variants;
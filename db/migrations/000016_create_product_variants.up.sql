@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS product_variants (
+    id              SERIAL PRIMARY KEY,
+    product_id      INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    sku             VARCHAR(100) NOT NULL UNIQUE,
+    options         JSONB NOT NULL DEFAULT '{}',
+    price           DECIMAL(10,2),
+    image_urls      JSONB NOT NULL DEFAULT '[]',
+    created_at      TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at      TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at      TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS product_variants_product_id_idx ON product_variants (product_id) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS product_variants_options_idx ON product_variants (product_id, options) WHERE deleted_at IS NULL;
+
+-- stock is kept per variant; variant_id 0 is the product itself
+ALTER TABLE inventory_items ADD COLUMN IF NOT EXISTS variant_id INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE inventory_reservations ADD COLUMN IF NOT EXISTS variant_id INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE inventory_reservations DROP CONSTRAINT IF EXISTS inventory_reservations_product_id_fkey;
+ALTER TABLE inventory_items DROP CONSTRAINT IF EXISTS inventory_items_product_id_key;
+ALTER TABLE inventory_items ADD CONSTRAINT inventory_items_product_variant_key UNIQUE (product_id, variant_id);
+ALTER TABLE inventory_reservations ADD CONSTRAINT inventory_reservations_product_variant_fkey
+    FOREIGN KEY (product_id, variant_id) REFERENCES inventory_items(product_id, variant_id) ON DELETE CASCADE;
+
+DROP INDEX IF EXISTS inventory_reservations_held_idx;
+DROP INDEX IF EXISTS inventory_reservations_held_unique_idx;
+CREATE INDEX IF NOT EXISTS inventory_reservations_held_idx ON inventory_reservations (product_id, variant_id, expires_at) WHERE status = 'held';
+CREATE UNIQUE INDEX IF NOT EXISTS inventory_reservations_held_unique_idx ON inventory_reservations (reference, product_id, variant_id) WHERE status = 'held';
+
+ALTER TABLE cart_items ADD COLUMN IF NOT EXISTS variant_id INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS variant_id INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+ALTER TABLE products ALTER COLUMN image_url SET DEFAULT 'https://picsum.photos/seed/picsum/200/300';
+
+DROP TABLE IF EXISTS product_images;
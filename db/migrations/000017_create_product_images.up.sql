@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS product_images (
+    id              SERIAL PRIMARY KEY,
+    product_id      INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    position        INTEGER NOT NULL,
+    blob_key        VARCHAR(255) NOT NULL UNIQUE,
+    thumbnail_key   VARCHAR(255) NOT NULL UNIQUE,
+    url             VARCHAR(255) NOT NULL,
+    thumbnail_url   VARCHAR(255) NOT NULL,
+    content_type    VARCHAR(50) NOT NULL,
+    size_bytes      INTEGER NOT NULL,
+    width           INTEGER NOT NULL,
+    height          INTEGER NOT NULL,
+    created_at      TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS product_images_product_id_idx ON product_images (product_id, position);
+
+-- image_url now mirrors the first uploaded image instead of a placeholder
+ALTER TABLE products ALTER COLUMN image_url SET DEFAULT '';
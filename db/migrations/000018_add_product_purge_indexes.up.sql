@@ -0,0 +1,3 @@
+CREATE INDEX IF NOT EXISTS products_deleted_at_idx ON products (deleted_at, id) WHERE deleted_at IS NOT NULL;
+-- purges check that no order item references a product
+CREATE INDEX IF NOT EXISTS order_items_product_id_idx ON order_items (product_id);
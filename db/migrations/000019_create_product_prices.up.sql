@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS product_prices (
+    id              SERIAL PRIMARY KEY,
+    product_id      INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    price           DECIMAL(10,2) NOT NULL CHECK (price > 0),
+    currency        CHAR(3) NOT NULL,
+    -- a price without ends_at replaces the base price; one with ends_at is a sale that reverts
+    starts_at       TIMESTAMP WITH TIME ZONE NOT NULL,
+    ends_at         TIMESTAMP WITH TIME ZONE CHECK (ends_at > starts_at),
+    applied_at      TIMESTAMP WITH TIME ZONE,
+    reverted_at     TIMESTAMP WITH TIME ZONE,
+    created_at      TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS product_prices_product_id_idx ON product_prices (product_id, starts_at);
+CREATE INDEX IF NOT EXISTS product_prices_pending_idx ON product_prices (starts_at) WHERE applied_at IS NULL;
+CREATE INDEX IF NOT EXISTS product_prices_ending_idx ON product_prices (ends_at) WHERE ends_at IS NOT NULL AND reverted_at IS NULL;
+
+-- the current prices start the history
+INSERT INTO product_prices (product_id, price, currency, starts_at, applied_at)
+SELECT id, price, currency, coalesce(created_at, now()), coalesce(created_at, now())
+FROM products;
@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS order_item_discounts;
+DROP TABLE IF EXISTS order_discounts;
+
+ALTER TABLE order_items DROP COLUMN IF EXISTS discount;
+
+ALTER TABLE orders DROP COLUMN IF EXISTS free_shipping;
+ALTER TABLE orders DROP COLUMN IF EXISTS coupon_code;
+ALTER TABLE orders DROP COLUMN IF EXISTS discount_total;
+ALTER TABLE orders DROP COLUMN IF EXISTS subtotal;
+
+DROP TABLE IF EXISTS promotions;
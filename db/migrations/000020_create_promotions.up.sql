@@ -0,0 +1,60 @@
+CREATE TABLE IF NOT EXISTS promotions (
+    id              SERIAL PRIMARY KEY,
+    name            VARCHAR(255) NOT NULL,
+    -- promotions without a code apply to every eligible order, the others need the coupon code
+    code            VARCHAR(50),
+    type            VARCHAR(20) NOT NULL CHECK (type IN ('percentage', 'fixed_amount', 'buy_x_get_y', 'free_shipping')),
+    percent_off     INTEGER CHECK (percent_off BETWEEN 1 AND 100),
+    amount_off      DECIMAL(10,2) CHECK (amount_off > 0),
+    buy_quantity    INTEGER CHECK (buy_quantity > 0),
+    get_quantity    INTEGER CHECK (get_quantity > 0),
+    min_subtotal    DECIMAL(10,2),
+    -- currency of amount_off and min_subtotal
+    currency        CHAR(3) NOT NULL DEFAULT 'USD',
+    -- empty scopes match every product
+    product_ids     INTEGER[] NOT NULL DEFAULT '{}',
+    category_ids    INTEGER[] NOT NULL DEFAULT '{}',
+    starts_at       TIMESTAMP WITH TIME ZONE,
+    ends_at         TIMESTAMP WITH TIME ZONE CHECK (ends_at > starts_at),
+    usage_limit     INTEGER CHECK (usage_limit > 0),
+    per_user_limit  INTEGER CHECK (per_user_limit > 0),
+    active          BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at      TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at      TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS promotions_code_idx ON promotions (upper(code)) WHERE code IS NOT NULL;
+CREATE INDEX IF NOT EXISTS promotions_automatic_idx ON promotions (id) WHERE code IS NULL AND active;
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS subtotal DECIMAL(10,2);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS discount_total DECIMAL(10,2) NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS coupon_code VARCHAR(50);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS free_shipping BOOLEAN NOT NULL DEFAULT FALSE;
+UPDATE orders SET subtotal = total_price WHERE subtotal IS NULL;
+ALTER TABLE orders ALTER COLUMN subtotal SET NOT NULL;
+
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS discount DECIMAL(10,2) NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS order_discounts (
+    id              SERIAL PRIMARY KEY,
+    order_id        INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    promotion_id    INTEGER NOT NULL REFERENCES promotions(id),
+    -- promotion details at the time the order was placed
+    code            VARCHAR(50),
+    name            VARCHAR(255) NOT NULL,
+    type            VARCHAR(20) NOT NULL,
+    amount          DECIMAL(10,2) NOT NULL,
+    created_at      TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (order_id, promotion_id)
+);
+
+CREATE INDEX IF NOT EXISTS order_discounts_promotion_id_idx ON order_discounts (promotion_id);
+
+CREATE TABLE IF NOT EXISTS order_item_discounts (
+    id                  SERIAL PRIMARY KEY,
+    order_discount_id   INTEGER NOT NULL REFERENCES order_discounts(id) ON DELETE CASCADE,
+    order_item_id       INTEGER NOT NULL REFERENCES order_items(id) ON DELETE CASCADE,
+    amount              DECIMAL(10,2) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS order_item_discounts_order_item_id_idx ON order_item_discounts (order_item_id);
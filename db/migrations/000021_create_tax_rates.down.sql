@@ -0,0 +1,9 @@
+ALTER TABLE order_items DROP COLUMN IF EXISTS tax_name;
+ALTER TABLE order_items DROP COLUMN IF EXISTS tax_rate_id;
+ALTER TABLE order_items DROP COLUMN IF EXISTS tax_rate;
+ALTER TABLE order_items DROP COLUMN IF EXISTS tax;
+
+ALTER TABLE orders DROP COLUMN IF EXISTS tax_total;
+
+DROP TABLE IF EXISTS tax_rate_exemptions;
+DROP TABLE IF EXISTS tax_rates;
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS tax_rates (
+    id              SERIAL PRIMARY KEY,
+    country         CHAR(2) NOT NULL,
+    -- an empty state is the rate of the whole country
+    state           VARCHAR(100) NOT NULL DEFAULT '',
+    name            VARCHAR(100) NOT NULL,
+    -- percent of the taxed amount, e.g. 7.2500
+    rate            DECIMAL(7,4) NOT NULL CHECK (rate >= 0 AND rate <= 100),
+    created_at      TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at      TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS tax_rates_region_idx ON tax_rates (country, upper(state));
+
+CREATE TABLE IF NOT EXISTS tax_rate_exemptions (
+    tax_rate_id     INTEGER NOT NULL REFERENCES tax_rates(id) ON DELETE CASCADE,
+    category_id     INTEGER NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+    PRIMARY KEY (tax_rate_id, category_id)
+);
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS tax_total DECIMAL(10,2) NOT NULL DEFAULT 0;
+
+-- the rate each line was taxed at, kept as it was when the order was placed
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS tax DECIMAL(10,2) NOT NULL DEFAULT 0;
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS tax_rate DECIMAL(7,4) NOT NULL DEFAULT 0;
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS tax_rate_id INTEGER;
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS tax_name VARCHAR(100) NOT NULL DEFAULT '';
@@ -0,0 +1,10 @@
+ALTER TABLE orders DROP COLUMN IF EXISTS shipped_at;
+ALTER TABLE orders DROP COLUMN IF EXISTS tracking_number;
+ALTER TABLE orders DROP COLUMN IF EXISTS carrier;
+ALTER TABLE orders DROP COLUMN IF EXISTS shipping_total;
+ALTER TABLE orders DROP COLUMN IF EXISTS shipping_method;
+ALTER TABLE orders DROP COLUMN IF EXISTS shipping_method_id;
+
+DROP TABLE IF EXISTS shipping_methods;
+
+ALTER TABLE products DROP COLUMN IF EXISTS weight_grams;
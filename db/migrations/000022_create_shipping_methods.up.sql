@@ -0,0 +1,25 @@
+ALTER TABLE products ADD COLUMN IF NOT EXISTS weight_grams INTEGER NOT NULL DEFAULT 0 CHECK (weight_grams >= 0);
+
+CREATE TABLE IF NOT EXISTS shipping_methods (
+    id              SERIAL PRIMARY KEY,
+    name            VARCHAR(100) NOT NULL,
+    type            VARCHAR(20) NOT NULL CHECK (type IN ('flat_rate', 'weight_tiered', 'price_tiered')),
+    -- the charge of flat rate methods; tiered methods keep theirs in tiers
+    price           DECIMAL(10,2),
+    currency        CHAR(3) NOT NULL DEFAULT 'USD',
+    tiers           JSONB NOT NULL DEFAULT '[]',
+    free_over       DECIMAL(10,2),
+    -- ISO 3166-1 alpha-2 codes the method ships to; empty ships everywhere
+    countries       JSONB NOT NULL DEFAULT '[]',
+    active          BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at      TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at      TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- the method an order ships with, kept as it was when the order was placed
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_method_id INTEGER REFERENCES shipping_methods(id) ON DELETE SET NULL;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_method VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_total DECIMAL(10,2) NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS carrier VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS tracking_number VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipped_at TIMESTAMP WITH TIME ZONE;
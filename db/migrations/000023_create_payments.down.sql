@@ -0,0 +1,3 @@
+-- postgres cannot drop the 'payment_authorized' value of order_saga_status; it is left in place
+DROP TABLE IF EXISTS payment_events;
+DROP TABLE IF EXISTS payments;
@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS payments (
+    id              SERIAL PRIMARY KEY,
+    order_id        INTEGER NOT NULL UNIQUE REFERENCES orders(id) ON DELETE CASCADE,
+    gateway         VARCHAR(50) NOT NULL,
+    -- the id of the payment at the gateway
+    reference       VARCHAR(255) NOT NULL UNIQUE,
+    status          VARCHAR(20) NOT NULL CHECK (status IN ('pending', 'authorized', 'captured', 'voided', 'refunded', 'failed')),
+    amount          DECIMAL(10,2) NOT NULL,
+    captured_amount DECIMAL(10,2) NOT NULL DEFAULT 0,
+    refunded_amount DECIMAL(10,2) NOT NULL DEFAULT 0,
+    currency        CHAR(3) NOT NULL DEFAULT 'USD',
+    failure_reason  TEXT NOT NULL DEFAULT '',
+    created_at      TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at      TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- webhook events already handled, so redeliveries are ignored
+CREATE TABLE IF NOT EXISTS payment_events (
+    id              VARCHAR(255) PRIMARY KEY,
+    payment_id      INTEGER NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+    type            VARCHAR(50) NOT NULL,
+    created_at      TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TYPE order_saga_status ADD VALUE IF NOT EXISTS 'payment_authorized' AFTER 'order_created';
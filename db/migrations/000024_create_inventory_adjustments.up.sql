@@ -0,0 +1,7 @@
+-- batch adjustments made with a reference are applied, and reversed, at most once
+CREATE TABLE IF NOT EXISTS inventory_adjustments (
+    reference   VARCHAR(100) PRIMARY KEY,
+    items       JSONB NOT NULL,
+    reversed_at TIMESTAMP WITH TIME ZONE,
+    created_at  TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,14 @@
+-- status changes that move money are recorded here with the change, and settled at the
+-- payment gateway once it has committed
+CREATE TABLE IF NOT EXISTS order_payment_settlements (
+    id          SERIAL PRIMARY KEY,
+    order_id    INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    status      order_status NOT NULL,
+    attempts    INTEGER NOT NULL DEFAULT 0,
+    last_error  TEXT,
+    settled_at  TIMESTAMP WITH TIME ZONE,
+    created_at  TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at  TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS order_payment_settlements_pending_idx ON order_payment_settlements (updated_at) WHERE settled_at IS NULL;
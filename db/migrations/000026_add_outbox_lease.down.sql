@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS outbox_failed_idx;
+DROP INDEX IF EXISTS outbox_pending_idx;
+CREATE INDEX IF NOT EXISTS outbox_pending_idx ON outbox (next_attempt_at, id) WHERE dispatched_at IS NULL;
+
+ALTER TABLE outbox DROP COLUMN IF EXISTS failed_at;
+ALTER TABLE outbox DROP COLUMN IF EXISTS locked_until;
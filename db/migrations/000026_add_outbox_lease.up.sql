@@ -0,0 +1,10 @@
+-- relays lease the rows they publish instead of keeping them locked while they talk to the broker
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP WITH TIME ZONE;
+-- set once a message has failed every attempt; it is then left for an operator to look at
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS failed_at TIMESTAMP WITH TIME ZONE;
+
+UPDATE outbox SET failed_at = now() WHERE dispatched_at IS NULL AND attempts >= 10;
+
+DROP INDEX IF EXISTS outbox_pending_idx;
+CREATE INDEX IF NOT EXISTS outbox_pending_idx ON outbox (next_attempt_at, id) WHERE dispatched_at IS NULL AND failed_at IS NULL;
+CREATE INDEX IF NOT EXISTS outbox_failed_idx ON outbox (failed_at) WHERE failed_at IS NOT NULL;
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -9,7 +10,7 @@ import (
 )
 
 type AuthService interface {
-	ValidateJWT(ctx context.Context, token string) (string, error)
+	ValidateJWT(ctx context.Context, token string) (*utils.Claims, error)
 }
 
 type authService struct {
@@ -26,25 +27,58 @@ func NewAuthService(r *redis.Client, jwtSecret string, tokenExpiration time.Dura
 	}
 }
 
-func (a *authService) ValidateJWT(ctx context.Context, token string) (string, error) {
+func (a *authService) ValidateJWT(ctx context.Context, token string) (*utils.Claims, error) {
 	// 1. Check Redis Cache
-	userID, err := a.cache.Get(ctx, token).Result()
+	cached, err := a.cache.Get(ctx, token).Result()
 	if err == nil {
-		return userID, nil
+		var claims utils.Claims
+		// entries cached before claims were structured hold a bare user id, revalidate those
+		if json.Unmarshal([]byte(cached), &claims) == nil && claims.UserID != "" && !claims.Expired(time.Now()) {
+			return &claims, nil
+		}
 	} else if err != redis.Nil {
-		return "", err
+		return nil, err
 	}
 
 	// 2. Cache Miss - Perform full validation
-	userID, err = utils.ValidateJWT(token, a.authSecret)
+	claims, err := utils.ValidateJWT(token, a.authSecret)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	// 3. If valid, store in Redis with expiration
-	err = a.cache.Set(ctx, token, userID, a.expiration).Err()
+	// 3. If valid, store in Redis until the token expires, at most for the configured expiration
+	ttl := cacheTTL(claims, a.expiration, time.Now())
+	if ttl <= 0 {
+		return claims, nil
+	}
+
+	b, err := json.Marshal(claims)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	return userID, nil
+
+	err = a.cache.Set(ctx, token, b, ttl).Err()
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// cacheTTL is how long the claims of a token may be cached at now: expiration, cut short by the
+// token's own expiry. Claims of a token that expires by now are not cached.
+func cacheTTL(claims *utils.Claims, expiration time.Duration, now time.Time) time.Duration {
+	if claims.ExpiresAt == 0 {
+		return expiration
+	}
+
+	untilExpiry := time.Unix(claims.ExpiresAt, 0).Sub(now)
+	if untilExpiry <= 0 {
+		return 0
+	}
+
+	if expiration <= 0 || untilExpiry < expiration {
+		return untilExpiry
+	}
+
+	return expiration
 }
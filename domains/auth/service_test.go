@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rovilay/ecommerce-service/common/utils"
+)
+
+func TestCacheTTL(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name       string
+		expiresAt  int64
+		expiration time.Duration
+		want       time.Duration
+	}{
+		{name: "no exp", expiration: time.Hour, want: time.Hour},
+		{name: "exp after the expiration", expiresAt: now.Add(2 * time.Hour).Unix(), expiration: time.Hour, want: time.Hour},
+		{name: "exp before the expiration", expiresAt: now.Add(10 * time.Minute).Unix(), expiration: time.Hour, want: 10 * time.Minute},
+		{name: "exp without an expiration", expiresAt: now.Add(10 * time.Minute).Unix(), want: 10 * time.Minute},
+		{name: "expires now", expiresAt: now.Unix(), expiration: time.Hour, want: 0},
+		{name: "expired", expiresAt: now.Add(-time.Minute).Unix(), expiration: time.Hour, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := &utils.Claims{UserID: "u", ExpiresAt: tt.expiresAt}
+			if got := cacheTTL(claims, tt.expiration, now); got != tt.want {
+				t.Errorf("cacheTTL() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package eventhandlers
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/rovilay/ecommerce-service/common/events"
+	eventdatatypes "github.com/rovilay/ecommerce-service/common/events/datatypes"
+)
+
+func (h *HandlerClient) ProductDeleted(ctx context.Context, e events.EventData) error {
+	p := eventdatatypes.ProductDeleted{}
+	if err := json.Unmarshal(e.Data, &p); err != nil {
+		h.log.Err(err).Msg("Failed to unmarshal event data")
+		return err
+	}
+
+	// deleted products can no longer be bought, purge them from every cart
+	removed, err := h.repo.RemoveProductFromCarts(ctx, p.ID)
+	if err != nil {
+		return err
+	}
+
+	h.log.Info().Int("product_id", p.ID).Int64("removed", removed).Msg("purged deleted product from carts")
+
+	return nil
+}
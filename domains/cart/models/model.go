@@ -4,21 +4,47 @@ import (
 	"encoding/json"
 	"io"
 	"time"
+
+	"github.com/rovilay/ecommerce-service/common/money"
 )
 
 type Cart struct {
 	ID        int        `json:"id"`
 	UserID    string     `json:"user_id"`
 	CartItems []CartItem `json:"cart_items"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at"`
+	// Subtotal is left out when the items are priced in different currencies.
+	Subtotal  *money.Money `json:"subtotal,omitempty"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
 }
 
 type CartItem struct {
-	ID        int `json:"id"`
-	CartID    int `json:"cart_id"`
-	ProductID int `json:"product_id"`
-	Quantity  int `json:"quantity"`
+	ID        int         `json:"id"`
+	CartID    int         `json:"cart_id"`
+	ProductID int         `json:"product_id"`
+	VariantID int         `json:"variant_id"` // 0 when the product has no variants
+	Quantity  int         `json:"quantity"`
+	UnitPrice money.Money `json:"unit_price"` // current product or variant price
+}
+
+// CalculateSubtotal sets the cart subtotal from the current unit prices of its items.
+func (c *Cart) CalculateSubtotal() {
+	c.Subtotal = nil
+	if len(c.CartItems) == 0 {
+		return
+	}
+
+	lines := make([]money.Money, 0, len(c.CartItems))
+	for _, item := range c.CartItems {
+		lines = append(lines, item.UnitPrice.Mul(item.Quantity))
+	}
+
+	subtotal, err := money.Sum(c.CartItems[0].UnitPrice.Currency, lines...)
+	if err != nil {
+		return
+	}
+
+	c.Subtotal = &subtotal
 }
 
 func (c *Cart) ToJSON(w io.Writer) error {
@@ -35,9 +35,11 @@ func NewPostgresCartRepository(ctx context.Context, db *sqlx.DB, log *zerolog.Lo
 func (r *postgresCartRepository) GetCartByUserID(ctx context.Context, userID string) (*models.Cart, error) {
 	log := r.log.With().Str("method", "GetCartByUserID").Logger()
 	query := `
-        SELECT c.id, c.user_id, ci.id, ci.product_id, ci.quantity
+        SELECT c.id, c.user_id, ci.id, ci.product_id, ci.variant_id, ci.quantity, coalesce(v.price, p.price), p.currency
         FROM carts c
         JOIN cart_items ci ON c.id = ci.cart_id
+        JOIN products p ON p.id = ci.product_id
+        LEFT JOIN product_variants v ON v.id = ci.variant_id
         WHERE c.user_id = $1
     `
 
@@ -50,7 +52,7 @@ func (r *postgresCartRepository) GetCartByUserID(ctx context.Context, userID str
 	cart := &models.Cart{UserID: userID}
 	for rows.Next() {
 		var item models.CartItem
-		if err := rows.Scan(&cart.ID, &cart.UserID, &item.ID, &item.ProductID, &item.Quantity); err != nil {
+		if err := rows.Scan(&cart.ID, &cart.UserID, &item.ID, &item.ProductID, &item.VariantID, &item.Quantity, &item.UnitPrice, &item.UnitPrice.Currency); err != nil {
 			return nil, r.mapDatabaseError(err, &log)
 		}
 		item.CartID = cart.ID
@@ -61,10 +63,12 @@ func (r *postgresCartRepository) GetCartByUserID(ctx context.Context, userID str
 		return nil, r.mapDatabaseError(sql.ErrNoRows, &log)
 	}
 
+	cart.CalculateSubtotal()
+
 	return cart, nil
 }
 
-func (r *postgresCartRepository) AddItemToCart(ctx context.Context, userID string, productID int, quantity int) (*models.CartItem, error) {
+func (r *postgresCartRepository) AddItemToCart(ctx context.Context, userID string, productID, variantID int, quantity int) (*models.CartItem, error) {
 	log := r.log.With().Str("method", "AddItemToCart").Logger()
 
 	tx, err := r.db.BeginTx(ctx, nil)
@@ -94,9 +98,9 @@ func (r *postgresCartRepository) AddItemToCart(ctx context.Context, userID strin
 	var existingQuantity int
 	query2 := `
 	SELECT quantity FROM cart_items 
-	WHERE cart_id = $1 AND product_id = $2`
+	WHERE cart_id = $1 AND product_id = $2 AND variant_id = $3`
 
-	err = tx.QueryRowContext(ctx, query2, cart.ID, productID).Scan(&existingQuantity)
+	err = tx.QueryRowContext(ctx, query2, cart.ID, productID, variantID).Scan(&existingQuantity)
 
 	// 3. Insert or Update Based on Existence
 	if err != nil && err != sql.ErrNoRows {
@@ -107,20 +111,20 @@ func (r *postgresCartRepository) AddItemToCart(ctx context.Context, userID strin
 		}
 
 		query3 := `
-			INSERT INTO cart_items (cart_id, product_id, quantity)
-			VALUES ($1, $2, $3)
-			RETURNING id, cart_id, product_id, quantity
+			INSERT INTO cart_items (cart_id, product_id, variant_id, quantity)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id, cart_id, product_id, variant_id, quantity
 		`
-		err = tx.QueryRowContext(ctx, query3, cart.ID, productID, quantity).
-			Scan(&item.ID, &item.CartID, &item.ProductID, &item.Quantity)
+		err = tx.QueryRowContext(ctx, query3, cart.ID, productID, variantID, quantity).
+			Scan(&item.ID, &item.CartID, &item.ProductID, &item.VariantID, &item.Quantity)
 	} else {
 		query4 := `
 			UPDATE cart_items SET quantity = quantity + $1 
-			WHERE cart_id = $2 AND product_id = $3
-			RETURNING id, cart_id, product_id, quantity
+			WHERE cart_id = $2 AND product_id = $3 AND variant_id = $4
+			RETURNING id, cart_id, product_id, variant_id, quantity
 		`
-		err = tx.QueryRowContext(ctx, query4, quantity, cart.ID, productID).
-			Scan(&item.ID, &item.CartID, &item.ProductID, &item.Quantity)
+		err = tx.QueryRowContext(ctx, query4, quantity, cart.ID, productID, variantID).
+			Scan(&item.ID, &item.CartID, &item.ProductID, &item.VariantID, &item.Quantity)
 	}
 	if err != nil {
 		return nil, r.mapDatabaseError(err, &log)
@@ -205,6 +209,24 @@ func (r *postgresCartRepository) ClearCartByUserID(ctx context.Context, userID s
 	return nil
 }
 
+// RemoveProductFromCarts deletes every cart line for productID and returns how many were removed.
+func (r *postgresCartRepository) RemoveProductFromCarts(ctx context.Context, productID int) (int64, error) {
+	log := r.log.With().Str("method", "RemoveProductFromCarts").Logger()
+
+	query := `DELETE FROM cart_items WHERE product_id = $1`
+	result, err := r.db.ExecContext(ctx, query, productID)
+	if err != nil {
+		return 0, r.mapDatabaseError(err, &log)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, r.mapDatabaseError(err, &log)
+	}
+
+	return rowsAffected, nil
+}
+
 func (r *postgresCartRepository) mapDatabaseError(err error, log *zerolog.Logger) error {
 	log.Err(err).Msg("database operation failed!")
 
@@ -8,8 +8,9 @@ import (
 
 type CartRepository interface {
 	GetCartByUserID(ctx context.Context, userID string) (*models.Cart, error)
-	AddItemToCart(ctx context.Context, userID string, productID int, quantity int) (*models.CartItem, error)
+	AddItemToCart(ctx context.Context, userID string, productID, variantID int, quantity int) (*models.CartItem, error)
 	UpdateCartItemQuantity(ctx context.Context, userID string, cartItemID int, newQuantity int) error
 	RemoveItemFromCart(ctx context.Context, userID string, cartItemID int) error
 	ClearCartByUserID(ctx context.Context, userID string) error
+	RemoveProductFromCarts(ctx context.Context, productID int) (int64, error)
 }
@@ -2,9 +2,12 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 
+	"github.com/rovilay/ecommerce-service/common/events"
 	"github.com/rovilay/ecommerce-service/domains/auth"
 	"github.com/rovilay/ecommerce-service/domains/cart"
+	eventhandlers "github.com/rovilay/ecommerce-service/domains/cart/eventHandlers"
 	"github.com/rovilay/ecommerce-service/domains/cart/models"
 	"github.com/rovilay/ecommerce-service/domains/cart/repository"
 	"github.com/rs/zerolog"
@@ -13,75 +16,121 @@ import (
 type CartService struct {
 	repo        repository.CartRepository
 	authService auth.AuthService
+	rc          *events.RabbitClient
+	hc          *eventhandlers.HandlerClient
 	log         *zerolog.Logger
 }
 
-func NewCartService(r repository.CartRepository, s auth.AuthService, l *zerolog.Logger) *CartService {
+func NewCartService(r repository.CartRepository, s auth.AuthService, rc *events.RabbitClient, l *zerolog.Logger) *CartService {
 	logger := l.With().Str("service", "CartService").Logger()
 
 	return &CartService{
 		log:         &logger,
 		authService: s,
 		repo:        r,
+		rc:          rc,
+		hc:          eventhandlers.NewHandlerClient(r, &logger),
 	}
 }
 
 func (s *CartService) GetCart(ctx context.Context, authToken string) (*models.Cart, error) {
 	log := s.log.With().Str("method", "GetCart").Logger()
 
-	userID, err := s.authService.ValidateJWT(ctx, authToken)
+	claims, err := s.authService.ValidateJWT(ctx, authToken)
 	if err != nil {
 		log.Err(err).Msg("error validating token")
 		return nil, cart.ErrInvalidJWToken
 	}
 
-	return s.repo.GetCartByUserID(ctx, userID)
+	return s.repo.GetCartByUserID(ctx, claims.UserID)
 }
 
 func (s *CartService) AddItemToCart(ctx context.Context, authToken string, item models.CartItem) (*models.CartItem, error) {
 	log := s.log.With().Str("method", "AddItemToCart").Logger()
 
-	userID, err := s.authService.ValidateJWT(ctx, authToken)
+	claims, err := s.authService.ValidateJWT(ctx, authToken)
 	if err != nil {
 		log.Err(err).Msg("error validating token")
 		return nil, cart.ErrInvalidJWToken
 	}
 
-	return s.repo.AddItemToCart(ctx, userID, item.ProductID, item.Quantity)
+	return s.repo.AddItemToCart(ctx, claims.UserID, item.ProductID, item.VariantID, item.Quantity)
 }
 
 func (s *CartService) UpdateCartItemQuantity(ctx context.Context, authToken string, item models.CartItem) error {
 	log := s.log.With().Str("method", "UpdateCartItemQuantity").Logger()
 
-	userID, err := s.authService.ValidateJWT(ctx, authToken)
+	claims, err := s.authService.ValidateJWT(ctx, authToken)
 	if err != nil {
 		log.Err(err).Msg("error validating token")
 		return cart.ErrInvalidJWToken
 	}
 
-	return s.repo.UpdateCartItemQuantity(ctx, userID, item.ID, item.Quantity)
+	return s.repo.UpdateCartItemQuantity(ctx, claims.UserID, item.ID, item.Quantity)
 }
 
 func (s *CartService) RemoveItemFromCart(ctx context.Context, authToken string, cartItemID int) error {
 	log := s.log.With().Str("method", "RemoveItemFromCart").Logger()
 
-	userID, err := s.authService.ValidateJWT(ctx, authToken)
+	claims, err := s.authService.ValidateJWT(ctx, authToken)
 	if err != nil {
 		log.Err(err).Msg("error validating token")
 		return cart.ErrInvalidJWToken
 	}
 
-	return s.repo.RemoveItemFromCart(ctx, userID, cartItemID)
+	return s.repo.RemoveItemFromCart(ctx, claims.UserID, cartItemID)
 }
 
 func (s *CartService) ClearCart(ctx context.Context, authToken string) error {
 	log := s.log.With().Str("method", "ClearCart").Logger()
 
-	userID, err := s.authService.ValidateJWT(ctx, authToken)
+	claims, err := s.authService.ValidateJWT(ctx, authToken)
 	if err != nil {
 		log.Err(err).Msg("error validating token")
 		return cart.ErrInvalidJWToken
 	}
 
+	return s.repo.ClearCartByUserID(ctx, claims.UserID)
+}
+
+// GetUserCart returns the cart of userID. It is meant for trusted services and does not check a user token.
+func (s *CartService) GetUserCart(ctx context.Context, userID string) (*models.Cart, error) {
+	return s.repo.GetCartByUserID(ctx, userID)
+}
+
+// ClearUserCart clears the cart of userID. It is meant for trusted services and does not check a user token.
+func (s *CartService) ClearUserCart(ctx context.Context, userID string) error {
 	return s.repo.ClearCartByUserID(ctx, userID)
 }
+
+// Listen consumes the given events from topic through queue and dispatches them to the event handlers.
+func (s *CartService) Listen(ctx context.Context, topic events.Topic, queue string, keys ...events.RoutingKey) error {
+	msgs, err := s.rc.Listen(ctx, topic, queue, false, keys...)
+	if err != nil {
+		s.log.Err(err).Msg("Failed to create queue binding")
+		return err
+	}
+
+	go func() {
+		for msg := range msgs {
+			e := events.EventData{}
+			if err := json.Unmarshal(msg.Body, &e); err != nil {
+				s.log.Err(err).Msg("error unmarshalling event")
+				continue
+			}
+
+			err = s.hc.HandleEvent(ctx, e)
+			if err != nil {
+				s.log.Err(err).Msgf("Error handling event: %s", msg.MessageId)
+				continue
+			}
+
+			err = msg.Ack(false)
+			if err != nil {
+				s.log.Err(err).Msgf("failed to acknowledge message: %s", msg.MessageId)
+			}
+		}
+	}()
+
+	return nil
+}
@@ -8,3 +8,9 @@ var ErrDuplicateEntry = errors.New("duplicate inventory entry")
 var ErrForeignKeyViolation = errors.New("foreign key violation (invalid product reference?)")
 var ErrInvalidQuantity = errors.New("initial quantity cannot be negative")
 var ErrInvalidProduct = errors.New("product not found")
+var ErrReservationNotFound = errors.New("no active reservation found for this reference")
+var ErrReservationExpired = errors.New("reservation has expired")
+
+// CodeInsufficientStock is the error code of responses failed with ErrInsufficientStock, so
+// callers can tell them from other bad requests.
+const CodeInsufficientStock = "insufficient_stock"
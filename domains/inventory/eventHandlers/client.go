@@ -46,6 +46,10 @@ func (h *HandlerClient) HandleEvent(ctx context.Context, event events.EventData)
 func (h *HandlerClient) GetFunctionMap() (map[string]func(context.Context, events.EventData) error, error) {
 	var functionMap = map[string]func(context.Context, events.EventData) error{
 		string(events.ProductCreated): h.ProductCreated,
+		string(events.ProductUpdated): h.ProductUpdated,
+		string(events.ProductDeleted): h.ProductDeleted,
+		string(events.OrderCancelled): h.OrderCancelled,
+		string(events.OrderRefunded):  h.OrderRefunded,
 	}
 
 	return functionMap, nil
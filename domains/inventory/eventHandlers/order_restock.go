@@ -0,0 +1,43 @@
+package eventhandlers
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/rovilay/ecommerce-service/common/events"
+	eventdatatypes "github.com/rovilay/ecommerce-service/common/events/datatypes"
+	"github.com/rovilay/ecommerce-service/domains/inventory/model"
+)
+
+func (h *HandlerClient) OrderCancelled(ctx context.Context, e events.EventData) error {
+	return h.restockOrder(ctx, e, "cancelled")
+}
+
+func (h *HandlerClient) OrderRefunded(ctx context.Context, e events.EventData) error {
+	return h.restockOrder(ctx, e, "refunded")
+}
+
+// restockOrder returns the stock taken by the order in the event, once per order.
+func (h *HandlerClient) restockOrder(ctx context.Context, e events.EventData, reason string) error {
+	o := eventdatatypes.Order{}
+	if err := json.Unmarshal(e.Data, &o); err != nil {
+		h.log.Err(err).Msg("Failed to unmarshal event data")
+		return err
+	}
+
+	items := make([]model.ReservationItem, 0, len(o.OrderItems))
+	for _, item := range o.OrderItems {
+		items = append(items, model.ReservationItem{ProductID: item.ProductID, VariantID: item.VariantID, Quantity: item.Quantity})
+	}
+
+	restocked, err := h.repo.RestockOrder(ctx, o.ID, reason, items)
+	if err != nil {
+		return err
+	}
+
+	if !restocked {
+		h.log.Info().Int("order_id", o.ID).Msg("order already restocked, skipping")
+	}
+
+	return nil
+}
@@ -15,8 +15,16 @@ func (h *HandlerClient) ProductCreated(ctx context.Context, e events.EventData)
 		return err
 	}
 
-	// create product inventory
-	_, err := h.repo.CreateInventoryItem(ctx, p.ID, 0)
+	// create inventory for the product or each of its variants; events may be delivered more than once
+	return h.repo.SyncProductInventory(ctx, p.ID, variantIDs(p))
+}
+
+// variantIDs returns the IDs of the live variants of p.
+func variantIDs(p eventdatatypes.Product) []int {
+	ids := make([]int, 0, len(p.Variants))
+	for _, v := range p.Variants {
+		ids = append(ids, v.ID)
+	}
 
-	return err
+	return ids
 }
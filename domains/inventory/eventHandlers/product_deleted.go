@@ -0,0 +1,27 @@
+package eventhandlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/rovilay/ecommerce-service/common/events"
+	eventdatatypes "github.com/rovilay/ecommerce-service/common/events/datatypes"
+	"github.com/rovilay/ecommerce-service/domains/inventory"
+)
+
+func (h *HandlerClient) ProductDeleted(ctx context.Context, e events.EventData) error {
+	p := eventdatatypes.ProductDeleted{}
+	if err := json.Unmarshal(e.Data, &p); err != nil {
+		h.log.Err(err).Msg("Failed to unmarshal event data")
+		return err
+	}
+
+	// deactivate product inventory so it can no longer be reserved or ordered
+	err := h.repo.SetInventoryActive(ctx, p.ID, false)
+	if errors.Is(err, inventory.ErrNotFound) {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,20 @@
+package eventhandlers
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/rovilay/ecommerce-service/common/events"
+	eventdatatypes "github.com/rovilay/ecommerce-service/common/events/datatypes"
+)
+
+func (h *HandlerClient) ProductUpdated(ctx context.Context, e events.EventData) error {
+	p := eventdatatypes.Product{}
+	if err := json.Unmarshal(e.Data, &p); err != nil {
+		h.log.Err(err).Msg("Failed to unmarshal event data")
+		return err
+	}
+
+	// an updated product is live, make sure it and its current variants have active inventory
+	return h.repo.SyncProductInventory(ctx, p.ID, variantIDs(p))
+}
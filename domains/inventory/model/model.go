@@ -3,14 +3,24 @@ package model
 import (
 	"encoding/json"
 	"io"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 )
 
+// StockKey identifies a stock row: a product, or one of its variants. VariantID is 0 for
+// products sold without variants.
+type StockKey struct {
+	ProductID int
+	VariantID int
+}
+
 type InventoryItem struct {
-	ID        int `json:"id"`
-	ProductID int `json:"product_id" db:"product_id" validate:"required"`
-	Quantity  int `json:"quantity" db:"quantity" validate:"min=0,required"`
+	ID        int  `json:"id"`
+	ProductID int  `json:"product_id" db:"product_id" validate:"required"`
+	VariantID int  `json:"variant_id" db:"variant_id" validate:"min=0"`
+	Quantity  int  `json:"quantity" db:"quantity" validate:"min=0,required"`
+	Active    bool `json:"active" db:"active"`
 }
 
 func (i *InventoryItem) ToJSON(w io.Writer) error {
@@ -25,3 +35,117 @@ func (i *InventoryItem) Validate() error {
 	v := validator.New()
 	return v.Struct(i)
 }
+
+type ReservationStatus string
+
+const (
+	ReservationStatusHeld      ReservationStatus = "held"
+	ReservationStatusCommitted ReservationStatus = "committed"
+	ReservationStatusReleased  ReservationStatus = "released"
+	ReservationStatusExpired   ReservationStatus = "expired"
+)
+
+type Reservation struct {
+	ID        int               `json:"id"`
+	Reference string            `json:"reference" db:"reference"`
+	ProductID int               `json:"product_id" db:"product_id"`
+	VariantID int               `json:"variant_id" db:"variant_id"`
+	Quantity  int               `json:"quantity" db:"quantity"`
+	Status    ReservationStatus `json:"status" db:"status"`
+	ExpiresAt time.Time         `json:"expires_at" db:"expires_at"`
+	CreatedAt time.Time         `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at" db:"updated_at"`
+}
+
+type ReservationItem struct {
+	ProductID int `json:"product_id" validate:"required"`
+	VariantID int `json:"variant_id" validate:"min=0"`
+	Quantity  int `json:"quantity" validate:"required,gt=0"`
+}
+
+func (i ReservationItem) Key() StockKey {
+	return StockKey{ProductID: i.ProductID, VariantID: i.VariantID}
+}
+
+// ReservationRequest holds stock for an order, identified by Reference, until it is
+// committed, released or expires.
+type ReservationRequest struct {
+	Reference  string            `json:"reference" validate:"required,max=100"`
+	Items      []ReservationItem `json:"items" validate:"required,min=1,dive"`
+	TTLSeconds int               `json:"ttl_seconds" validate:"min=0"`
+}
+
+func (r *ReservationRequest) FromJSON(rd io.Reader) error {
+	return json.NewDecoder(rd).Decode(r)
+}
+
+func (r *ReservationRequest) Validate() error {
+	v := validator.New()
+	return v.Struct(r)
+}
+
+// StockItem is a quantity of a product or of one of its variants.
+type StockItem struct {
+	ProductID int `json:"product_id" validate:"required"`
+	VariantID int `json:"variant_id" validate:"min=0"`
+	Quantity  int `json:"quantity" validate:"required,gt=0"`
+}
+
+func (i StockItem) Key() StockKey {
+	return StockKey{ProductID: i.ProductID, VariantID: i.VariantID}
+}
+
+// AvailabilityRequest checks several products in one call.
+type AvailabilityRequest struct {
+	Items []StockItem `json:"items" validate:"required,min=1,dive"`
+}
+
+func (r *AvailabilityRequest) FromJSON(rd io.Reader) error {
+	return json.NewDecoder(rd).Decode(r)
+}
+
+func (r *AvailabilityRequest) Validate() error {
+	v := validator.New()
+	return v.Struct(r)
+}
+
+type Availability struct {
+	ProductID  int  `json:"product_id"`
+	VariantID  int  `json:"variant_id"`
+	Requested  int  `json:"requested"`
+	Available  int  `json:"available"`
+	Sufficient bool `json:"sufficient"`
+}
+
+// AvailabilityResult is Available only when every item is.
+type AvailabilityResult struct {
+	Available bool           `json:"available"`
+	Items     []Availability `json:"items"`
+}
+
+// StockAdjustment changes the stock of a product or variant by Delta, which is negative for a decrement.
+type StockAdjustment struct {
+	ProductID int `json:"product_id" validate:"required"`
+	VariantID int `json:"variant_id" validate:"min=0"`
+	Delta     int `json:"delta" validate:"required"`
+}
+
+func (a StockAdjustment) Key() StockKey {
+	return StockKey{ProductID: a.ProductID, VariantID: a.VariantID}
+}
+
+// AdjustmentRequest applies all of its adjustments or none of them. A request with a
+// Reference is applied once: repeating it changes nothing.
+type AdjustmentRequest struct {
+	Reference string            `json:"reference" validate:"max=100"`
+	Items     []StockAdjustment `json:"items" validate:"required,min=1,dive"`
+}
+
+func (r *AdjustmentRequest) FromJSON(rd io.Reader) error {
+	return json.NewDecoder(rd).Decode(r)
+}
+
+func (r *AdjustmentRequest) Validate() error {
+	v := validator.New()
+	return v.Struct(r)
+}
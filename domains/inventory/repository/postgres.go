@@ -3,11 +3,14 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jmoiron/sqlx"
+	"github.com/rovilay/ecommerce-service/common/events"
+	eventdatatypes "github.com/rovilay/ecommerce-service/common/events/datatypes"
 	"github.com/rovilay/ecommerce-service/domains/inventory"
 	"github.com/rovilay/ecommerce-service/domains/inventory/model"
 	"github.com/rs/zerolog"
@@ -32,65 +35,165 @@ func NewPostgresInventoryRepository(ctx context.Context, db *sqlx.DB, log *zerol
 	}
 }
 
-func (r *postgresInventoryRepository) CreateInventoryItem(ctx context.Context, productID int, quantity uint) (*model.InventoryItem, error) {
+func (r *postgresInventoryRepository) CreateInventoryItem(ctx context.Context, productID, variantID int, quantity uint) (*model.InventoryItem, error) {
 	log := r.log.With().Str("method", "CreateInventoryItem").Logger()
 
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, r.mapDatabaseError(err, &log)
+	}
+	defer tx.Rollback()
+
 	var ivn model.InventoryItem
-	query := `INSERT INTO inventory_items (product_id, quantity)
-		values ($1, $2)
-		RETURNING id, product_id, quantity
+	query := `INSERT INTO inventory_items (product_id, variant_id, quantity)
+		values ($1, $2, $3)
+		RETURNING id, product_id, variant_id, quantity, active
 	`
 
-	err := r.db.QueryRowContext(
+	err = tx.QueryRowContext(
 		ctx,
 		query,
 		productID,
+		variantID,
 		quantity,
 	).Scan(
-		&ivn.ID, &ivn.ProductID, &ivn.Quantity,
+		&ivn.ID, &ivn.ProductID, &ivn.VariantID, &ivn.Quantity, &ivn.Active,
 	)
 	if err != nil {
 		return nil, r.mapDatabaseError(err, &log)
 	}
 
+	err = writeInventoryEvent(ctx, tx, events.InventoryCreated, ivn.ProductID, ivn.VariantID, ivn.Quantity)
+	if err != nil {
+		return nil, r.mapDatabaseError(err, &log)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, r.mapDatabaseError(err, &log)
+	}
+
 	return &ivn, nil
 }
 
-func (r *postgresInventoryRepository) GetInventoryItemByProductID(ctx context.Context, productID int) (*model.InventoryItem, error) {
+func (r *postgresInventoryRepository) GetInventoryItemByProductID(ctx context.Context, productID, variantID int) (*model.InventoryItem, error) {
 	log := r.log.With().Str("method", "GetInventoryItemByProductID").Logger()
 
 	var inventoryItem model.InventoryItem
-	query := `SELECT id, product_id, quantity FROM inventory_items WHERE product_id = $1`
-	err := r.db.GetContext(ctx, &inventoryItem, query, productID)
+	query := `SELECT id, product_id, variant_id, quantity, active FROM inventory_items WHERE product_id = $1 AND variant_id = $2`
+	err := r.db.GetContext(ctx, &inventoryItem, query, productID, variantID)
 	if err != nil {
 		return nil, r.mapDatabaseError(err, &log)
 	}
 	return &inventoryItem, nil
 }
 
-func (r *postgresInventoryRepository) UpdateInventoryQuantity(ctx context.Context, productID int, quantityDelta int) error {
-	log := r.log.With().Str("method", "UpdateInventoryQuantity").Logger()
+func (r *postgresInventoryRepository) SetInventoryActive(ctx context.Context, productID int, active bool) error {
+	log := r.log.With().Str("method", "SetInventoryActive").Logger()
 
-	tx, err := r.db.Begin()
+	query := `UPDATE inventory_items SET active = $1, updated_at = now() WHERE product_id = $2`
+	result, err := r.db.ExecContext(ctx, query, active, productID)
 	if err != nil {
 		return r.mapDatabaseError(err, &log)
 	}
-	defer tx.Rollback()
 
-	query := `UPDATE inventory_items SET quantity = quantity + $1 WHERE product_id = $2 AND quantity + $1 >= 0`
-	result, err := tx.ExecContext(ctx, query, quantityDelta, productID)
+	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		return r.mapDatabaseError(err, &log)
 	}
 
-	rowsAffected, _ := result.RowsAffected()
 	if rowsAffected == 0 {
+		return inventory.ErrNotFound
+	}
+
+	return nil
+}
+
+// SyncProductInventory gives a live product one active inventory row per variant, or a single
+// row with variant 0 when it has no variants. Rows of variants it no longer has are deactivated
+// but keep their stock. It is safe to repeat, as product events may be delivered more than once.
+func (r *postgresInventoryRepository) SyncProductInventory(ctx context.Context, productID int, variantIDs []int) error {
+	log := r.log.With().Str("method", "SyncProductInventory").Logger()
+
+	if len(variantIDs) == 0 {
+		variantIDs = []int{0}
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return r.mapDatabaseError(err, &log)
+	}
+	defer tx.Rollback()
+
+	var created []int
+	query := `INSERT INTO inventory_items (product_id, variant_id, quantity)
+		SELECT $1, unnest($2::int[]), 0
+		ON CONFLICT (product_id, variant_id) DO NOTHING
+		RETURNING variant_id
+	`
+	err = tx.SelectContext(ctx, &created, query, productID, variantIDs)
+	if err != nil {
+		return r.mapDatabaseError(err, &log)
+	}
+
+	for _, variantID := range created {
+		err = writeInventoryEvent(ctx, tx, events.InventoryCreated, productID, variantID, 0)
+		if err != nil {
+			return r.mapDatabaseError(err, &log)
+		}
+	}
+
+	query = `UPDATE inventory_items SET active = variant_id = ANY($2), updated_at = now()
+		WHERE product_id = $1 AND active <> (variant_id = ANY($2))
+	`
+	_, err = tx.ExecContext(ctx, query, productID, variantIDs)
+	if err != nil {
+		return r.mapDatabaseError(err, &log)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return r.mapDatabaseError(err, &log)
+	}
+
+	return nil
+}
+
+// adjustQuantityQuery adds $1 to the stock of product $2, variant $3. Stock held by
+// reservations cannot be taken by a direct decrement.
+const adjustQuantityQuery = `
+	UPDATE inventory_items SET quantity = quantity + $1, updated_at = now()
+	WHERE product_id = $2 AND variant_id = $3 AND quantity + $1 >= CASE WHEN $1 < 0 THEN (
+		SELECT coalesce(sum(quantity), 0) FROM inventory_reservations
+		WHERE product_id = $2 AND variant_id = $3 AND status = 'held' AND expires_at > now()
+	) ELSE 0 END
+	RETURNING quantity
+`
+
+func (r *postgresInventoryRepository) UpdateInventoryQuantity(ctx context.Context, productID, variantID, quantityDelta int) error {
+	log := r.log.With().Str("method", "UpdateInventoryQuantity").Logger()
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return r.mapDatabaseError(err, &log)
+	}
+	defer tx.Rollback()
+
+	var quantity int
+	err = tx.QueryRowContext(ctx, adjustQuantityQuery, quantityDelta, productID, variantID).Scan(&quantity)
+	if errors.Is(err, sql.ErrNoRows) {
 		// Implies attempted overselling
 		if quantityDelta < 0 {
 			return inventory.ErrInsufficientStock
 		}
 
 		return inventory.ErrNotFound
+	} else if err != nil {
+		return r.mapDatabaseError(err, &log)
+	}
+
+	err = writeInventoryEvent(ctx, tx, events.InventoryUpdated, productID, variantID, quantity)
+	if err != nil {
+		return r.mapDatabaseError(err, &log)
 	}
 
 	err = tx.Commit()
@@ -101,6 +204,218 @@ func (r *postgresInventoryRepository) UpdateInventoryQuantity(ctx context.Contex
 	return nil
 }
 
+// AdjustInventoryQuantities applies every adjustment in one transaction; if any of them
+// fails none is applied. Adjustments to the same product and variant are summed. Adjustments
+// with a reference are applied at most once: a reference that was applied, or reversed,
+// before changes nothing and returns the current stock of the items.
+func (r *postgresInventoryRepository) AdjustInventoryQuantities(ctx context.Context, reference string, adjustments []model.StockAdjustment) ([]*model.InventoryItem, error) {
+	log := r.log.With().Str("method", "AdjustInventoryQuantities").Logger()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, r.mapDatabaseError(err, &log)
+	}
+	defer tx.Rollback()
+
+	// update rows in a stable order so concurrent batches cannot deadlock
+	adjustments = mergeStockAdjustments(adjustments)
+
+	if reference != "" {
+		recorded, err := json.Marshal(adjustments)
+		if err != nil {
+			return nil, err
+		}
+
+		// a concurrent request with the same reference waits here until the first one is done
+		query := `INSERT INTO inventory_adjustments (reference, items) VALUES ($1, $2) ON CONFLICT (reference) DO NOTHING`
+		res, err := tx.ExecContext(ctx, query, reference, string(recorded))
+		if err != nil {
+			return nil, r.mapDatabaseError(err, &log)
+		}
+
+		if n, _ := res.RowsAffected(); n == 0 {
+			log.Info().Str("reference", reference).Msg("adjustment already applied")
+			return r.getInventoryItems(ctx, tx, adjustments, &log)
+		}
+	}
+
+	items, err := r.applyStockAdjustments(ctx, tx, adjustments, &log)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, r.mapDatabaseError(err, &log)
+	}
+
+	return items, nil
+}
+
+// ReverseAdjustment undoes the adjustments applied with reference, once. A reference that was
+// never applied is recorded as reversed, so a request with it that arrives late changes nothing.
+func (r *postgresInventoryRepository) ReverseAdjustment(ctx context.Context, reference string) ([]*model.InventoryItem, error) {
+	log := r.log.With().Str("method", "ReverseAdjustment").Logger()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, r.mapDatabaseError(err, &log)
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO inventory_adjustments (reference, items, reversed_at) VALUES ($1, '[]', now())
+		ON CONFLICT (reference) DO NOTHING
+	`
+	res, err := tx.ExecContext(ctx, query, reference)
+	if err != nil {
+		return nil, r.mapDatabaseError(err, &log)
+	}
+
+	if n, _ := res.RowsAffected(); n == 1 {
+		return []*model.InventoryItem{}, tx.Commit()
+	}
+
+	var recorded struct {
+		Items    []byte `db:"items"`
+		Reversed bool   `db:"reversed"`
+	}
+	query = `SELECT items, reversed_at IS NOT NULL AS reversed FROM inventory_adjustments WHERE reference = $1 FOR UPDATE`
+	if err = tx.GetContext(ctx, &recorded, query, reference); err != nil {
+		return nil, r.mapDatabaseError(err, &log)
+	}
+
+	var adjustments []model.StockAdjustment
+	if err = json.Unmarshal(recorded.Items, &adjustments); err != nil {
+		return nil, err
+	}
+
+	if recorded.Reversed {
+		return r.getInventoryItems(ctx, tx, adjustments, &log)
+	}
+
+	for i := range adjustments {
+		adjustments[i].Delta = -adjustments[i].Delta
+	}
+
+	items, err := r.applyStockAdjustments(ctx, tx, adjustments, &log)
+	if err != nil {
+		return nil, err
+	}
+
+	query = `UPDATE inventory_adjustments SET reversed_at = now() WHERE reference = $1`
+	if _, err = tx.ExecContext(ctx, query, reference); err != nil {
+		return nil, r.mapDatabaseError(err, &log)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, r.mapDatabaseError(err, &log)
+	}
+
+	return items, nil
+}
+
+// applyStockAdjustments applies merged adjustments and records an inventory.updated event for
+// each of them.
+func (r *postgresInventoryRepository) applyStockAdjustments(ctx context.Context, tx *sqlx.Tx, adjustments []model.StockAdjustment, log *zerolog.Logger) ([]*model.InventoryItem, error) {
+	items := make([]*model.InventoryItem, 0, len(adjustments))
+	for _, adj := range adjustments {
+		item := &model.InventoryItem{ProductID: adj.ProductID, VariantID: adj.VariantID, Active: true}
+
+		err := tx.QueryRowContext(ctx, adjustQuantityQuery, adj.Delta, adj.ProductID, adj.VariantID).Scan(&item.Quantity)
+		if errors.Is(err, sql.ErrNoRows) {
+			if adj.Delta < 0 {
+				return nil, fmt.Errorf("%w for product: %d, variant: %d", inventory.ErrInsufficientStock, adj.ProductID, adj.VariantID)
+			}
+
+			return nil, fmt.Errorf("%w for product: %d, variant: %d", inventory.ErrNotFound, adj.ProductID, adj.VariantID)
+		} else if err != nil {
+			return nil, r.mapDatabaseError(err, log)
+		}
+
+		err = writeInventoryEvent(ctx, tx, events.InventoryUpdated, adj.ProductID, adj.VariantID, item.Quantity)
+		if err != nil {
+			return nil, r.mapDatabaseError(err, log)
+		}
+
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// getInventoryItems returns the current stock of the products and variants adjustments are about.
+func (r *postgresInventoryRepository) getInventoryItems(ctx context.Context, q sqlx.QueryerContext, adjustments []model.StockAdjustment, log *zerolog.Logger) ([]*model.InventoryItem, error) {
+	items := make([]*model.InventoryItem, 0, len(adjustments))
+	for _, adj := range adjustments {
+		item := &model.InventoryItem{ProductID: adj.ProductID, VariantID: adj.VariantID}
+
+		query := `SELECT quantity, active FROM inventory_items WHERE product_id = $1 AND variant_id = $2`
+		err := q.QueryRowxContext(ctx, query, adj.ProductID, adj.VariantID).Scan(&item.Quantity, &item.Active)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		} else if err != nil {
+			return nil, r.mapDatabaseError(err, log)
+		}
+
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// RestockOrder returns the stock of an order's items at most once per order.
+// It reports false when the order had already been restocked.
+func (r *postgresInventoryRepository) RestockOrder(ctx context.Context, orderID int, reason string, items []model.ReservationItem) (bool, error) {
+	log := r.log.With().Str("method", "RestockOrder").Logger()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, r.mapDatabaseError(err, &log)
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO inventory_restocks (order_id, reason) VALUES ($1, $2) ON CONFLICT (order_id) DO NOTHING`
+	result, err := tx.ExecContext(ctx, query, orderID, reason)
+	if err != nil {
+		return false, r.mapDatabaseError(err, &log)
+	}
+
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return false, nil
+	}
+
+	for _, item := range items {
+		var quantity int
+		query := `UPDATE inventory_items SET quantity = quantity + $1, updated_at = now()
+			WHERE product_id = $2 AND variant_id = $3
+			RETURNING quantity
+		`
+		err = tx.QueryRowContext(ctx, query, item.Quantity, item.ProductID, item.VariantID).Scan(&quantity)
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn().Int("product_id", item.ProductID).Int("variant_id", item.VariantID).Msg("no inventory to restock")
+			continue
+		} else if err != nil {
+			return false, r.mapDatabaseError(err, &log)
+		}
+
+		err = writeInventoryEvent(ctx, tx, events.InventoryUpdated, item.ProductID, item.VariantID, quantity)
+		if err != nil {
+			return false, r.mapDatabaseError(err, &log)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return false, r.mapDatabaseError(err, &log)
+	}
+
+	return true, nil
+}
+
+// writeInventoryEvent adds a stock change event to the outbox within tx.
+func writeInventoryEvent(ctx context.Context, tx sqlx.ExecerContext, key events.RoutingKey, productID, variantID, quantity int) error {
+	data := eventdatatypes.Inventory{ProductID: productID, VariantID: variantID, Quantity: quantity}
+	return events.WriteOutbox(ctx, tx, events.Inventory, key, data)
+}
+
 func (r *postgresInventoryRepository) mapDatabaseError(err error, log *zerolog.Logger) error {
 	log.Err(err).Msg("database operation failed!")
 
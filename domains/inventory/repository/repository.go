@@ -2,12 +2,26 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/rovilay/ecommerce-service/domains/inventory/model"
 )
 
 type InventoryRepository interface {
-	CreateInventoryItem(ctx context.Context, productID int, quantity uint) (*model.InventoryItem, error)
-	GetInventoryItemByProductID(ctx context.Context, productID int) (*model.InventoryItem, error)
-	UpdateInventoryQuantity(ctx context.Context, productID, quantityDelta int) error
+	CreateInventoryItem(ctx context.Context, productID, variantID int, quantity uint) (*model.InventoryItem, error)
+	GetInventoryItemByProductID(ctx context.Context, productID, variantID int) (*model.InventoryItem, error)
+	UpdateInventoryQuantity(ctx context.Context, productID, variantID, quantityDelta int) error
+	AdjustInventoryQuantities(ctx context.Context, reference string, adjustments []model.StockAdjustment) ([]*model.InventoryItem, error)
+	ReverseAdjustment(ctx context.Context, reference string) ([]*model.InventoryItem, error)
+	SetInventoryActive(ctx context.Context, productID int, active bool) error
+	SyncProductInventory(ctx context.Context, productID int, variantIDs []int) error
+	RestockOrder(ctx context.Context, orderID int, reason string, items []model.ReservationItem) (bool, error)
+	GetAvailableQuantity(ctx context.Context, productID, variantID int) (int, error)
+	GetAvailableQuantities(ctx context.Context, keys []model.StockKey) (map[model.StockKey]int, error)
+
+	CreateReservations(ctx context.Context, reference string, items []model.ReservationItem, expiresAt time.Time) ([]*model.Reservation, error)
+	GetReservationsByReference(ctx context.Context, reference string) ([]*model.Reservation, error)
+	CommitReservations(ctx context.Context, reference string) ([]*model.Reservation, error)
+	ReleaseReservations(ctx context.Context, reference string) ([]*model.Reservation, error)
+	ExpireReservations(ctx context.Context) (int64, error)
 }
@@ -0,0 +1,300 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/rovilay/ecommerce-service/common/events"
+	"github.com/rovilay/ecommerce-service/domains/inventory"
+	"github.com/rovilay/ecommerce-service/domains/inventory/model"
+)
+
+const reservationColumns = `id, reference, product_id, variant_id, quantity, status, expires_at, created_at, updated_at`
+
+// heldQuantityQuery sums the unexpired holds on a product or variant.
+const heldQuantityQuery = `
+	SELECT coalesce(sum(quantity), 0) FROM inventory_reservations
+	WHERE product_id = $1 AND variant_id = $2 AND status = 'held' AND expires_at > now()
+`
+
+func (r *postgresInventoryRepository) GetAvailableQuantity(ctx context.Context, productID, variantID int) (int, error) {
+	log := r.log.With().Str("method", "GetAvailableQuantity").Logger()
+
+	query := `
+		SELECT CASE WHEN i.active THEN i.quantity - coalesce(sum(ir.quantity), 0) ELSE 0 END
+		FROM inventory_items i
+		LEFT JOIN inventory_reservations ir
+			ON ir.product_id = i.product_id AND ir.variant_id = i.variant_id
+			AND ir.status = 'held' AND ir.expires_at > now()
+		WHERE i.product_id = $1 AND i.variant_id = $2
+		GROUP BY i.id
+	`
+
+	var available int
+	err := r.db.GetContext(ctx, &available, query, productID, variantID)
+	if err != nil {
+		return 0, r.mapDatabaseError(err, &log)
+	}
+
+	return available, nil
+}
+
+// GetAvailableQuantities returns the available quantity of each of the keys that has inventory.
+func (r *postgresInventoryRepository) GetAvailableQuantities(ctx context.Context, keys []model.StockKey) (map[model.StockKey]int, error) {
+	log := r.log.With().Str("method", "GetAvailableQuantities").Logger()
+
+	productIDs := make([]int, 0, len(keys))
+	variantIDs := make([]int, 0, len(keys))
+	for _, k := range keys {
+		productIDs = append(productIDs, k.ProductID)
+		variantIDs = append(variantIDs, k.VariantID)
+	}
+
+	query := `
+		SELECT i.product_id, i.variant_id,
+			CASE WHEN i.active THEN i.quantity - coalesce(sum(ir.quantity), 0) ELSE 0 END AS available
+		FROM inventory_items i
+		LEFT JOIN inventory_reservations ir
+			ON ir.product_id = i.product_id AND ir.variant_id = i.variant_id
+			AND ir.status = 'held' AND ir.expires_at > now()
+		WHERE (i.product_id, i.variant_id) IN (SELECT * FROM unnest($1::int[], $2::int[]))
+		GROUP BY i.id
+	`
+
+	var rows []struct {
+		ProductID int `db:"product_id"`
+		VariantID int `db:"variant_id"`
+		Available int `db:"available"`
+	}
+	err := r.db.SelectContext(ctx, &rows, query, productIDs, variantIDs)
+	if err != nil {
+		return nil, r.mapDatabaseError(err, &log)
+	}
+
+	available := make(map[model.StockKey]int, len(rows))
+	for _, row := range rows {
+		available[model.StockKey{ProductID: row.ProductID, VariantID: row.VariantID}] = row.Available
+	}
+
+	return available, nil
+}
+
+func (r *postgresInventoryRepository) CreateReservations(ctx context.Context, reference string, items []model.ReservationItem, expiresAt time.Time) ([]*model.Reservation, error) {
+	log := r.log.With().Str("method", "CreateReservations").Logger()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, r.mapDatabaseError(err, &log)
+	}
+	defer tx.Rollback()
+
+	// lock rows in a stable order so concurrent reservations cannot deadlock
+	items = mergeReservationItems(items)
+
+	var reservations []*model.Reservation
+	for _, item := range items {
+		var ivn model.InventoryItem
+		query := `SELECT id, product_id, variant_id, quantity, active FROM inventory_items
+			WHERE product_id = $1 AND variant_id = $2
+			FOR UPDATE
+		`
+		err = tx.GetContext(ctx, &ivn, query, item.ProductID, item.VariantID)
+		if err != nil {
+			return nil, r.mapDatabaseError(err, &log)
+		}
+
+		if !ivn.Active {
+			return nil, inventory.ErrInvalidProduct
+		}
+
+		var held int
+		err = tx.GetContext(ctx, &held, heldQuantityQuery, item.ProductID, item.VariantID)
+		if err != nil {
+			return nil, r.mapDatabaseError(err, &log)
+		}
+
+		if ivn.Quantity-held < item.Quantity {
+			return nil, inventory.ErrInsufficientStock
+		}
+
+		var rsv model.Reservation
+		query = `
+			INSERT INTO inventory_reservations (reference, product_id, variant_id, quantity, expires_at)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING ` + reservationColumns
+		err = tx.GetContext(ctx, &rsv, query, reference, item.ProductID, item.VariantID, item.Quantity, expiresAt)
+		if err != nil {
+			return nil, r.mapDatabaseError(err, &log)
+		}
+
+		reservations = append(reservations, &rsv)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, r.mapDatabaseError(err, &log)
+	}
+
+	return reservations, nil
+}
+
+func (r *postgresInventoryRepository) GetReservationsByReference(ctx context.Context, reference string) ([]*model.Reservation, error) {
+	log := r.log.With().Str("method", "GetReservationsByReference").Logger()
+
+	query := `SELECT ` + reservationColumns + ` FROM inventory_reservations WHERE reference = $1 ORDER BY id`
+
+	var reservations []*model.Reservation
+	err := r.db.SelectContext(ctx, &reservations, query, reference)
+	if err != nil {
+		return nil, r.mapDatabaseError(err, &log)
+	}
+
+	if len(reservations) == 0 {
+		return nil, inventory.ErrReservationNotFound
+	}
+
+	return reservations, nil
+}
+
+func (r *postgresInventoryRepository) CommitReservations(ctx context.Context, reference string) ([]*model.Reservation, error) {
+	log := r.log.With().Str("method", "CommitReservations").Logger()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, r.mapDatabaseError(err, &log)
+	}
+	defer tx.Rollback()
+
+	var held []*model.Reservation
+	query := `SELECT ` + reservationColumns + ` FROM inventory_reservations
+		WHERE reference = $1 AND status = 'held'
+		ORDER BY product_id, variant_id
+		FOR UPDATE
+	`
+	err = tx.SelectContext(ctx, &held, query, reference)
+	if err != nil {
+		return nil, r.mapDatabaseError(err, &log)
+	}
+
+	if len(held) == 0 {
+		return nil, inventory.ErrReservationNotFound
+	}
+
+	now := time.Now()
+	for _, rsv := range held {
+		if !rsv.ExpiresAt.After(now) {
+			return nil, inventory.ErrReservationExpired
+		}
+
+		var quantity int
+		query := `UPDATE inventory_items SET quantity = quantity - $1, updated_at = now()
+			WHERE product_id = $2 AND variant_id = $3 AND quantity - $1 >= 0
+			RETURNING quantity
+		`
+		err := tx.QueryRowContext(ctx, query, rsv.Quantity, rsv.ProductID, rsv.VariantID).Scan(&quantity)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, inventory.ErrInsufficientStock
+		} else if err != nil {
+			return nil, r.mapDatabaseError(err, &log)
+		}
+
+		err = writeInventoryEvent(ctx, tx, events.InventoryUpdated, rsv.ProductID, rsv.VariantID, quantity)
+		if err != nil {
+			return nil, r.mapDatabaseError(err, &log)
+		}
+	}
+
+	var committed []*model.Reservation
+	query = `UPDATE inventory_reservations SET status = 'committed', updated_at = now()
+		WHERE reference = $1 AND status = 'held'
+		RETURNING ` + reservationColumns
+	err = tx.SelectContext(ctx, &committed, query, reference)
+	if err != nil {
+		return nil, r.mapDatabaseError(err, &log)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, r.mapDatabaseError(err, &log)
+	}
+
+	return committed, nil
+}
+
+func (r *postgresInventoryRepository) ReleaseReservations(ctx context.Context, reference string) ([]*model.Reservation, error) {
+	log := r.log.With().Str("method", "ReleaseReservations").Logger()
+
+	var released []*model.Reservation
+	query := `UPDATE inventory_reservations SET status = 'released', updated_at = now()
+		WHERE reference = $1 AND status = 'held'
+		RETURNING ` + reservationColumns
+	err := r.db.SelectContext(ctx, &released, query, reference)
+	if err != nil {
+		return nil, r.mapDatabaseError(err, &log)
+	}
+
+	if len(released) == 0 {
+		return nil, inventory.ErrReservationNotFound
+	}
+
+	return released, nil
+}
+
+func (r *postgresInventoryRepository) ExpireReservations(ctx context.Context) (int64, error) {
+	log := r.log.With().Str("method", "ExpireReservations").Logger()
+
+	query := `UPDATE inventory_reservations SET status = 'expired', updated_at = now()
+		WHERE status = 'held' AND expires_at <= now()
+	`
+	result, err := r.db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, r.mapDatabaseError(err, &log)
+	}
+
+	return result.RowsAffected()
+}
+
+// mergeStockAdjustments sums adjustments to the same product and variant and sorts them by key.
+func mergeStockAdjustments(adjustments []model.StockAdjustment) []model.StockAdjustment {
+	deltas := make(map[model.StockKey]int, len(adjustments))
+	for _, adj := range adjustments {
+		deltas[adj.Key()] += adj.Delta
+	}
+
+	merged := make([]model.StockAdjustment, 0, len(deltas))
+	for key, delta := range deltas {
+		merged = append(merged, model.StockAdjustment{ProductID: key.ProductID, VariantID: key.VariantID, Delta: delta})
+	}
+
+	sort.Slice(merged, func(i, j int) bool { return stockKeyLess(merged[i].Key(), merged[j].Key()) })
+
+	return merged
+}
+
+// mergeReservationItems sums duplicate products and variants and sorts the items by key.
+func mergeReservationItems(items []model.ReservationItem) []model.ReservationItem {
+	quantities := make(map[model.StockKey]int, len(items))
+	for _, item := range items {
+		quantities[item.Key()] += item.Quantity
+	}
+
+	merged := make([]model.ReservationItem, 0, len(quantities))
+	for key, quantity := range quantities {
+		merged = append(merged, model.ReservationItem{ProductID: key.ProductID, VariantID: key.VariantID, Quantity: quantity})
+	}
+
+	sort.Slice(merged, func(i, j int) bool { return stockKeyLess(merged[i].Key(), merged[j].Key()) })
+
+	return merged
+}
+
+func stockKeyLess(a, b model.StockKey) bool {
+	if a.ProductID != b.ProductID {
+		return a.ProductID < b.ProductID
+	}
+
+	return a.VariantID < b.VariantID
+}
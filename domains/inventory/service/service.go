@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"time"
 
 	"github.com/rovilay/ecommerce-service/common/events"
 	"github.com/rovilay/ecommerce-service/domains/inventory"
@@ -13,73 +14,154 @@ import (
 )
 
 type InventoryService struct {
-	repo repository.InventoryRepository
-	rc   *events.RabbitClient
-	log  *zerolog.Logger
-	hc   *eventhandlers.HandlerClient
+	repo           repository.InventoryRepository
+	rc             *events.RabbitClient
+	log            *zerolog.Logger
+	hc             *eventhandlers.HandlerClient
+	reservationTTL time.Duration
 }
 
-func NewInventoryService(repo repository.InventoryRepository, rc *events.RabbitClient, l *zerolog.Logger) (*InventoryService, error) {
+func NewInventoryService(repo repository.InventoryRepository, rc *events.RabbitClient, reservationTTL time.Duration, l *zerolog.Logger) (*InventoryService, error) {
 	logger := l.With().Str("service", "InventoryService").Logger()
 
 	hc := eventhandlers.NewHandlerClient(repo, &logger)
 
 	s := &InventoryService{
-		repo: repo,
-		rc:   rc,
-		log:  &logger,
-		hc:   hc,
+		repo:           repo,
+		rc:             rc,
+		log:            &logger,
+		hc:             hc,
+		reservationTTL: reservationTTL,
 	}
 
 	return s, nil
 }
 
-func (s *InventoryService) CreateInventoryItem(ctx context.Context, productID int, quantity int) (*model.InventoryItem, error) {
+func (s *InventoryService) CreateInventoryItem(ctx context.Context, productID, variantID int, quantity int) (*model.InventoryItem, error) {
 	if quantity < 0 {
 		return nil, inventory.ErrInvalidQuantity
 	}
 
-	return s.repo.CreateInventoryItem(ctx, productID, uint(quantity))
+	return s.repo.CreateInventoryItem(ctx, productID, variantID, uint(quantity))
 }
 
-func (s *InventoryService) GetInventoryByProductID(ctx context.Context, productID int) (*model.InventoryItem, error) {
-	return s.repo.GetInventoryItemByProductID(ctx, productID)
+// GetInventoryByProductID returns the stock of a product, or of one of its variants when variantID is not 0.
+func (s *InventoryService) GetInventoryByProductID(ctx context.Context, productID, variantID int) (*model.InventoryItem, error) {
+	return s.repo.GetInventoryItemByProductID(ctx, productID, variantID)
 }
 
-func (s *InventoryService) CheckAvailability(ctx context.Context, productID int, quantity uint) (bool, error) {
-	inventoryItem, err := s.repo.GetInventoryItemByProductID(ctx, productID)
+// CheckAvailability reports whether quantity is available once stock held by reservations is subtracted.
+func (s *InventoryService) CheckAvailability(ctx context.Context, productID, variantID int, quantity uint) (bool, error) {
+	available, err := s.repo.GetAvailableQuantity(ctx, productID, variantID)
 	if err != nil {
 		return false, err
 	}
 
-	if inventoryItem.Quantity >= int(quantity) {
+	if available >= int(quantity) {
 		return true, nil
 	}
 
 	return false, nil
 }
 
-func (s *InventoryService) DecrementInventory(ctx context.Context, productID int, quantity uint) error {
-	return s.repo.UpdateInventoryQuantity(ctx, productID, -int(quantity))
+// CheckAvailabilities checks every item with a single query. Products and variants without inventory are unavailable.
+func (s *InventoryService) CheckAvailabilities(ctx context.Context, items []model.StockItem) (*model.AvailabilityResult, error) {
+	keys := make([]model.StockKey, 0, len(items))
+	for _, item := range items {
+		keys = append(keys, item.Key())
+	}
+
+	quantities, err := s.repo.GetAvailableQuantities(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &model.AvailabilityResult{Available: true, Items: make([]model.Availability, 0, len(items))}
+	for _, item := range items {
+		a := model.Availability{
+			ProductID:  item.ProductID,
+			VariantID:  item.VariantID,
+			Requested:  item.Quantity,
+			Available:  quantities[item.Key()],
+			Sufficient: quantities[item.Key()] >= item.Quantity,
+		}
+
+		res.Available = res.Available && a.Sufficient
+		res.Items = append(res.Items, a)
+	}
+
+	return res, nil
 }
 
-func (s *InventoryService) IncrementInventory(ctx context.Context, productID int, quantity uint) error {
-	return s.repo.UpdateInventoryQuantity(ctx, productID, int(quantity))
+// AdjustInventory applies all adjustments atomically, at most once per non-empty reference.
+func (s *InventoryService) AdjustInventory(ctx context.Context, reference string, adjustments []model.StockAdjustment) ([]*model.InventoryItem, error) {
+	return s.repo.AdjustInventoryQuantities(ctx, reference, adjustments)
 }
 
-func (s *InventoryService) Publish(ctx context.Context, topic events.Topic, key events.RoutingKey, e events.EventData) error {
-	// create exchange if it doesn't exist
-	err := s.rc.CreateExchange(topic, true, false)
-	if err != nil {
-		s.log.Err(err).Msg("Failed to create exchange")
-		return err
+// ReverseAdjustment undoes the adjustments made with reference. It also keeps them from being
+// applied later when they have not been yet.
+func (s *InventoryService) ReverseAdjustment(ctx context.Context, reference string) ([]*model.InventoryItem, error) {
+	return s.repo.ReverseAdjustment(ctx, reference)
+}
+
+func (s *InventoryService) DecrementInventory(ctx context.Context, productID, variantID int, quantity uint) error {
+	return s.repo.UpdateInventoryQuantity(ctx, productID, variantID, -int(quantity))
+}
+
+func (s *InventoryService) IncrementInventory(ctx context.Context, productID, variantID int, quantity uint) error {
+	return s.repo.UpdateInventoryQuantity(ctx, productID, variantID, int(quantity))
+}
+
+func (s *InventoryService) ReserveInventory(ctx context.Context, req *model.ReservationRequest) ([]*model.Reservation, error) {
+	ttl := s.reservationTTL
+	if req.TTLSeconds > 0 {
+		ttl = time.Duration(req.TTLSeconds) * time.Second
 	}
 
-	return s.rc.Send(ctx, topic, key, e)
+	return s.repo.CreateReservations(ctx, req.Reference, req.Items, time.Now().Add(ttl))
+}
+
+func (s *InventoryService) GetReservations(ctx context.Context, reference string) ([]*model.Reservation, error) {
+	return s.repo.GetReservationsByReference(ctx, reference)
+}
+
+// CommitReservation turns the held stock for reference into a permanent decrement.
+func (s *InventoryService) CommitReservation(ctx context.Context, reference string) ([]*model.Reservation, error) {
+	return s.repo.CommitReservations(ctx, reference)
+}
+
+func (s *InventoryService) ReleaseReservation(ctx context.Context, reference string) ([]*model.Reservation, error) {
+	return s.repo.ReleaseReservations(ctx, reference)
+}
+
+// SweepExpiredReservations marks stale holds as expired every interval until ctx is done.
+func (s *InventoryService) SweepExpiredReservations(ctx context.Context, interval time.Duration) {
+	log := s.log.With().Str("method", "SweepExpiredReservations").Logger()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			expired, err := s.repo.ExpireReservations(ctx)
+			if err != nil {
+				log.Err(err).Msg("failed to expire reservations")
+				continue
+			}
+
+			if expired > 0 {
+				log.Info().Int64("expired", expired).Msg("expired stale reservations")
+			}
+		}
+	}
 }
 
-func (s *InventoryService) Listen(ctx context.Context, topic events.Topic, key events.RoutingKey) error {
-	msgs, err := s.rc.Listen(ctx, events.Product, key, false)
+// Listen consumes the given events from topic through queue and dispatches them to the event handlers.
+func (s *InventoryService) Listen(ctx context.Context, topic events.Topic, queue string, keys ...events.RoutingKey) error {
+	msgs, err := s.rc.Listen(ctx, topic, queue, false, keys...)
 	if err != nil {
 		s.log.Err(err).Msg("Failed to create queue binding")
 		return err
@@ -1,6 +1,9 @@
 package order
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var ErrInsufficientStock = errors.New("insufficient stock")
 var ErrNotFound = errors.New("order not found")
@@ -11,3 +14,25 @@ var ErrInvalidQuantity = errors.New("quantity must not be negative or zero")
 var ErrInvalidProduct = errors.New("product not found")
 var ErrInvalidCart = errors.New("cart not found")
 var ErrInvalidJWToken = errors.New("unauthorized, invalid token")
+var ErrForbidden = errors.New("forbidden, not allowed to access this order")
+var ErrOrderPlacementFailed = errors.New("order placement failed")
+var ErrInvalidStatus = errors.New("invalid order status")
+var ErrInvalidStatusTransition = errors.New("invalid order status transition")
+var ErrInvalidShipment = errors.New("carrier and tracking number can only be recorded when an order is shipped")
+var ErrAwaitingPayment = errors.New("order is awaiting its payment")
+var ErrMixedCurrencies = errors.New("order items must be priced in a single currency")
+
+// StatusTransitionError is returned when an order cannot move from one status to another.
+// It matches ErrInvalidStatusTransition with errors.Is.
+type StatusTransitionError struct {
+	From string
+	To   string
+}
+
+func (e *StatusTransitionError) Error() string {
+	return fmt.Sprintf("%s: cannot move order from %s to %s", ErrInvalidStatusTransition, e.From, e.To)
+}
+
+func (e *StatusTransitionError) Is(target error) bool {
+	return target == ErrInvalidStatusTransition
+}
@@ -6,6 +6,9 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/google/uuid"
+	"github.com/rovilay/ecommerce-service/common/httpclient"
+	"github.com/rovilay/ecommerce-service/common/utils"
 	"github.com/rovilay/ecommerce-service/domains/order"
 )
 
@@ -16,34 +19,41 @@ type Cart struct {
 
 type CartItem struct {
 	ProductID int `json:"product_id"`
+	VariantID int `json:"variant_id"`
 	Quantity  int `json:"quantity"`
 }
 
+// CartService reads and clears a user's cart through the cart service's internal endpoints,
+// so it works without the user's token (e.g. when a saga is resumed).
 type CartService interface {
-	GetCart(ctx context.Context, authToken string) (*Cart, error)
-	ClearCart(ctx context.Context, authToken string) error
+	GetCart(ctx context.Context, userID uuid.UUID) (*Cart, error)
+	ClearCart(ctx context.Context, userID uuid.UUID) error
 }
 
 type HTTPCartService struct {
 	baseURL    string
-	httpClient *http.Client
+	httpClient *httpclient.Client
+	signer     *utils.ServiceTokenSigner
 }
 
-func NewHTTPCartService(baseURL string) *HTTPCartService {
+func NewHTTPCartService(baseURL string, client *httpclient.Client, signer *utils.ServiceTokenSigner) *HTTPCartService {
 	return &HTTPCartService{
-		httpClient: &http.Client{},
+		httpClient: client,
 		baseURL:    baseURL,
+		signer:     signer,
 	}
 }
 
-func (s *HTTPCartService) GetCart(ctx context.Context, authToken string) (*Cart, error) {
-	url := fmt.Sprintf("%s/api/v1/cart", s.baseURL)
+func (s *HTTPCartService) GetCart(ctx context.Context, userID uuid.UUID) (*Cart, error) {
+	url := fmt.Sprintf("%s/api/v1/internal/carts/%s", s.baseURL, userID)
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", authToken))
+	if err = setServiceToken(req, s.signer, utils.ServiceCart); err != nil {
+		return nil, err
+	}
 
 	resp, err := s.httpClient.Do(req)
 	if err != nil {
@@ -64,14 +74,16 @@ func (s *HTTPCartService) GetCart(ctx context.Context, authToken string) (*Cart,
 	return &cart, nil
 }
 
-func (s *HTTPCartService) ClearCart(ctx context.Context, authToken string) error {
-	url := fmt.Sprintf("%s/api/v1/cart", s.baseURL)
+func (s *HTTPCartService) ClearCart(ctx context.Context, userID uuid.UUID) error {
+	url := fmt.Sprintf("%s/api/v1/internal/carts/%s", s.baseURL, userID)
 	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, url, nil)
 	if err != nil {
 		return err
 	}
 
-	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", authToken))
+	if err = setServiceToken(req, s.signer, utils.ServiceCart); err != nil {
+		return err
+	}
 
 	resp, err := s.httpClient.Do(req)
 	if err != nil {
@@ -7,23 +7,59 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	neturl "net/url"
+
+	"github.com/google/uuid"
+	"github.com/rovilay/ecommerce-service/common/httpclient"
+	"github.com/rovilay/ecommerce-service/common/utils"
+	"github.com/rovilay/ecommerce-service/domains/order"
 )
 
+type StockItem struct {
+	ProductID int `json:"product_id"`
+	VariantID int `json:"variant_id"`
+	Quantity  int `json:"quantity"`
+}
+
+type Availability struct {
+	ProductID  int  `json:"product_id"`
+	VariantID  int  `json:"variant_id"`
+	Requested  int  `json:"requested"`
+	Available  int  `json:"available"`
+	Sufficient bool `json:"sufficient"`
+}
+
+// StockAdjustment changes the stock of a product or variant by Delta, which is negative for a decrement.
+type StockAdjustment struct {
+	ProductID int `json:"product_id"`
+	VariantID int `json:"variant_id"`
+	Delta     int `json:"delta"`
+}
+
 type InventoryService interface {
 	CheckAvailability(ctx context.Context, productID int, quantity int) (bool, error)
 	UpdateInventory(ctx context.Context, descrease bool, productID int, quantity int) error
+	// CheckAvailabilities checks every item in one call.
+	CheckAvailabilities(ctx context.Context, items []StockItem) ([]Availability, error)
+	// AdjustInventory applies every adjustment, or none of them, once per reference: a
+	// repeated or retried call changes nothing.
+	AdjustInventory(ctx context.Context, reference string, adjustments []StockAdjustment) error
+	// ReverseAdjustment undoes the adjustments made with reference, and keeps them from being
+	// applied by a call that is still in flight.
+	ReverseAdjustment(ctx context.Context, reference string) error
 }
 
 type HTTPInventoryService struct {
 	baseURL    string
-	httpClient *http.Client
+	httpClient *httpclient.Client
+	signer     *utils.ServiceTokenSigner
 }
 
-func NewHTTPInventoryService(baseURL string) *HTTPInventoryService {
-
+func NewHTTPInventoryService(baseURL string, client *httpclient.Client, signer *utils.ServiceTokenSigner) *HTTPInventoryService {
 	return &HTTPInventoryService{
-		httpClient: &http.Client{},
+		httpClient: client,
 		baseURL:    baseURL,
+		signer:     signer,
 	}
 }
 
@@ -34,6 +70,10 @@ func (s *HTTPInventoryService) CheckAvailability(ctx context.Context, productID
 		return false, err
 	}
 
+	if err = setServiceToken(req, s.signer, utils.ServiceInventory); err != nil {
+		return false, err
+	}
+
 	resp, err := s.httpClient.Do(req)
 	if err != nil {
 		return false, err
@@ -80,21 +120,160 @@ func (s *HTTPInventoryService) UpdateInventory(ctx context.Context, descrease bo
 	if err != nil {
 		return err
 	}
-	url := fmt.Sprintf("%s/inventory/products/%d/%s", s.baseURL, productID, ops)
+	url := fmt.Sprintf("%s/api/v1/inventory/products/%d/%s", s.baseURL, productID, ops)
 	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return err
 	}
 
+	if err = setServiceToken(req, s.signer, utils.ServiceInventory); err != nil {
+		return err
+	}
+
 	resp, err := s.httpClient.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusBadRequest && descrease {
+		return fmt.Errorf("%w for product: %d", order.ErrInsufficientStock, productID)
+	}
+
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("failed to %s inventory for product: %d", ops, productID)
 	}
 
 	return nil
 }
+
+func (s *HTTPInventoryService) CheckAvailabilities(ctx context.Context, items []StockItem) ([]Availability, error) {
+	jsonData, err := json.Marshal(map[string][]StockItem{"items": items})
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s/api/v1/inventory/availability", s.baseURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+
+	// the check only reads stock, so it is safe to retry
+	req.Header.Set("Idempotency-Key", uuid.NewString())
+
+	if err = setServiceToken(req, s.signer, utils.ServiceInventory); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to check availability: %s", readError(resp))
+	}
+
+	var availableRes struct {
+		Items []Availability `json:"items"`
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(&availableRes); err != nil {
+		return nil, err
+	}
+
+	return availableRes.Items, nil
+}
+
+func (s *HTTPInventoryService) AdjustInventory(ctx context.Context, reference string, adjustments []StockAdjustment) error {
+	jsonData, err := json.Marshal(struct {
+		Reference string            `json:"reference"`
+		Items     []StockAdjustment `json:"items"`
+	}{reference, adjustments})
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/api/v1/inventory/adjustments", s.baseURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return err
+	}
+
+	// inventory applies a reference once, so a request that timed out can be retried
+	req.Header.Set("Idempotency-Key", reference)
+
+	if err = setServiceToken(req, s.signer, utils.ServiceInventory); err != nil {
+		return err
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, code := readErrorResponse(resp)
+		if code == codeInsufficientStock {
+			return fmt.Errorf("%w: %s", order.ErrInsufficientStock, msg)
+		}
+
+		return fmt.Errorf("failed to adjust inventory: %s", msg)
+	}
+
+	return nil
+}
+
+func (s *HTTPInventoryService) ReverseAdjustment(ctx context.Context, reference string) error {
+	url := fmt.Sprintf("%s/api/v1/inventory/adjustments/%s/reverse", s.baseURL, neturl.PathEscape(reference))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
+	if err != nil {
+		return err
+	}
+
+	// reversing twice changes nothing
+	req.Header.Set("Idempotency-Key", reference)
+
+	if err = setServiceToken(req, s.signer, utils.ServiceInventory); err != nil {
+		return err
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to reverse inventory adjustment %s: %s", reference, readError(resp))
+	}
+
+	return nil
+}
+
+// codeInsufficientStock is the error code inventory fails adjustments that would oversell with.
+const codeInsufficientStock = "insufficient_stock"
+
+// readError returns the error message of a failed response, or its status when there is none.
+func readError(resp *http.Response) string {
+	msg, _ := readErrorResponse(resp)
+	return msg
+}
+
+// readErrorResponse returns the error message of a failed response, or its status when there
+// is none, and its error code if it has one.
+func readErrorResponse(resp *http.Response) (string, string) {
+	var errRes struct {
+		Error string `json:"error"`
+		Code  string `json:"code"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&errRes); err != nil || errRes.Error == "" {
+		return resp.Status, errRes.Code
+	}
+
+	return errRes.Error, errRes.Code
+}
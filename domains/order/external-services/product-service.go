@@ -5,28 +5,77 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
+	"github.com/rovilay/ecommerce-service/common/httpclient"
+	"github.com/rovilay/ecommerce-service/common/money"
+	"github.com/rovilay/ecommerce-service/common/utils"
 	"github.com/rovilay/ecommerce-service/domains/order"
 )
 
 type Product struct {
-	ID    int     `json:"id"`
-	Price float32 `json:"price"`
+	ID      int         `json:"id"`
+	Name    string      `json:"name"`
+	SKU     string      `json:"sku"`
+	Price   money.Money `json:"price"`
+	Deleted bool        `json:"deleted"`
+	// WeightGrams is the shipping weight of one unit.
+	WeightGrams int `json:"weight_grams"`
+	// CategoryIDs holds the category of the product and all of its ancestors.
+	CategoryIDs []int `json:"category_ids"`
+
+	Variants []ProductVariant `json:"variants"`
+}
+
+// ProductVariant is a variant of a product priced at what it sells for.
+type ProductVariant struct {
+	ID      int         `json:"id"`
+	SKU     string      `json:"sku"`
+	Price   money.Money `json:"price"`
+	Deleted bool        `json:"deleted"`
+}
+
+// Variant returns the live variant with the given ID, or nil.
+func (p *Product) Variant(id int) *ProductVariant {
+	for i := range p.Variants {
+		if p.Variants[i].ID == id && !p.Variants[i].Deleted {
+			return &p.Variants[i]
+		}
+	}
+
+	return nil
+}
+
+// HasVariants reports whether the product is only sold as one of its variants.
+func (p *Product) HasVariants() bool {
+	for _, v := range p.Variants {
+		if !v.Deleted {
+			return true
+		}
+	}
+
+	return false
 }
 
 type ProductService interface {
 	GetProduct(ctx context.Context, productID int) (*Product, error)
+	// GetProducts looks up several products, in as few calls as the product service allows.
+	// Deleted products are included and flagged, unknown ids are left out.
+	GetProducts(ctx context.Context, productIDs []int) ([]*Product, error)
 }
 
 type HTTPProductService struct {
 	baseURL    string
-	httpClient *http.Client
+	httpClient *httpclient.Client
+	signer     *utils.ServiceTokenSigner
 }
 
-func NewHTTPProductService(baseURL string) *HTTPProductService {
+func NewHTTPProductService(baseURL string, client *httpclient.Client, signer *utils.ServiceTokenSigner) *HTTPProductService {
 	return &HTTPProductService{
-		httpClient: &http.Client{},
+		httpClient: client,
 		baseURL:    baseURL,
+		signer:     signer,
 	}
 }
 
@@ -37,6 +86,10 @@ func (s *HTTPProductService) GetProduct(ctx context.Context, productID int) (*Pr
 		return nil, err
 	}
 
+	if err = setServiceToken(req, s.signer, utils.ServiceProduct); err != nil {
+		return nil, err
+	}
+
 	resp, err := s.httpClient.Do(req)
 	if err != nil {
 		return nil, err
@@ -55,3 +108,58 @@ func (s *HTTPProductService) GetProduct(ctx context.Context, productID int) (*Pr
 
 	return &prd, nil
 }
+
+// maxLookupIDs is the most products the product service looks up in one request.
+const maxLookupIDs = 100
+
+func (s *HTTPProductService) GetProducts(ctx context.Context, productIDs []int) ([]*Product, error) {
+	products := make([]*Product, 0, len(productIDs))
+	for start := 0; start < len(productIDs); start += maxLookupIDs {
+		end := min(start+maxLookupIDs, len(productIDs))
+
+		batch, err := s.lookupProducts(ctx, productIDs[start:end])
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, batch...)
+	}
+
+	return products, nil
+}
+
+func (s *HTTPProductService) lookupProducts(ctx context.Context, productIDs []int) ([]*Product, error) {
+	ids := make([]string, 0, len(productIDs))
+	for _, id := range productIDs {
+		ids = append(ids, strconv.Itoa(id))
+	}
+
+	url := fmt.Sprintf("%s/api/v1/products?ids=%s", s.baseURL, strings.Join(ids, ","))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = setServiceToken(req, s.signer, utils.ServiceProduct); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to look up products: %s", readError(resp))
+	}
+
+	var prdRes struct {
+		Items []*Product `json:"items"`
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(&prdRes); err != nil {
+		return nil, err
+	}
+
+	return prdRes.Items, nil
+}
@@ -0,0 +1,19 @@
+package externalservices
+
+import (
+	"net/http"
+
+	"github.com/rovilay/ecommerce-service/common/utils"
+)
+
+// setServiceToken authenticates req as the order service to audience.
+func setServiceToken(req *http.Request, signer *utils.ServiceTokenSigner, audience string) error {
+	token, err := signer.Sign(audience)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set(utils.ServiceTokenHeader, token)
+
+	return nil
+}
@@ -7,6 +7,9 @@ import (
 
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
+	"github.com/rovilay/ecommerce-service/common/money"
+	"github.com/rovilay/ecommerce-service/domains/shipping"
+	"github.com/rovilay/ecommerce-service/domains/tax"
 )
 
 type OrderStatus string
@@ -19,6 +22,31 @@ const (
 	OrderStatusRefunded   OrderStatus = "refunded"
 )
 
+// orderStatusTransitions lists the statuses each status may move to.
+var orderStatusTransitions = map[OrderStatus][]OrderStatus{
+	OrderStatusPending:    {OrderStatusProcessing, OrderStatusCancelled},
+	OrderStatusProcessing: {OrderStatusShipped, OrderStatusCancelled},
+	OrderStatusShipped:    {OrderStatusRefunded},
+	OrderStatusCancelled:  {},
+	OrderStatusRefunded:   {},
+}
+
+func (s OrderStatus) IsValid() bool {
+	_, ok := orderStatusTransitions[s]
+	return ok
+}
+
+// CanTransitionTo reports whether an order in status s may move to next.
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	for _, allowed := range orderStatusTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+
+	return false
+}
+
 type Address struct {
 	Street     string `json:"street" validate:"required"`
 	City       string `json:"city" validate:"required"`
@@ -45,22 +73,115 @@ func (a *Address) Value() ([]byte, error) {
 }
 
 type Order struct {
-	ID              int         `json:"id"`
-	UserID          uuid.UUID   `json:"user_id"`
-	Status          OrderStatus `json:"status"`
-	TotalPrice      float32     `json:"total_price"`
-	ShippingAddress Address     `json:"shipping_address" validate:"required"`
-	OrderItems      []OrderItem `json:"order_items" validate:"omitempty,required"`
-	CreatedAt       time.Time   `json:"created_at"`
-	UpdatedAt       time.Time   `json:"updated_at"`
+	ID     int         `json:"id"`
+	UserID uuid.UUID   `json:"user_id"`
+	Status OrderStatus `json:"status"`
+	// PaymentToken stands for the customer's payment method. It is only used to authorize the
+	// payment of the order and is never stored with it.
+	PaymentToken string `json:"payment_token,omitempty"`
+	// CouponCode is the coupon the customer placed the order with, if any.
+	CouponCode string `json:"coupon_code,omitempty" validate:"max=50"`
+	// Subtotal is the price of the items before discounts and tax, TotalPrice what is charged
+	// for them and their shipping.
+	Subtotal        money.Money     `json:"subtotal"`
+	DiscountTotal   money.Money     `json:"discount_total"`
+	TaxTotal        money.Money     `json:"tax_total"`
+	ShippingTotal   money.Money     `json:"shipping_total"`
+	TotalPrice      money.Money     `json:"total_price"`
+	FreeShipping    bool            `json:"free_shipping"`
+	Discounts       []OrderDiscount `json:"discounts"`
+	ShippingAddress Address         `json:"shipping_address" validate:"required"`
+	// ShippingMethodID is the method the customer picked, or the cheapest available one when
+	// they did not. ShippingMethod is its name at the time the order was placed.
+	ShippingMethodID *int       `json:"shipping_method_id,omitempty"`
+	ShippingMethod   string     `json:"shipping_method,omitempty"`
+	Carrier          string     `json:"carrier,omitempty"`
+	TrackingNumber   string     `json:"tracking_number,omitempty"`
+	ShippedAt        *time.Time `json:"shipped_at,omitempty"`
+	// OrderItems are left empty to order the customer's cart.
+	OrderItems []OrderItem `json:"order_items" validate:"omitempty,dive"`
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
 }
 
 type OrderItem struct {
-	ID        int     `json:"id"`
-	OrderID   int     `json:"order_id"`
-	ProductID int     `json:"product_id" validate:"required"`
-	Quantity  int     `json:"quantity" validate:"required"`
-	Price     float32 `json:"price"`
+	ID        int         `json:"id"`
+	OrderID   int         `json:"order_id"`
+	ProductID int         `json:"product_id" validate:"required"`
+	VariantID int         `json:"variant_id" validate:"min=0"` // 0 when the product has no variants
+	Quantity  int         `json:"quantity" validate:"required,gt=0"`
+	Price     money.Money `json:"price"` // unit price
+	// product details at the time the order was placed
+	ProductName string `json:"product_name"`
+	ProductSKU  string `json:"product_sku"`
+	// Discount is taken off the line total by the promotions in Discounts.
+	Discount  money.Money         `json:"discount"`
+	Discounts []OrderItemDiscount `json:"discounts,omitempty"`
+	// Tax is charged on the line total less its discount, at the rate the order was placed
+	// with. TaxRateID is nil when no rate covered the shipping address.
+	Tax       money.Money `json:"tax"`
+	TaxRate   tax.Rate    `json:"tax_rate"`
+	TaxRateID *int        `json:"tax_rate_id,omitempty"`
+	TaxName   string      `json:"tax_name,omitempty"`
+}
+
+// OrderDiscount is a promotion applied to an order, as it was when the order was placed.
+type OrderDiscount struct {
+	ID          int         `json:"id"`
+	PromotionID int         `json:"promotion_id"`
+	Code        string      `json:"code,omitempty"`
+	Name        string      `json:"name"`
+	Type        string      `json:"type"`
+	Amount      money.Money `json:"amount"`
+}
+
+// OrderItemDiscount is the share of an order discount taken off one line.
+type OrderItemDiscount struct {
+	PromotionID int         `json:"promotion_id"`
+	Amount      money.Money `json:"amount"`
+}
+
+// OrderQuote is what an order for a set of items would cost before tax, with the price of
+// every shipping method that can ship them.
+type OrderQuote struct {
+	Subtotal        money.Money      `json:"subtotal"`
+	DiscountTotal   money.Money      `json:"discount_total"`
+	FreeShipping    bool             `json:"free_shipping"`
+	WeightGrams     int              `json:"weight_grams"`
+	ShippingMethods []shipping.Quote `json:"shipping_methods"`
+}
+
+// Shipment is how a shipped order is tracked.
+type Shipment struct {
+	Carrier        string `json:"carrier" validate:"max=100"`
+	TrackingNumber string `json:"tracking_number" validate:"max=100"`
+}
+
+// PaymentSettlement is the change to its payment an order moving to Status calls for: shipped
+// orders capture it, cancelled ones void it and refunded ones refund it. It is recorded with
+// the status change and carried out at the gateway once the change has committed.
+type PaymentSettlement struct {
+	ID        int         `json:"id" db:"id"`
+	OrderID   int         `json:"order_id" db:"order_id"`
+	Status    OrderStatus `json:"status" db:"status"`
+	Attempts  int         `json:"attempts" db:"attempts"`
+	LastError string      `json:"last_error,omitempty" db:"last_error"`
+	CreatedAt time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at" db:"updated_at"`
+}
+
+// SettlesPayment reports whether orders moving to s need their payment settled.
+func (s OrderStatus) SettlesPayment() bool {
+	return s == OrderStatusShipped || s == OrderStatusCancelled || s == OrderStatusRefunded
+}
+
+type OrderStatusChange struct {
+	ID         int          `json:"id" db:"id"`
+	OrderID    int          `json:"order_id" db:"order_id"`
+	FromStatus *OrderStatus `json:"from_status" db:"from_status"`
+	ToStatus   OrderStatus  `json:"to_status" db:"to_status"`
+	ChangedBy  *uuid.UUID   `json:"changed_by" db:"changed_by"`
+	CreatedAt  time.Time    `json:"created_at" db:"created_at"`
 }
 
 type PaginationResult[T any] struct {
@@ -74,3 +195,8 @@ func (o *Order) Validate() error {
 	v := validator.New()
 	return v.Struct(o)
 }
+
+func (s *Shipment) Validate() error {
+	v := validator.New()
+	return v.Struct(s)
+}
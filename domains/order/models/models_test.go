@@ -0,0 +1,99 @@
+package models
+
+import "testing"
+
+func TestOrderStatusCanTransitionTo(t *testing.T) {
+	const (
+		pending    = OrderStatusPending
+		processing = OrderStatusProcessing
+		shipped    = OrderStatusShipped
+		cancelled  = OrderStatusCancelled
+		refunded   = OrderStatusRefunded
+	)
+
+	tests := []struct {
+		from, to OrderStatus
+		want     bool
+	}{
+		{pending, pending, false},
+		{pending, processing, true},
+		{pending, shipped, false},
+		{pending, cancelled, true},
+		{pending, refunded, false},
+
+		{processing, pending, false},
+		{processing, processing, false},
+		{processing, shipped, true},
+		{processing, cancelled, true},
+		{processing, refunded, false},
+
+		{shipped, pending, false},
+		{shipped, processing, false},
+		{shipped, shipped, false},
+		{shipped, cancelled, false},
+		{shipped, refunded, true},
+
+		{cancelled, pending, false},
+		{cancelled, processing, false},
+		{cancelled, shipped, false},
+		{cancelled, cancelled, false},
+		{cancelled, refunded, false},
+
+		{refunded, pending, false},
+		{refunded, processing, false},
+		{refunded, shipped, false},
+		{refunded, cancelled, false},
+		{refunded, refunded, false},
+
+		{"unknown", processing, false},
+		{pending, "unknown", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"->"+string(tt.to), func(t *testing.T) {
+			if got := tt.from.CanTransitionTo(tt.to); got != tt.want {
+				t.Errorf("%s.CanTransitionTo(%s) = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderStatusIsValid(t *testing.T) {
+	for _, s := range []OrderStatus{OrderStatusPending, OrderStatusProcessing, OrderStatusShipped, OrderStatusCancelled, OrderStatusRefunded} {
+		if !s.IsValid() {
+			t.Errorf("%s.IsValid() = false, want true", s)
+		}
+	}
+
+	for _, s := range []OrderStatus{"", "unknown", "Pending"} {
+		if s.IsValid() {
+			t.Errorf("%q.IsValid() = true, want false", s)
+		}
+	}
+}
+
+func TestOrderValidateItems(t *testing.T) {
+	address := Address{Street: "1 Main St", City: "Lagos", State: "LA", Country: "NG", PostalCode: "100001"}
+
+	tests := []struct {
+		name    string
+		items   []OrderItem
+		wantErr bool
+	}{
+		{name: "cart order", items: nil},
+		{name: "positive quantity", items: []OrderItem{{ProductID: 1, Quantity: 2}}},
+		{name: "zero quantity", items: []OrderItem{{ProductID: 1, Quantity: 0}}, wantErr: true},
+		{name: "negative quantity", items: []OrderItem{{ProductID: 1, Quantity: 1}, {ProductID: 2, Quantity: -1}}, wantErr: true},
+		{name: "missing product", items: []OrderItem{{Quantity: 1}}, wantErr: true},
+		{name: "negative variant", items: []OrderItem{{ProductID: 1, VariantID: -1, Quantity: 1}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &Order{ShippingAddress: address, OrderItems: tt.items}
+			if err := o.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
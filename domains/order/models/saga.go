@@ -0,0 +1,72 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type SagaStatus string
+
+const (
+	SagaStatusStarted       SagaStatus = "started"
+	SagaStatusStockReserved SagaStatus = "stock_reserved"
+	SagaStatusOrderCreated  SagaStatus = "order_created"
+	// SagaStatusPaymentAuthorized is reached once the gateway has answered the authorization,
+	// even if it will only confirm it later.
+	SagaStatusPaymentAuthorized SagaStatus = "payment_authorized"
+	SagaStatusCompleted         SagaStatus = "completed"
+	SagaStatusCompensating      SagaStatus = "compensating"
+	SagaStatusCompensated       SagaStatus = "compensated"
+)
+
+// IsTerminal reports whether the saga has nothing left to run.
+func (s SagaStatus) IsTerminal() bool {
+	return s == SagaStatusCompleted || s == SagaStatusCompensated
+}
+
+// SagaPayload is the state an order saga needs to move forward or to undo its steps.
+type SagaPayload struct {
+	Order    Order `json:"order"`
+	FromCart bool  `json:"from_cart"`
+	// PaymentToken is kept until the payment is authorized.
+	PaymentToken string `json:"payment_token,omitempty"`
+	// ReservedItems holds the items whose stock has been decremented and not yet restored.
+	ReservedItems []OrderItem `json:"reserved_items"`
+}
+
+func (p *SagaPayload) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+
+	var bytes []byte
+	switch v := value.(type) {
+	case []byte:
+		bytes = v
+	case string:
+		bytes = []byte(v)
+	default:
+		return fmt.Errorf("failed to unmarshal JSONB value: %v", value)
+	}
+
+	return json.Unmarshal(bytes, p)
+}
+
+func (p SagaPayload) Value() (driver.Value, error) {
+	return json.Marshal(p)
+}
+
+type OrderSaga struct {
+	ID        uuid.UUID   `json:"id"`
+	UserID    uuid.UUID   `json:"user_id"`
+	OrderID   *int        `json:"order_id"`
+	Status    SagaStatus  `json:"status"`
+	Payload   SagaPayload `json:"payload"`
+	Error     string      `json:"error"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rovilay/ecommerce-service/common/money"
+	"github.com/rovilay/ecommerce-service/domains/order/models"
+	"github.com/rovilay/ecommerce-service/domains/promotion"
+)
+
+// createDiscounts stores the discounts of a new order and their shares of its lines. The
+// promotions are locked before their usage is counted, so concurrent orders cannot go past
+// a usage limit together.
+func (r *postgresOrderRepository) createDiscounts(ctx context.Context, tx *sql.Tx, o *models.Order) error {
+	if len(o.Discounts) == 0 {
+		return nil
+	}
+
+	ids := make([]int, len(o.Discounts))
+	for i, d := range o.Discounts {
+		ids[i] = d.PromotionID
+	}
+
+	// locked in id order so orders applying the same promotions cannot deadlock
+	query := `SELECT id FROM promotions WHERE id = ANY($1) ORDER BY id FOR UPDATE`
+	if _, err := tx.ExecContext(ctx, query, ids); err != nil {
+		return err
+	}
+
+	// counted in a statement of its own: under READ COMMITTED it sees the discounts of orders
+	// that held the locks before us, which the locking statement's snapshot does not
+	query = `
+		SELECT p.id, p.usage_limit, p.per_user_limit,
+			(SELECT count(*) FROM order_discounts d JOIN orders ord ON ord.id = d.order_id
+				WHERE d.promotion_id = p.id AND ord.status <> 'cancelled'),
+			(SELECT count(*) FROM order_discounts d JOIN orders ord ON ord.id = d.order_id
+				WHERE d.promotion_id = p.id AND ord.status <> 'cancelled' AND ord.user_id = $2)
+		FROM promotions p
+		WHERE p.id = ANY($1)
+	`
+	rows, err := tx.QueryContext(ctx, query, ids, o.UserID)
+	if err != nil {
+		return err
+	}
+
+	var exhausted []int
+	for rows.Next() {
+		var id int
+		var p promotion.Promotion
+		var u promotion.Usage
+		if err := rows.Scan(&id, &p.UsageLimit, &p.PerUserLimit, &u.Total, &u.ByUser); err != nil {
+			rows.Close()
+			return err
+		}
+
+		if !p.Allows(u) {
+			exhausted = append(exhausted, id)
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	if len(exhausted) > 0 {
+		return fmt.Errorf("%w: promotion %v", promotion.ErrUsageLimitReached, exhausted)
+	}
+
+	query = `
+		INSERT INTO order_discounts (order_id, promotion_id, code, name, type, amount)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6)
+		RETURNING id
+	`
+	discountIDs := make(map[int]int, len(o.Discounts))
+	for i := range o.Discounts {
+		d := &o.Discounts[i]
+		err = tx.QueryRowContext(ctx, query, o.ID, d.PromotionID, d.Code, d.Name, d.Type, d.Amount).Scan(&d.ID)
+		if err != nil {
+			return err
+		}
+
+		discountIDs[d.PromotionID] = d.ID
+	}
+
+	query = `INSERT INTO order_item_discounts (order_discount_id, order_item_id, amount) VALUES ($1, $2, $3)`
+	for _, item := range o.OrderItems {
+		for _, d := range item.Discounts {
+			if _, err = tx.ExecContext(ctx, query, discountIDs[d.PromotionID], item.ID, d.Amount); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *postgresOrderRepository) getOrderDiscounts(ctx context.Context, q sqlx.QueryerContext, orderID int, currency string) ([]models.OrderDiscount, error) {
+	query := `
+		SELECT id, promotion_id, coalesce(code, ''), name, type, amount
+		FROM order_discounts
+		WHERE order_id = $1
+		ORDER BY id
+	`
+	rows, err := q.QueryxContext(ctx, query, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	discounts := []models.OrderDiscount{}
+	for rows.Next() {
+		d := models.OrderDiscount{Amount: money.New(0, currency)}
+		if err := rows.Scan(&d.ID, &d.PromotionID, &d.Code, &d.Name, &d.Type, &d.Amount); err != nil {
+			return nil, err
+		}
+
+		discounts = append(discounts, d)
+	}
+
+	return discounts, rows.Err()
+}
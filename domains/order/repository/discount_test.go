@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/jmoiron/sqlx"
+	"github.com/rovilay/ecommerce-service/common/money"
+	"github.com/rovilay/ecommerce-service/domains/order/models"
+	"github.com/rovilay/ecommerce-service/domains/promotion"
+	"github.com/rs/zerolog"
+)
+
+// testRepository connects to the migrated database at TEST_DATABASE_URL, and skips the test
+// when it is unset.
+func testRepository(t *testing.T) (*postgresOrderRepository, *sqlx.DB) {
+	t.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db, err := sqlx.Connect("pgx", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	logger := zerolog.Nop()
+	return NewPostgresOrderRepository(context.Background(), db, &logger), db
+}
+
+func testOrder(promotionID int) *models.Order {
+	usd := func(a int64) money.Money { return money.New(a, "USD") }
+
+	return &models.Order{
+		UserID:          uuid.New(),
+		Status:          models.OrderStatusPending,
+		Subtotal:        usd(1000),
+		DiscountTotal:   usd(100),
+		TaxTotal:        usd(0),
+		ShippingTotal:   usd(0),
+		TotalPrice:      usd(900),
+		ShippingAddress: models.Address{Street: "1 Main St", City: "Lagos", State: "LA", Country: "NG", PostalCode: "100001"},
+		Discounts: []models.OrderDiscount{
+			{PromotionID: promotionID, Name: "Last one", Type: "fixed_amount", Amount: usd(100)},
+		},
+	}
+}
+
+func TestCreateDiscountsUsageLimitUnderConcurrency(t *testing.T) {
+	repo, db := testRepository(t)
+	ctx := context.Background()
+
+	for round := 0; round < 20; round++ {
+		var promotionID int
+		query := `INSERT INTO promotions (name, type, amount_off, usage_limit) VALUES ('Last one', 'fixed_amount', 1, 2) RETURNING id`
+		if err := db.GetContext(ctx, &promotionID, query); err != nil {
+			t.Fatal(err)
+		}
+
+		// one of the two uses is taken already
+		if _, err := repo.CreateOrder(ctx, testOrder(promotionID)); err != nil {
+			t.Fatal(err)
+		}
+
+		var wg sync.WaitGroup
+		errs := make([]error, 2)
+		for i := range errs {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, errs[i] = repo.CreateOrder(ctx, testOrder(promotionID))
+			}(i)
+		}
+		wg.Wait()
+
+		placed := 0
+		for _, err := range errs {
+			if err == nil {
+				placed++
+			} else if !errors.Is(err, promotion.ErrUsageLimitReached) {
+				t.Fatalf("round %d: CreateOrder() error = %v, want nil or %v", round, err, promotion.ErrUsageLimitReached)
+			}
+		}
+		if placed != 1 {
+			t.Fatalf("round %d: %d concurrent orders used the last use of the promotion, want 1", round, placed)
+		}
+
+		var used int
+		query = `SELECT count(*) FROM order_discounts WHERE promotion_id = $1`
+		if err := db.GetContext(ctx, &used, query, promotionID); err != nil {
+			t.Fatal(err)
+		}
+		if used != 2 {
+			t.Fatalf("round %d: promotion used %d times, want its limit of 2", round, used)
+		}
+	}
+}
@@ -10,8 +10,10 @@ import (
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jmoiron/sqlx"
+	"github.com/rovilay/ecommerce-service/common/events"
 	"github.com/rovilay/ecommerce-service/domains/order"
 	"github.com/rovilay/ecommerce-service/domains/order/models"
+	"github.com/rovilay/ecommerce-service/domains/promotion"
 	"github.com/rs/zerolog"
 )
 
@@ -48,23 +50,29 @@ func (r *postgresOrderRepository) CreateOrder(ctx context.Context, order *models
 	}
 	// 1. Insert Order
 	query1 := `
-        INSERT INTO orders (user_id, status, total_price, shipping_address)
-        VALUES ($1, $2, $3, $4)
-        RETURNING id
+        INSERT INTO orders (user_id, status, subtotal, discount_total, tax_total, shipping_total, total_price, currency, coupon_code,
+            free_shipping, shipping_address, shipping_method_id, shipping_method)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), $10, $11, $12, $13)
+        RETURNING id, created_at, updated_at
     `
-	err = tx.QueryRowContext(ctx, query1, order.UserID, string(order.Status), order.TotalPrice, order.ShippingAddress).Scan(&orderID.ID)
+	err = tx.QueryRowContext(ctx, query1, order.UserID, string(order.Status), order.Subtotal, order.DiscountTotal, order.TaxTotal,
+		order.ShippingTotal, order.TotalPrice, order.TotalPrice.Currency, order.CouponCode, order.FreeShipping, order.ShippingAddress,
+		order.ShippingMethodID, order.ShippingMethod).
+		Scan(&orderID.ID, &order.CreatedAt, &order.UpdatedAt)
 	if err != nil {
 		return nil, r.mapDatabaseError(err, &log)
 	}
 
 	// 2. Insert Order Items
 	query2 := `
-        INSERT INTO order_items (order_id, product_id, quantity, price)
-        VALUES ($1, $2, $3, $4)
+        INSERT INTO order_items (order_id, product_id, variant_id, quantity, price, product_name, product_sku, discount,
+            tax, tax_rate, tax_rate_id, tax_name)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		RETURNING id
     `
 	for i, item := range order.OrderItems {
-		err = tx.QueryRowContext(ctx, query2, orderID.ID, item.ProductID, item.Quantity, item.Price).
+		err = tx.QueryRowContext(ctx, query2, orderID.ID, item.ProductID, item.VariantID, item.Quantity, item.Price, item.ProductName,
+			item.ProductSKU, item.Discount, item.Tax, item.TaxRate, item.TaxRateID, item.TaxName).
 			Scan(&item.ID)
 		if err != nil {
 			return nil, r.mapDatabaseError(err, &log)
@@ -74,43 +82,107 @@ func (r *postgresOrderRepository) CreateOrder(ctx context.Context, order *models
 		order.OrderItems[i] = item
 	}
 
+	order.ID = orderID.ID
+
+	// 3. Record the discounts, within the usage limits of their promotions
+	if err = r.createDiscounts(ctx, tx, order); err != nil {
+		if errors.Is(err, promotion.ErrUsageLimitReached) {
+			return nil, err
+		}
+		return nil, r.mapDatabaseError(err, &log)
+	}
+
+	// 4. Record the initial status
+	err = r.recordStatusChange(ctx, tx, order.ID, nil, order.Status, &order.UserID)
+	if err != nil {
+		return nil, r.mapDatabaseError(err, &log)
+	}
+
+	// 5. Record order.created for the outbox relay
+	err = events.WriteOutbox(ctx, tx, events.Order, events.OrderCreated, order)
+	if err != nil {
+		return nil, r.mapDatabaseError(err, &log)
+	}
+
 	// If everything succeeds:
 	err = tx.Commit()
 	if err != nil {
 		return nil, r.mapDatabaseError(err, &log)
 	}
 
-	order.ID = orderID.ID
-
 	return order, nil
 }
 
 func (r *postgresOrderRepository) GetOrderByID(ctx context.Context, orderID int) (*models.Order, error) {
 	log := r.log.With().Str("method", "GetOrderByID").Logger()
 
+	order, err := r.getOrderByID(ctx, r.db, orderID, false)
+	if err != nil {
+		return nil, r.mapDatabaseError(err, &log)
+	}
+
+	return order, nil
+}
+
+// getOrderByID loads an order with its items using q, locking the order row when forUpdate is set.
+func (r *postgresOrderRepository) getOrderByID(ctx context.Context, q sqlx.QueryerContext, orderID int, forUpdate bool) (*models.Order, error) {
 	query := `
-        SELECT o.id, o.user_id, o.status, o.total_price, o.shipping_address, o.created_at, o.updated_at, 
-               coalesce(json_agg(oi) FILTER (WHERE oi.id IS NOT NULL), '[]') AS order_items 
+        SELECT o.id, o.user_id, o.status, o.subtotal, o.discount_total, o.tax_total, o.shipping_total, o.total_price, o.currency,
+               coalesce(o.coupon_code, ''), o.free_shipping, o.shipping_address, o.shipping_method_id, o.shipping_method, o.carrier,
+               o.tracking_number, o.shipped_at, o.created_at, o.updated_at,
+               coalesce((
+                   SELECT json_agg(to_jsonb(oi) || jsonb_build_object('discounts', coalesce((
+                       SELECT jsonb_agg(jsonb_build_object('promotion_id', d.promotion_id, 'amount', oid.amount) ORDER BY d.id)
+                       FROM order_item_discounts oid
+                       JOIN order_discounts d ON d.id = oid.order_discount_id
+                       WHERE oid.order_item_id = oi.id
+                   ), '[]')) ORDER BY oi.id)
+                   FROM order_items oi WHERE oi.order_id = o.id
+               ), '[]') AS order_items
         FROM orders o
-        LEFT JOIN order_items oi ON o.id = oi.order_id
         WHERE o.id = $1
-        GROUP BY o.id 
     `
+	if forUpdate {
+		query += " FOR UPDATE OF o"
+	}
 
 	var order models.Order
 	var orderItemsJSON string // To store aggregated JSON
 
-	err := r.db.QueryRowContext(ctx, query, orderID).Scan(
-		&order.ID, &order.UserID, &order.Status, &order.TotalPrice, &order.ShippingAddress, &order.CreatedAt, &order.UpdatedAt, &orderItemsJSON,
+	err := q.QueryRowxContext(ctx, query, orderID).Scan(
+		&order.ID, &order.UserID, &order.Status, &order.Subtotal, &order.DiscountTotal, &order.TaxTotal, &order.ShippingTotal, &order.TotalPrice,
+		&order.TotalPrice.Currency, &order.CouponCode, &order.FreeShipping, &order.ShippingAddress, &order.ShippingMethodID, &order.ShippingMethod,
+		&order.Carrier, &order.TrackingNumber, &order.ShippedAt, &order.CreatedAt, &order.UpdatedAt, &orderItemsJSON,
 	)
 	if err != nil {
-		return nil, r.mapDatabaseError(err, &log)
+		return nil, err
 	}
 
+	order.Subtotal.Currency = order.TotalPrice.Currency
+	order.DiscountTotal.Currency = order.TotalPrice.Currency
+	order.TaxTotal.Currency = order.TotalPrice.Currency
+	order.ShippingTotal.Currency = order.TotalPrice.Currency
+
 	// Unmarshal order items
 	err = json.Unmarshal([]byte(orderItemsJSON), &order.OrderItems)
 	if err != nil {
-		return nil, r.mapDatabaseError(err, &log)
+		return nil, err
+	}
+
+	// items are priced in the order currency
+	for i := range order.OrderItems {
+		item := &order.OrderItems[i]
+		item.Price.Currency = order.TotalPrice.Currency
+		item.Discount.Currency = order.TotalPrice.Currency
+		item.Tax.Currency = order.TotalPrice.Currency
+		for j := range item.Discounts {
+			item.Discounts[j].Amount.Currency = order.TotalPrice.Currency
+		}
+	}
+
+	order.Discounts, err = r.getOrderDiscounts(ctx, q, orderID, order.TotalPrice.Currency)
+	if err != nil {
+		return nil, err
 	}
 
 	return &order, nil
@@ -120,7 +192,9 @@ func (r *postgresOrderRepository) GetOrdersByUser(ctx context.Context, userID uu
 	log := r.log.With().Str("method", "GetOrderByUser").Logger()
 
 	query := `
-		SELECT o.id, o.user_id, o.status, o.total_price, o.shipping_address, o.created_at, o.updated_at
+		SELECT o.id, o.user_id, o.status, o.subtotal, o.discount_total, o.tax_total, o.shipping_total, o.total_price, o.currency,
+			coalesce(o.coupon_code, ''), o.free_shipping, o.shipping_address, o.shipping_method_id, o.shipping_method, o.carrier,
+			o.tracking_number, o.shipped_at, o.created_at, o.updated_at
 		FROM orders o
 		WHERE o.user_id = $1
 		ORDER BY o.created_at DESC
@@ -136,12 +210,18 @@ func (r *postgresOrderRepository) GetOrdersByUser(ctx context.Context, userID uu
 	var orders []*models.Order
 	for rows.Next() {
 		var order models.Order
-		if err := rows.Scan(&order.ID, &order.UserID, &order.Status, &order.TotalPrice,
-			&order.ShippingAddress, &order.CreatedAt, &order.UpdatedAt,
+		if err := rows.Scan(&order.ID, &order.UserID, &order.Status, &order.Subtotal, &order.DiscountTotal, &order.TaxTotal, &order.ShippingTotal,
+			&order.TotalPrice, &order.TotalPrice.Currency, &order.CouponCode, &order.FreeShipping, &order.ShippingAddress, &order.ShippingMethodID,
+			&order.ShippingMethod, &order.Carrier, &order.TrackingNumber, &order.ShippedAt, &order.CreatedAt, &order.UpdatedAt,
 		); err != nil {
 			return nil, r.mapDatabaseError(err, &log)
 		}
 
+		order.Subtotal.Currency = order.TotalPrice.Currency
+		order.DiscountTotal.Currency = order.TotalPrice.Currency
+		order.TaxTotal.Currency = order.TotalPrice.Currency
+		order.ShippingTotal.Currency = order.TotalPrice.Currency
+
 		orders = append(orders, &order)
 	}
 
@@ -164,22 +244,122 @@ func (r *postgresOrderRepository) CountUserOrders(ctx context.Context, userID uu
 	return count, nil
 }
 
-func (r *postgresOrderRepository) UpdateOrderStatus(ctx context.Context, orderID int, newStatus models.OrderStatus) error {
+// UpdateOrderStatus changes the order status, records the change in the status history and
+// writes the lifecycle events for it. Setting the status an order already has is a no-op and
+// emits nothing; any other change must be allowed by the order status transition table.
+// changedBy is nil when the change is made by the system. shipment, if any, is recorded on
+// orders moving to shipped. Changes that move money record the payment settlement they call
+// for, which is returned; it is nil for other changes.
+func (r *postgresOrderRepository) UpdateOrderStatus(ctx context.Context, orderID int, newStatus models.OrderStatus, changedBy *uuid.UUID, shipment *models.Shipment) (*models.PaymentSettlement, error) {
 	log := r.log.With().Str("method", "UpdateOrderStatus").Logger()
 
-	query := `UPDATE orders SET status = $1 WHERE id = $2`
-	result, err := r.db.ExecContext(ctx, query, string(newStatus), orderID)
+	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
-		return err
+		return nil, r.mapDatabaseError(err, &log)
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := result.RowsAffected()
+	o, err := r.getOrderByID(ctx, tx, orderID, true)
 	if err != nil {
-		return r.mapDatabaseError(err, &log)
+		return nil, r.mapDatabaseError(err, &log)
 	}
 
-	if rowsAffected == 0 {
-		return order.ErrNotFound
+	if o.Status == newStatus {
+		return nil, nil
+	}
+
+	// checked under the row lock so concurrent updates cannot skip a step
+	if !o.Status.CanTransitionTo(newStatus) {
+		return nil, &order.StatusTransitionError{From: string(o.Status), To: string(newStatus)}
+	}
+
+	fromStatus := o.Status
+
+	query := `UPDATE orders SET status = $1, updated_at = now() WHERE id = $2 RETURNING updated_at`
+	err = tx.QueryRowContext(ctx, query, string(newStatus), orderID).Scan(&o.UpdatedAt)
+	if err != nil {
+		return nil, r.mapDatabaseError(err, &log)
+	}
+
+	if newStatus == models.OrderStatusShipped {
+		if shipment == nil {
+			shipment = &models.Shipment{}
+		}
+
+		query = `UPDATE orders SET carrier = $1, tracking_number = $2, shipped_at = now() WHERE id = $3 RETURNING shipped_at`
+		err = tx.QueryRowContext(ctx, query, shipment.Carrier, shipment.TrackingNumber, orderID).Scan(&o.ShippedAt)
+		if err != nil {
+			return nil, r.mapDatabaseError(err, &log)
+		}
+
+		o.Carrier = shipment.Carrier
+		o.TrackingNumber = shipment.TrackingNumber
+	}
+
+	o.Status = newStatus
+
+	if err = r.recordStatusChange(ctx, tx, orderID, &fromStatus, newStatus, changedBy); err != nil {
+		return nil, r.mapDatabaseError(err, &log)
+	}
+
+	if err = r.writeStatusEvents(ctx, tx, o); err != nil {
+		return nil, r.mapDatabaseError(err, &log)
+	}
+
+	var settlement *models.PaymentSettlement
+	if newStatus.SettlesPayment() {
+		if settlement, err = r.createPaymentSettlement(ctx, tx, orderID, newStatus); err != nil {
+			return nil, r.mapDatabaseError(err, &log)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, r.mapDatabaseError(err, &log)
+	}
+
+	return settlement, nil
+}
+
+func (r *postgresOrderRepository) GetOrderStatusHistory(ctx context.Context, orderID int) ([]*models.OrderStatusChange, error) {
+	log := r.log.With().Str("method", "GetOrderStatusHistory").Logger()
+
+	query := `
+		SELECT id, order_id, from_status, to_status, changed_by, created_at
+		FROM order_status_history
+		WHERE order_id = $1
+		ORDER BY created_at, id
+	`
+
+	history := []*models.OrderStatusChange{}
+	err := r.db.SelectContext(ctx, &history, query, orderID)
+	if err != nil {
+		return nil, r.mapDatabaseError(err, &log)
+	}
+
+	return history, nil
+}
+
+func (r *postgresOrderRepository) recordStatusChange(ctx context.Context, tx sqlx.ExecerContext, orderID int, from *models.OrderStatus, to models.OrderStatus, changedBy *uuid.UUID) error {
+	query := `
+		INSERT INTO order_status_history (order_id, from_status, to_status, changed_by)
+		VALUES ($1, $2, $3, $4)
+	`
+	_, err := tx.ExecContext(ctx, query, orderID, from, string(to), changedBy)
+
+	return err
+}
+
+func (r *postgresOrderRepository) writeStatusEvents(ctx context.Context, tx sqlx.ExecerContext, o *models.Order) error {
+	err := events.WriteOutbox(ctx, tx, events.Order, events.OrderStatusChanged, o)
+	if err != nil {
+		return err
+	}
+
+	switch o.Status {
+	case models.OrderStatusCancelled:
+		return events.WriteOutbox(ctx, tx, events.Order, events.OrderCancelled, o)
+	case models.OrderStatusRefunded:
+		return events.WriteOutbox(ctx, tx, events.Order, events.OrderRefunded, o)
 	}
 
 	return nil
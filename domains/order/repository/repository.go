@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/rovilay/ecommerce-service/domains/order/models"
@@ -12,5 +13,16 @@ type OrderRepository interface {
 	GetOrderByID(ctx context.Context, orderID int) (*models.Order, error)
 	GetOrdersByUser(ctx context.Context, userID uuid.UUID, limit int, offset int) ([]*models.Order, error)
 	CountUserOrders(ctx context.Context, userID uuid.UUID) (int, error)
-	UpdateOrderStatus(ctx context.Context, orderID int, newStatus models.OrderStatus) error
+	UpdateOrderStatus(ctx context.Context, orderID int, newStatus models.OrderStatus, changedBy *uuid.UUID, shipment *models.Shipment) (*models.PaymentSettlement, error)
+	GetOrderStatusHistory(ctx context.Context, orderID int) ([]*models.OrderStatusChange, error)
+	// CompletePaymentSettlement records the outcome of carrying out a settlement: settled when
+	// settleErr is nil, else one more failed attempt.
+	CompletePaymentSettlement(ctx context.Context, id int, settleErr error) error
+	ClaimStalePaymentSettlements(ctx context.Context, staleAfter time.Duration, maxAttempts, limit int) ([]*models.PaymentSettlement, error)
+}
+
+type SagaRepository interface {
+	CreateSaga(ctx context.Context, saga *models.OrderSaga) (*models.OrderSaga, error)
+	UpdateSaga(ctx context.Context, saga *models.OrderSaga) error
+	ClaimStaleSagas(ctx context.Context, staleAfter time.Duration, limit int) ([]*models.OrderSaga, error)
 }
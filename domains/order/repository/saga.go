@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/rovilay/ecommerce-service/domains/order/models"
+)
+
+func (r *postgresOrderRepository) CreateSaga(ctx context.Context, saga *models.OrderSaga) (*models.OrderSaga, error) {
+	log := r.log.With().Str("method", "CreateSaga").Logger()
+
+	query := `
+		INSERT INTO order_sagas (id, user_id, status, payload)
+		VALUES ($1, $2, $3, $4)
+		RETURNING created_at, updated_at
+	`
+	err := r.db.QueryRowContext(ctx, query, saga.ID, saga.UserID, string(saga.Status), saga.Payload).
+		Scan(&saga.CreatedAt, &saga.UpdatedAt)
+	if err != nil {
+		return nil, r.mapDatabaseError(err, &log)
+	}
+
+	return saga, nil
+}
+
+func (r *postgresOrderRepository) UpdateSaga(ctx context.Context, saga *models.OrderSaga) error {
+	log := r.log.With().Str("method", "UpdateSaga").Logger()
+
+	query := `
+		UPDATE order_sagas
+		SET order_id = $1, status = $2, payload = $3, error = $4, updated_at = now()
+		WHERE id = $5
+		RETURNING updated_at
+	`
+	err := r.db.QueryRowContext(ctx, query, saga.OrderID, string(saga.Status), saga.Payload, saga.Error, saga.ID).
+		Scan(&saga.UpdatedAt)
+	if err != nil {
+		return r.mapDatabaseError(err, &log)
+	}
+
+	return nil
+}
+
+// ClaimStaleSagas claims up to limit unfinished sagas that have not moved for staleAfter.
+// Claiming touches updated_at, as every step of a running saga does, so a claimed saga is left
+// alone by other instances until it stalls again; concurrent claims skip each other's rows.
+func (r *postgresOrderRepository) ClaimStaleSagas(ctx context.Context, staleAfter time.Duration, limit int) ([]*models.OrderSaga, error) {
+	log := r.log.With().Str("method", "ClaimStaleSagas").Logger()
+
+	query := `
+		UPDATE order_sagas SET updated_at = now()
+		WHERE id IN (
+			SELECT id FROM order_sagas
+			WHERE status NOT IN ($1, $2) AND updated_at < now() - make_interval(secs => $3)
+			ORDER BY created_at
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, user_id, order_id, status, payload, coalesce(error, ''), created_at, updated_at
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, string(models.SagaStatusCompleted), string(models.SagaStatusCompensated),
+		staleAfter.Seconds(), limit)
+	if err != nil {
+		return nil, r.mapDatabaseError(err, &log)
+	}
+	defer rows.Close()
+
+	var sagas []*models.OrderSaga
+	for rows.Next() {
+		var saga models.OrderSaga
+		var orderID sql.NullInt64
+		if err := rows.Scan(&saga.ID, &saga.UserID, &orderID, &saga.Status, &saga.Payload,
+			&saga.Error, &saga.CreatedAt, &saga.UpdatedAt,
+		); err != nil {
+			return nil, r.mapDatabaseError(err, &log)
+		}
+
+		if orderID.Valid {
+			id := int(orderID.Int64)
+			saga.OrderID = &id
+		}
+
+		sagas = append(sagas, &saga)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, r.mapDatabaseError(err, &log)
+	}
+
+	return sagas, nil
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rovilay/ecommerce-service/domains/order/models"
+)
+
+const settlementColumns = `id, order_id, status, attempts, coalesce(last_error, '') AS last_error, created_at, updated_at`
+
+func (r *postgresOrderRepository) createPaymentSettlement(ctx context.Context, tx sqlx.QueryerContext, orderID int, status models.OrderStatus) (*models.PaymentSettlement, error) {
+	query := `INSERT INTO order_payment_settlements (order_id, status) VALUES ($1, $2) RETURNING ` + settlementColumns
+
+	var settlement models.PaymentSettlement
+	if err := sqlx.GetContext(ctx, tx, &settlement, query, orderID, string(status)); err != nil {
+		return nil, err
+	}
+
+	return &settlement, nil
+}
+
+func (r *postgresOrderRepository) CompletePaymentSettlement(ctx context.Context, id int, settleErr error) error {
+	log := r.log.With().Str("method", "CompletePaymentSettlement").Logger()
+
+	query := `UPDATE order_payment_settlements SET settled_at = now(), last_error = NULL, updated_at = now() WHERE id = $1`
+	args := []any{id}
+	if settleErr != nil {
+		query = `UPDATE order_payment_settlements
+			SET attempts = attempts + 1, last_error = $2, updated_at = now()
+			WHERE id = $1
+		`
+		args = append(args, settleErr.Error())
+	}
+
+	if _, err := r.db.ExecContext(ctx, query, args...); err != nil {
+		return r.mapDatabaseError(err, &log)
+	}
+
+	return nil
+}
+
+// ClaimStalePaymentSettlements claims up to limit unsettled settlements that have not been
+// tried for staleAfter and have failed fewer than maxAttempts times. Claiming touches
+// updated_at, so a claimed settlement, like one just recorded, is left alone by other
+// instances for another staleAfter; concurrent claims skip each other's rows.
+func (r *postgresOrderRepository) ClaimStalePaymentSettlements(ctx context.Context, staleAfter time.Duration, maxAttempts, limit int) ([]*models.PaymentSettlement, error) {
+	log := r.log.With().Str("method", "ClaimStalePaymentSettlements").Logger()
+
+	query := `
+		UPDATE order_payment_settlements SET updated_at = now()
+		WHERE id IN (
+			SELECT id FROM order_payment_settlements
+			WHERE settled_at IS NULL AND attempts < $1 AND updated_at < now() - make_interval(secs => $2)
+			ORDER BY created_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + settlementColumns
+
+	settlements := []*models.PaymentSettlement{}
+	if err := r.db.SelectContext(ctx, &settlements, query, maxAttempts, staleAfter.Seconds(), limit); err != nil {
+		return nil, r.mapDatabaseError(err, &log)
+	}
+
+	return settlements, nil
+}
@@ -0,0 +1,242 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rovilay/ecommerce-service/domains/order"
+	externalservices "github.com/rovilay/ecommerce-service/domains/order/external-services"
+	"github.com/rovilay/ecommerce-service/domains/order/models"
+	"github.com/rovilay/ecommerce-service/domains/payment"
+)
+
+// startOrderSaga persists a new saga for the order so that its progress survives a crash.
+func (s *OrderService) startOrderSaga(ctx context.Context, data *models.Order, fromCart bool) (*models.OrderSaga, error) {
+	saga := &models.OrderSaga{
+		ID:     uuid.New(),
+		UserID: data.UserID,
+		Status: models.SagaStatusStarted,
+		Payload: models.SagaPayload{
+			Order:        *data,
+			FromCart:     fromCart,
+			PaymentToken: data.PaymentToken,
+		},
+	}
+	// the token stays out of the order, and so out of its events
+	saga.Payload.Order.PaymentToken = ""
+
+	return s.sagaRepo.CreateSaga(ctx, saga)
+}
+
+// runOrderSaga drives the saga from its persisted status until it completes or is compensated.
+// Steps are: reserve stock -> persist order -> authorize payment -> clear cart. If any step
+// fails the completed steps are undone in reverse order.
+func (s *OrderService) runOrderSaga(ctx context.Context, saga *models.OrderSaga) (*models.Order, error) {
+	log := s.log.With().Str("method", "runOrderSaga").Str("saga", saga.ID.String()).Logger()
+
+	// a saga must be allowed to finish even if the caller goes away
+	ctx = context.WithoutCancel(ctx)
+
+	var stepErr error
+	for {
+		var err error
+
+		switch saga.Status {
+		case models.SagaStatusStarted:
+			err = s.sagaReserveStock(ctx, saga)
+		case models.SagaStatusStockReserved:
+			err = s.sagaPersistOrder(ctx, saga)
+		case models.SagaStatusOrderCreated:
+			err = s.sagaAuthorizePayment(ctx, saga)
+		case models.SagaStatusPaymentAuthorized:
+			err = s.sagaClearCart(ctx, saga)
+		case models.SagaStatusCompensating:
+			if err = s.sagaCompensate(ctx, saga); err != nil {
+				log.Err(err).Msg("compensation failed, saga will be retried")
+				return nil, err
+			}
+		case models.SagaStatusCompleted:
+			return &saga.Payload.Order, nil
+		case models.SagaStatusCompensated:
+			if stepErr == nil {
+				stepErr = fmt.Errorf("%w: %s", order.ErrOrderPlacementFailed, saga.Error)
+			}
+			return nil, stepErr
+		default:
+			return nil, fmt.Errorf("unknown saga status: %s", saga.Status)
+		}
+
+		if err != nil && saga.Status != models.SagaStatusCompensating {
+			log.Err(err).Str("status", string(saga.Status)).Msg("saga step failed, compensating")
+
+			stepErr = err
+			saga.Error = err.Error()
+			saga.Status = models.SagaStatusCompensating
+			if err := s.sagaRepo.UpdateSaga(ctx, saga); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+func (s *OrderService) sagaReserveStock(ctx context.Context, saga *models.OrderSaga) error {
+	adjustments := make([]externalservices.StockAdjustment, 0, len(saga.Payload.Order.OrderItems))
+	for _, item := range saga.Payload.Order.OrderItems {
+		adjustments = append(adjustments, externalservices.StockAdjustment{ProductID: item.ProductID, VariantID: item.VariantID, Delta: -item.Quantity})
+	}
+
+	// the batch is applied atomically, so either every item is taken or none is, and only
+	// once per saga however often the step is retried
+	if err := s.inventoryService.AdjustInventory(ctx, stockReference(saga), adjustments); err != nil {
+		return err
+	}
+
+	saga.Payload.ReservedItems = append([]models.OrderItem(nil), saga.Payload.Order.OrderItems...)
+	saga.Status = models.SagaStatusStockReserved
+	return s.sagaRepo.UpdateSaga(ctx, saga)
+}
+
+// stockReference is the inventory reference the stock of the saga's order is taken with.
+func stockReference(saga *models.OrderSaga) string {
+	return "order-saga-" + saga.ID.String()
+}
+
+func (s *OrderService) sagaPersistOrder(ctx context.Context, saga *models.OrderSaga) error {
+	o, err := s.repo.CreateOrder(ctx, &saga.Payload.Order)
+	if err != nil {
+		return err
+	}
+
+	saga.Payload.Order = *o
+	saga.OrderID = &o.ID
+	saga.Status = models.SagaStatusOrderCreated
+	return s.sagaRepo.UpdateSaga(ctx, saga)
+}
+
+// sagaAuthorizePayment authorizes the payment of the order and moves an authorized order to
+// processing. A declined payment fails the saga; a payment the gateway confirms later leaves
+// the order pending until its webhook arrives.
+func (s *OrderService) sagaAuthorizePayment(ctx context.Context, saga *models.OrderSaga) error {
+	o := &saga.Payload.Order
+
+	p, err := s.payments.Authorize(ctx, o.ID, o.TotalPrice, saga.Payload.PaymentToken)
+	if err != nil {
+		return err
+	}
+
+	switch p.Status {
+	case payment.StatusFailed:
+		return fmt.Errorf("%w: %s", payment.ErrDeclined, p.FailureReason)
+	case payment.StatusAuthorized:
+		if err = s.moveOrder(ctx, o.ID, models.OrderStatusProcessing, nil, nil); err != nil {
+			return err
+		}
+		o.Status = models.OrderStatusProcessing
+	}
+
+	saga.Payload.PaymentToken = ""
+	saga.Status = models.SagaStatusPaymentAuthorized
+	return s.sagaRepo.UpdateSaga(ctx, saga)
+}
+
+func (s *OrderService) sagaClearCart(ctx context.Context, saga *models.OrderSaga) error {
+	if saga.Payload.FromCart {
+		if err := s.cartService.ClearCart(ctx, saga.UserID); err != nil {
+			return err
+		}
+	}
+
+	saga.Status = models.SagaStatusCompleted
+	return s.sagaRepo.UpdateSaga(ctx, saga)
+}
+
+func (s *OrderService) sagaCompensate(ctx context.Context, saga *models.OrderSaga) error {
+	if saga.OrderID != nil {
+		// cancelling the order voids its payment; a declined one has nothing to release
+		err := s.moveOrder(ctx, *saga.OrderID, models.OrderStatusCancelled, nil, nil)
+		if err == nil {
+			// inventory restocks cancelled orders when it receives order.cancelled
+			saga.Payload.Order.Status = models.OrderStatusCancelled
+			saga.Payload.ReservedItems = nil
+		} else if !errors.Is(err, order.ErrNotFound) {
+			return err
+		} else if _, err = s.payments.Void(ctx, *saga.OrderID); err != nil {
+			// the order is gone, so nothing else will release its payment
+			return err
+		}
+	}
+
+	// a saga stopped before it recorded the stock it took may have taken it anyway; reversing
+	// by reference returns whatever was taken, or keeps a late request from taking it
+	if saga.OrderID == nil || len(saga.Payload.ReservedItems) > 0 {
+		if err := s.inventoryService.ReverseAdjustment(ctx, stockReference(saga)); err != nil {
+			return err
+		}
+
+		saga.Payload.ReservedItems = nil
+	}
+
+	saga.Status = models.SagaStatusCompensated
+	return s.sagaRepo.UpdateSaga(ctx, saga)
+}
+
+// sagaClaimBatchSize is the number of stale sagas claimed at a time.
+const sagaClaimBatchSize = 100
+
+// ResumeOrderSagas picks up, every interval until ctx is done, sagas that have not moved for
+// staleAfter, e.g. because the instance running them stopped. Sagas that never persisted their
+// order are compensated, since the client has already been told the request failed; sagas
+// whose order exists are driven to completion.
+func (s *OrderService) ResumeOrderSagas(ctx context.Context, interval, staleAfter time.Duration) {
+	log := s.log.With().Str("method", "ResumeOrderSagas").Logger()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.resumeStaleSagas(ctx, staleAfter); err != nil {
+			log.Err(err).Msg("failed to resume order sagas")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *OrderService) resumeStaleSagas(ctx context.Context, staleAfter time.Duration) error {
+	log := s.log.With().Str("method", "resumeStaleSagas").Logger()
+
+	for {
+		sagas, err := s.sagaRepo.ClaimStaleSagas(ctx, staleAfter, sagaClaimBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, saga := range sagas {
+			log.Info().Str("saga", saga.ID.String()).Str("status", string(saga.Status)).Msg("resuming order saga")
+
+			if saga.Status == models.SagaStatusStarted || saga.Status == models.SagaStatusStockReserved {
+				saga.Error = "interrupted before the order was persisted"
+				saga.Status = models.SagaStatusCompensating
+				if err := s.sagaRepo.UpdateSaga(ctx, saga); err != nil {
+					log.Err(err).Str("saga", saga.ID.String()).Msg("failed to update saga")
+					continue
+				}
+			}
+
+			if _, err := s.runOrderSaga(ctx, saga); err != nil {
+				log.Err(err).Str("saga", saga.ID.String()).Msg("resumed saga did not complete")
+			}
+		}
+
+		if len(sagas) < sagaClaimBatchSize {
+			return nil
+		}
+	}
+}
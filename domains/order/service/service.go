@@ -2,39 +2,57 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"sync"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/rovilay/ecommerce-service/common/money"
+	"github.com/rovilay/ecommerce-service/common/utils"
 	"github.com/rovilay/ecommerce-service/domains/auth"
 	"github.com/rovilay/ecommerce-service/domains/order"
 	externalservices "github.com/rovilay/ecommerce-service/domains/order/external-services"
 	"github.com/rovilay/ecommerce-service/domains/order/models"
 	"github.com/rovilay/ecommerce-service/domains/order/repository"
+	"github.com/rovilay/ecommerce-service/domains/payment"
+	"github.com/rovilay/ecommerce-service/domains/promotion"
+	"github.com/rovilay/ecommerce-service/domains/shipping"
+	"github.com/rovilay/ecommerce-service/domains/tax"
 	"github.com/rs/zerolog"
 )
 
 type OrderService struct {
 	repo             repository.OrderRepository
+	sagaRepo         repository.SagaRepository
 	authService      auth.AuthService
 	inventoryService externalservices.InventoryService
 	prdService       externalservices.ProductService
 	cartService      externalservices.CartService
+	promotions       *promotion.Service
+	taxes            tax.TaxCalculator
+	shipping         *shipping.Service
+	payments         *payment.Service
 	log              *zerolog.Logger
 }
 
-func NewOrderService(repo repository.OrderRepository, a auth.AuthService, i externalservices.InventoryService,
-	p externalservices.ProductService, c externalservices.CartService, l *zerolog.Logger,
+func NewOrderService(repo repository.OrderRepository, sr repository.SagaRepository, a auth.AuthService, i externalservices.InventoryService,
+	p externalservices.ProductService, c externalservices.CartService, promotions *promotion.Service, taxes tax.TaxCalculator, sh *shipping.Service,
+	payments *payment.Service, l *zerolog.Logger,
 ) *OrderService {
 	logger := l.With().Str("service", "OrderService").Logger()
 
 	return &OrderService{
 		repo:             repo,
+		sagaRepo:         sr,
 		authService:      a,
 		inventoryService: i,
 		prdService:       p,
 		cartService:      c,
+		promotions:       promotions,
+		taxes:            taxes,
+		shipping:         sh,
+		payments:         payments,
 		log:              &logger,
 	}
 }
@@ -42,20 +60,19 @@ func NewOrderService(repo repository.OrderRepository, a auth.AuthService, i exte
 func (s *OrderService) CreateOrder(ctx context.Context, authToken string, data *models.Order, fromCart bool) (*models.Order, error) {
 	log := s.log.With().Str("method", "GetCart").Logger()
 
-	userID, err := s.authService.ValidateJWT(ctx, authToken)
+	_, userID, err := s.authenticate(ctx, authToken, &log)
 	if err != nil {
-		log.Err(err).Msg("error validating token")
-		return nil, order.ErrInvalidJWToken
+		return nil, err
 	}
 
-	data.UserID, err = uuid.Parse(userID)
-	if err != nil {
-		log.Err(err).Msg("error parsing userID")
-		return nil, order.ErrInvalidJWToken
+	data.UserID = userID
+
+	if data.PaymentToken == "" {
+		return nil, payment.ErrTokenRequired
 	}
 
 	if fromCart {
-		orderItemsFromCart, err := s.getOrderItemsFromCart(ctx, authToken)
+		orderItemsFromCart, err := s.getOrderItemsFromCart(ctx, userID)
 		if err != nil {
 			log.Err(err).Msg("failed to get order items from cart")
 			return nil, err
@@ -74,69 +91,130 @@ func (s *OrderService) CreateOrder(ctx context.Context, authToken string, data *
 	defer cancel()
 
 	log.Debug().Msgf("🥰🥰Before%+v", data.OrderItems)
-	validOrderItems, totalPrice, err := s.validateOrderItems(timeoutCtx, data.OrderItems)
+	validOrderItems, products, err := s.validateOrderItems(timeoutCtx, data.OrderItems)
 	if err != nil {
 		log.Err(err).Msg("error validating order items")
 		return nil, err
 	}
 
+	subtotal, err := s.calculateTotalPrice(validOrderItems)
+	if err != nil {
+		return nil, err
+	}
+
 	data.OrderItems = validOrderItems
-	data.TotalPrice = totalPrice
+	data.Subtotal = subtotal
 	data.Status = models.OrderStatusPending
 
+	if err = s.applyPromotions(timeoutCtx, userID, data, products); err != nil {
+		log.Err(err).Msg("error applying promotions")
+		return nil, err
+	}
+
+	if err = s.applyTaxes(timeoutCtx, data, products); err != nil {
+		log.Err(err).Msg("error calculating taxes")
+		return nil, err
+	}
+
+	if err = s.applyShipping(timeoutCtx, data, products); err != nil {
+		log.Err(err).Msg("error charging shipping")
+		return nil, err
+	}
+
 	log.Debug().Msgf("🥰🥰%+v", data.OrderItems)
 
-	order, err := s.repo.CreateOrder(ctx, data)
+	saga, err := s.startOrderSaga(ctx, data, fromCart)
 	if err != nil {
+		log.Err(err).Msg("failed to start order saga")
 		return nil, err
 	}
 
-	_, err = s.updateInventory(ctx, order)
+	return s.runOrderSaga(ctx, saga)
+}
+
+// QuoteOrder prices the items of data, or of the caller's cart when fromCart is set, and
+// quotes every shipping method that can ship them to data.ShippingAddress. Stock is not
+// checked and nothing is reserved.
+func (s *OrderService) QuoteOrder(ctx context.Context, authToken string, data *models.Order, fromCart bool) (*models.OrderQuote, error) {
+	log := s.log.With().Str("method", "QuoteOrder").Logger()
+
+	_, userID, err := s.authenticate(ctx, authToken, &log)
 	if err != nil {
-		log.Err(err).Msg("inventory update failed")
+		return nil, err
 	}
 
 	if fromCart {
-		err = s.cartService.ClearCart(ctx, authToken)
-		if err != nil {
-			log.Err(err).Msg("cart clearance failed")
+		if data.OrderItems, err = s.getOrderItemsFromCart(ctx, userID); err != nil {
+			log.Err(err).Msg("failed to get order items from cart")
+			return nil, err
 		}
 	}
 
-	return order, nil
+	items, products, err := s.priceOrderItems(ctx, data.OrderItems)
+	if err != nil {
+		return nil, err
+	}
+
+	data.OrderItems = items
+	if data.Subtotal, err = s.calculateTotalPrice(items); err != nil {
+		return nil, err
+	}
+
+	if err = s.applyPromotions(ctx, userID, data, products); err != nil {
+		return nil, err
+	}
+
+	parcel, err := newParcel(data, products)
+	if err != nil {
+		return nil, err
+	}
+
+	quotes, err := s.shipping.Quote(ctx, parcel)
+	if err != nil {
+		return nil, err
+	}
+
+	if data.FreeShipping {
+		for i := range quotes {
+			quotes[i].Price = money.New(0, quotes[i].Price.Currency)
+		}
+	}
+
+	return &models.OrderQuote{
+		Subtotal:        data.Subtotal,
+		DiscountTotal:   data.DiscountTotal,
+		FreeShipping:    data.FreeShipping,
+		WeightGrams:     parcel.WeightGrams,
+		ShippingMethods: quotes,
+	}, nil
 }
 
 func (s *OrderService) GetOrder(ctx context.Context, authToken string, orderID int) (*models.Order, error) {
 	log := s.log.With().Str("method", "GetOrder").Logger()
 
-	userID, err := s.authService.ValidateJWT(ctx, authToken)
+	claims, userID, err := s.authenticate(ctx, authToken, &log)
 	if err != nil {
-		log.Err(err).Msg("error validating token")
-		return nil, order.ErrInvalidJWToken
+		return nil, err
 	}
 
-	_, err = uuid.Parse(userID)
+	o, err := s.repo.GetOrderByID(ctx, orderID)
 	if err != nil {
-		log.Err(err).Msg("error parsing userID")
-		return nil, order.ErrInvalidJWToken
+		return nil, err
 	}
 
-	return s.repo.GetOrderByID(ctx, orderID)
+	if err = authorizeOrderAccess(claims, userID, o); err != nil {
+		return nil, err
+	}
+
+	return o, nil
 }
 
 func (s *OrderService) GetUserOrders(ctx context.Context, authToken string, limit int, offset int) (*models.PaginationResult[*models.Order], error) {
 	log := s.log.With().Str("method", "GetUserOrders").Logger()
 
-	userID, err := s.authService.ValidateJWT(ctx, authToken)
-	if err != nil {
-		log.Err(err).Msg("error validating token")
-		return nil, order.ErrInvalidJWToken
-	}
-
-	uUserID, err := uuid.Parse(userID)
+	_, uUserID, err := s.authenticate(ctx, authToken, &log)
 	if err != nil {
-		log.Err(err).Msg("error parsing userID")
-		return nil, order.ErrInvalidJWToken
+		return nil, err
 	}
 
 	totalOrders, err := s.repo.CountUserOrders(ctx, uUserID)
@@ -159,165 +237,514 @@ func (s *OrderService) GetUserOrders(ctx context.Context, authToken string, limi
 	return &res, nil
 }
 
-func (s *OrderService) UpdateOrderStatus(ctx context.Context, authToken string, orderID int, newStatus models.OrderStatus) error {
-	log := s.log.With().Str("method", "GetUserOrders").Logger()
+// UpdateOrderStatus is reserved for staff, customers cannot move their own orders. shipment
+// records the carrier and tracking number of orders moving to shipped. The payment of the
+// order is settled at the gateway once the status change has committed.
+func (s *OrderService) UpdateOrderStatus(ctx context.Context, authToken string, orderID int, newStatus models.OrderStatus, shipment *models.Shipment) error {
+	log := s.log.With().Str("method", "UpdateOrderStatus").Logger()
 
-	userID, err := s.authService.ValidateJWT(ctx, authToken)
+	claims, userID, err := s.authenticate(ctx, authToken, &log)
+	if err != nil {
+		return err
+	}
+
+	if !claims.HasRole(staffRoles...) {
+		return order.ErrForbidden
+	}
+
+	if !newStatus.IsValid() {
+		return order.ErrInvalidStatus
+	}
+
+	if shipment != nil && newStatus != models.OrderStatusShipped {
+		return order.ErrInvalidShipment
+	}
+
+	if newStatus == models.OrderStatusProcessing {
+		if err = s.checkPaymentAuthorized(ctx, orderID); err != nil {
+			return err
+		}
+	}
+
+	return s.moveOrder(ctx, orderID, newStatus, &userID, shipment)
+}
+
+// moveOrder changes the status of an order, then settles its payment when the change calls
+// for it. A settlement that fails is left to SettlePayments: the status change stands.
+func (s *OrderService) moveOrder(ctx context.Context, orderID int, newStatus models.OrderStatus, changedBy *uuid.UUID, shipment *models.Shipment) error {
+	settlement, err := s.repo.UpdateOrderStatus(ctx, orderID, newStatus, changedBy, shipment)
+	if err != nil || settlement == nil {
+		return err
+	}
+
+	if err = s.settlePayment(ctx, settlement); err != nil {
+		s.log.Err(err).Str("method", "moveOrder").Int("order", orderID).Str("status", string(newStatus)).
+			Msg("failed to settle payment, it will be retried")
+	}
+
+	return nil
+}
+
+// GetOrderPayment returns the payment of an order to its customer or to staff.
+func (s *OrderService) GetOrderPayment(ctx context.Context, authToken string, orderID int) (*payment.Payment, error) {
+	log := s.log.With().Str("method", "GetOrderPayment").Logger()
+
+	claims, userID, err := s.authenticate(ctx, authToken, &log)
+	if err != nil {
+		return nil, err
+	}
+
+	o, err := s.repo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = authorizeOrderAccess(claims, userID, o); err != nil {
+		return nil, err
+	}
+
+	return s.payments.GetOrderPayment(ctx, orderID)
+}
+
+// HandlePaymentWebhook applies a signed gateway webhook and moves the order of the payment
+// along: authorized payments are processed, failed and voided ones cancel their order, and
+// refunded ones refund it. Events that changed nothing leave the order alone.
+func (s *OrderService) HandlePaymentWebhook(ctx context.Context, body []byte, signature string) error {
+	log := s.log.With().Str("method", "HandlePaymentWebhook").Logger()
+
+	p, changed, err := s.payments.HandleWebhook(ctx, body, signature)
+	if err != nil || !changed {
+		return err
+	}
+
+	var next models.OrderStatus
+	switch p.Status {
+	case payment.StatusAuthorized:
+		next = models.OrderStatusProcessing
+	case payment.StatusFailed, payment.StatusVoided:
+		next = models.OrderStatusCancelled
+	case payment.StatusRefunded:
+		next = models.OrderStatusRefunded
+	default:
+		return nil
+	}
+
+	err = s.moveOrder(ctx, p.OrderID, next, nil, nil)
+	if errors.Is(err, order.ErrInvalidStatusTransition) {
+		// the order has moved on, e.g. it was cancelled while the payment was pending
+		log.Warn().Err(err).Int("order", p.OrderID).Str("payment", string(p.Status)).Msg("order not moved by payment")
+		return nil
+	}
+
+	return err
+}
+
+// checkPaymentAuthorized fails with ErrAwaitingPayment unless the payment of the order is
+// authorized. Orders placed before payments were taken have none and pass.
+func (s *OrderService) checkPaymentAuthorized(ctx context.Context, orderID int) error {
+	p, err := s.payments.GetOrderPayment(ctx, orderID)
+	if errors.Is(err, payment.ErrNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if p.Status != payment.StatusAuthorized {
+		return fmt.Errorf("%w: payment is %s", order.ErrAwaitingPayment, p.Status)
+	}
+
+	return nil
+}
+
+// settlePayment makes the payment of an order follow it to the status of settlement: shipping
+// captures it, cancelling voids it and refunding gives it back. Orders placed before payments
+// were taken have none to settle. The outcome is recorded on the settlement.
+func (s *OrderService) settlePayment(ctx context.Context, settlement *models.PaymentSettlement) error {
+	var err error
+
+	switch settlement.Status {
+	case models.OrderStatusShipped:
+		_, err = s.payments.Capture(ctx, settlement.OrderID)
+	case models.OrderStatusCancelled:
+		_, err = s.payments.Void(ctx, settlement.OrderID)
+	case models.OrderStatusRefunded:
+		_, err = s.payments.Refund(ctx, settlement.OrderID)
+	}
+
+	if cerr := s.repo.CompletePaymentSettlement(ctx, settlement.ID, err); cerr != nil {
+		return errors.Join(err, cerr)
+	}
+
+	return err
+}
+
+// settlementClaimBatchSize is the number of stale settlements claimed at a time.
+const settlementClaimBatchSize = 100
+
+// maxSettlementAttempts bounds how often a settlement is tried. Settlements that keep failing,
+// e.g. because the gateway declines them, are left for staff to look into.
+const maxSettlementAttempts = 10
+
+// SettlePayments retries, every interval until ctx is done, payment settlements that have
+// not been carried out for staleAfter, e.g. because the gateway was down or the instance that
+// recorded them stopped.
+func (s *OrderService) SettlePayments(ctx context.Context, interval, staleAfter time.Duration) {
+	log := s.log.With().Str("method", "SettlePayments").Logger()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.settleStalePayments(ctx, staleAfter); err != nil {
+				log.Err(err).Msg("failed to settle payments")
+			}
+		}
+	}
+}
+
+func (s *OrderService) settleStalePayments(ctx context.Context, staleAfter time.Duration) error {
+	log := s.log.With().Str("method", "settleStalePayments").Logger()
+
+	for {
+		settlements, err := s.repo.ClaimStalePaymentSettlements(ctx, staleAfter, maxSettlementAttempts, settlementClaimBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, settlement := range settlements {
+			if err := s.settlePayment(ctx, settlement); err != nil {
+				log.Err(err).Int("order", settlement.OrderID).Str("status", string(settlement.Status)).
+					Int("attempts", settlement.Attempts+1).Msg("failed to settle payment")
+			}
+		}
+
+		if len(settlements) < settlementClaimBatchSize {
+			return nil
+		}
+	}
+}
+
+func (s *OrderService) GetOrderStatusHistory(ctx context.Context, authToken string, orderID int) ([]*models.OrderStatusChange, error) {
+	log := s.log.With().Str("method", "GetOrderStatusHistory").Logger()
+
+	claims, userID, err := s.authenticate(ctx, authToken, &log)
+	if err != nil {
+		return nil, err
+	}
+
+	// load the order first so an unknown id is a 404 rather than an empty history
+	o, err := s.repo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = authorizeOrderAccess(claims, userID, o); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetOrderStatusHistory(ctx, orderID)
+}
+
+// staffRoles may read any order and change order statuses.
+var staffRoles = []string{utils.RoleAdmin, utils.RoleFulfillment}
+
+// authenticate validates authToken and returns its claims along with the caller's user id.
+func (s *OrderService) authenticate(ctx context.Context, authToken string, log *zerolog.Logger) (*utils.Claims, uuid.UUID, error) {
+	claims, err := s.authService.ValidateJWT(ctx, authToken)
 	if err != nil {
 		log.Err(err).Msg("error validating token")
-		return order.ErrInvalidJWToken
+		return nil, uuid.Nil, order.ErrInvalidJWToken
 	}
 
-	_, err = uuid.Parse(userID)
+	userID, err := uuid.Parse(claims.UserID)
 	if err != nil {
 		log.Err(err).Msg("error parsing userID")
-		return order.ErrInvalidJWToken
+		return nil, uuid.Nil, order.ErrInvalidJWToken
 	}
 
-	return s.repo.UpdateOrderStatus(ctx, orderID, newStatus)
+	return claims, userID, nil
 }
 
-type validationResult struct {
-	productID int
-	price     float32
-	available bool
+// authorizeOrderAccess lets customers see only their own orders; staff may see any order.
+func authorizeOrderAccess(claims *utils.Claims, userID uuid.UUID, o *models.Order) error {
+	if o.UserID == userID || claims.HasRole(staffRoles...) {
+		return nil
+	}
+
+	return order.ErrForbidden
 }
 
-func (s *OrderService) validateOrderItems(ctx context.Context, items []models.OrderItem) ([]models.OrderItem, float32, error) {
-	numItems := len(items)
-	results := make([]models.OrderItem, numItems)
+// validateOrderItems checks the stock of every item in one call and prices the items. It
+// also returns the products of the items, keyed by product ID.
+func (s *OrderService) validateOrderItems(ctx context.Context, items []models.OrderItem) ([]models.OrderItem, map[int]*externalservices.Product, error) {
+	stock := make([]externalservices.StockItem, 0, len(items))
+	for _, item := range items {
+		stock = append(stock, externalservices.StockItem{ProductID: item.ProductID, VariantID: item.VariantID, Quantity: item.Quantity})
+	}
+
+	availability, err := s.inventoryService.CheckAvailabilities(ctx, stock)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	var validationErrors []string
+	for _, a := range availability {
+		if !a.Sufficient {
+			validationErrors = append(validationErrors, fmt.Sprintf("product:%d variant:%d not available", a.ProductID, a.VariantID))
+		}
+	}
+
+	if len(validationErrors) > 0 {
+		return nil, nil, fmt.Errorf("%w: %v", order.ErrInsufficientStock, validationErrors)
+	}
 
-	var wg sync.WaitGroup
-	wg.Add(numItems)
+	return s.priceOrderItems(ctx, items)
+}
 
+// priceOrderItems prices the items at what their products or variants sell for, and returns
+// the products of the items keyed by product ID.
+func (s *OrderService) priceOrderItems(ctx context.Context, items []models.OrderItem) ([]models.OrderItem, map[int]*externalservices.Product, error) {
+	products, err := s.getProducts(ctx, items)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	results := make([]models.OrderItem, len(items))
 	for i, item := range items {
-		go func(index int, item *models.OrderItem) {
-			defer wg.Done()
-
-			res, err := s.validateOrderItem(ctx, item)
-			s.log.Debug().Msgf("🥰🥰❌%+v", res)
-			if err != nil {
-				validationErrors = append(validationErrors, err.Error())
-				return
+		prd := products[item.ProductID]
+
+		results[i] = item
+		results[i].ProductID = prd.ID
+		results[i].Price = prd.Price
+		results[i].ProductName = prd.Name
+		results[i].ProductSKU = prd.SKU
+
+		// products with variants are only sold as one of them, at the variant's price and SKU
+		if item.VariantID != 0 {
+			v := prd.Variant(item.VariantID)
+			if v == nil {
+				return nil, nil, fmt.Errorf("%w: %d variant %d", order.ErrInvalidProduct, item.ProductID, item.VariantID)
 			}
 
-			if res.available {
-				results[index] = *item
-				results[index].ProductID = res.productID
-				results[index].Price = res.price
-			} else {
-				validationErrors = append(validationErrors, fmt.Sprintf("product:%d not available", item.ID))
-			}
-		}(i, &item)
+			results[i].Price = v.Price
+			results[i].ProductSKU = v.SKU
+		} else if prd.HasVariants() {
+			return nil, nil, fmt.Errorf("%w: %d requires a variant", order.ErrInvalidProduct, item.ProductID)
+		}
 	}
 
-	wg.Wait()
+	return results, products, nil
+}
 
-	if len(validationErrors) > 0 {
-		return nil, 0, fmt.Errorf("validation errors: %v", validationErrors)
+// getProducts looks up the products of all items in one call, keyed by product ID.
+// Unknown and deleted products are rejected.
+func (s *OrderService) getProducts(ctx context.Context, items []models.OrderItem) (map[int]*externalservices.Product, error) {
+	ids := make([]int, 0, len(items))
+	for _, item := range items {
+		// variants of one product are looked up once
+		if !slices.Contains(ids, item.ProductID) {
+			ids = append(ids, item.ProductID)
+		}
+	}
+
+	prds, err := s.prdService.GetProducts(ctx, ids)
+	if err != nil {
+		return nil, err
 	}
 
-	totalPrice := s.calculateTotalPrice(results)
+	products := make(map[int]*externalservices.Product, len(prds))
+	for _, prd := range prds {
+		if !prd.Deleted {
+			products[prd.ID] = prd
+		}
+	}
+
+	for _, id := range ids {
+		if _, ok := products[id]; !ok {
+			return nil, fmt.Errorf("%w: %d", order.ErrInvalidProduct, id)
+		}
+	}
 
-	return results, totalPrice, nil
+	return products, nil
 }
 
-func (s *OrderService) validateOrderItem(ctx context.Context, orderItem *models.OrderItem) (*validationResult, error) {
-	vRes := &validationResult{}
+// calculateTotalPrice sums unit price times quantity over the items, which must all be
+// priced in the same currency.
+func (s *OrderService) calculateTotalPrice(items []models.OrderItem) (money.Money, error) {
+	if len(items) == 0 {
+		return money.New(0, money.DefaultCurrency), nil
+	}
 
-	numRoutines := 2
-	productChan := make(chan *externalservices.Product)
-	availabilityChan := make(chan bool)
-	errChan := make(chan error)
+	totalPrice := money.New(0, items[0].Price.Currency)
+	for _, item := range items {
+		var err error
+		totalPrice, err = totalPrice.Add(item.Price.Mul(item.Quantity))
+		if errors.Is(err, money.ErrCurrencyMismatch) {
+			return money.Money{}, fmt.Errorf("%w: %v", order.ErrMixedCurrencies, err)
+		} else if err != nil {
+			return money.Money{}, err
+		}
+	}
 
-	go func() {
-		prd, err := s.prdService.GetProduct(ctx, orderItem.ProductID)
-		if err != nil {
-			errChan <- err
-			return
+	return totalPrice, nil
+}
+
+// applyPromotions works out the discounts of the priced order and sets its discount and
+// total price. Every line of the order keeps its share of each discount.
+func (s *OrderService) applyPromotions(ctx context.Context, userID uuid.UUID, data *models.Order, products map[int]*externalservices.Product) error {
+	currency := data.Subtotal.Currency
+	data.Discounts = []models.OrderDiscount{}
+	data.DiscountTotal = money.New(0, currency)
+	data.FreeShipping = false
+	data.TotalPrice = data.Subtotal
+
+	lines := make([]promotion.Line, len(data.OrderItems))
+	for i, item := range data.OrderItems {
+		data.OrderItems[i].Discount = money.New(0, currency)
+		data.OrderItems[i].Discounts = nil
+		lines[i] = promotion.Line{
+			ProductID:   item.ProductID,
+			CategoryIDs: products[item.ProductID].CategoryIDs,
+			Quantity:    item.Quantity,
+			UnitPrice:   item.Price,
 		}
+	}
 
-		productChan <- prd
-	}()
+	applied, err := s.promotions.Evaluate(ctx, userID, data.CouponCode, lines)
+	if err != nil {
+		return err
+	}
 
-	go func() {
-		available, err := s.inventoryService.CheckAvailability(ctx, orderItem.ProductID, orderItem.Quantity)
-		s.log.Debug().Msgf("available:🥰❌%v", available)
-		if err != nil {
-			errChan <- err
-			return
+	for _, a := range applied {
+		p := a.Promotion
+		d := models.OrderDiscount{PromotionID: p.ID, Name: p.Name, Type: string(p.Type), Amount: a.Amount}
+		if p.Code != nil {
+			d.Code = *p.Code
 		}
+		data.Discounts = append(data.Discounts, d)
+		data.FreeShipping = data.FreeShipping || a.FreeShipping
+
+		for i, amount := range a.LineAmounts {
+			if amount.IsZero() {
+				continue
+			}
 
-		availabilityChan <- available
-	}()
+			item := &data.OrderItems[i]
+			item.Discounts = append(item.Discounts, models.OrderItemDiscount{PromotionID: p.ID, Amount: amount})
+			if item.Discount, err = item.Discount.Add(amount); err != nil {
+				return err
+			}
+		}
 
-	for i := 0; i < numRoutines; i++ {
-		select {
-		case err := <-errChan:
-			return nil, err
-		case prd := <-productChan:
-			vRes.productID = prd.ID
-			vRes.price = prd.Price
-		case available := <-availabilityChan:
-			vRes.available = available
-		case <-ctx.Done():
-			return nil, ctx.Err()
+		if data.DiscountTotal, err = data.DiscountTotal.Add(a.Amount); err != nil {
+			return err
 		}
 	}
 
-	return vRes, nil
+	data.CouponCode = promotion.NormalizeCode(data.CouponCode)
+	data.TotalPrice, err = data.Subtotal.Sub(data.DiscountTotal)
+	return err
 }
 
-func (s *OrderService) calculateTotalPrice(items []models.OrderItem) float32 {
-	totalPrice := float32(0.0)
+// applyTaxes taxes every line of the discounted order by its shipping address, and adds the
+// tax to the total price.
+func (s *OrderService) applyTaxes(ctx context.Context, data *models.Order, products map[int]*externalservices.Product) error {
+	lines := make([]tax.Line, len(data.OrderItems))
+	for i, item := range data.OrderItems {
+		amount, err := item.Price.Mul(item.Quantity).Sub(item.Discount)
+		if err != nil {
+			return err
+		}
+
+		lines[i] = tax.Line{ProductID: item.ProductID, CategoryIDs: products[item.ProductID].CategoryIDs, Amount: amount}
+	}
 
-	for _, item := range items {
-		totalPrice += item.Price
+	addr := tax.Address{
+		Country:    data.ShippingAddress.Country,
+		State:      data.ShippingAddress.State,
+		PostalCode: data.ShippingAddress.PostalCode,
 	}
 
-	return totalPrice
-}
+	taxes, err := s.taxes.Calculate(ctx, addr, lines)
+	if err != nil {
+		return err
+	}
 
-type updateError struct {
-	id       int
-	err      error
-	quantity int
+	data.TaxTotal = money.New(0, data.Subtotal.Currency)
+	for i, t := range taxes {
+		item := &data.OrderItems[i]
+		item.Tax = t.Amount
+		item.TaxRate = t.Rate
+		item.TaxRateID = t.RateID
+		item.TaxName = t.Name
+
+		if data.TaxTotal, err = data.TaxTotal.Add(t.Amount); err != nil {
+			return err
+		}
+	}
+
+	data.TotalPrice, err = data.TotalPrice.Add(data.TaxTotal)
+	return err
 }
 
-func (s *OrderService) updateInventory(ctx context.Context, order *models.Order) ([]updateError, error) {
-	numItems := len(order.OrderItems)
-	var errs []updateError
+// applyShipping charges the order for shipping with the method the customer picked, or the
+// cheapest method that can ship it when they did not pick one, and adds the charge to the
+// total price. Orders with a free shipping promotion ship for free, and orders no method can
+// ship are not charged.
+func (s *OrderService) applyShipping(ctx context.Context, data *models.Order, products map[int]*externalservices.Product) error {
+	parcel, err := newParcel(data, products)
+	if err != nil {
+		return err
+	}
+
+	q, err := s.shipping.QuoteMethod(ctx, data.ShippingMethodID, parcel)
+	if err != nil {
+		return err
+	}
 
-	var wg sync.WaitGroup
-	wg.Add(numItems)
-	for _, item := range order.OrderItems {
-		go func(orderItem *models.OrderItem) {
-			defer wg.Done()
+	data.ShippingTotal = money.New(0, data.Subtotal.Currency)
+	if q == nil {
+		return nil
+	}
 
-			err := s.inventoryService.UpdateInventory(ctx, true, orderItem.ProductID, orderItem.Quantity)
-			errs = append(errs, updateError{id: orderItem.ProductID, err: err, quantity: orderItem.Quantity})
-		}(&item)
+	data.ShippingMethodID = &q.MethodID
+	data.ShippingMethod = q.Name
+	if !data.FreeShipping {
+		data.ShippingTotal = q.Price
 	}
 
-	wg.Wait()
+	data.TotalPrice, err = data.TotalPrice.Add(data.ShippingTotal)
+	return err
+}
+
+// newParcel is what shipping the discounted order to its address takes.
+func newParcel(data *models.Order, products map[int]*externalservices.Product) (shipping.Parcel, error) {
+	subtotal, err := data.Subtotal.Sub(data.DiscountTotal)
+	if err != nil {
+		return shipping.Parcel{}, err
+	}
 
-	if len(errs) == 0 {
-		return nil, nil
+	p := shipping.Parcel{Country: data.ShippingAddress.Country, Subtotal: subtotal}
+	for _, item := range data.OrderItems {
+		p.WeightGrams += products[item.ProductID].WeightGrams * item.Quantity
 	}
 
-	return errs, fmt.Errorf("validation errors: %v", errs)
+	return p, nil
 }
 
-func (s *OrderService) getOrderItemsFromCart(ctx context.Context, authToken string) ([]models.OrderItem, error) {
-	cart, err := s.cartService.GetCart(ctx, authToken)
+func (s *OrderService) getOrderItemsFromCart(ctx context.Context, userID uuid.UUID) ([]models.OrderItem, error) {
+	cart, err := s.cartService.GetCart(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
 
 	var items []models.OrderItem
 	for _, cartItem := range cart.CartItems {
-		items = append(items, models.OrderItem{ProductID: cartItem.ProductID, Quantity: cartItem.Quantity})
+		items = append(items, models.OrderItem{ProductID: cartItem.ProductID, VariantID: cartItem.VariantID, Quantity: cartItem.Quantity})
 	}
 
 	return items, nil
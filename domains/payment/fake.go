@@ -0,0 +1,160 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/rovilay/ecommerce-service/common/money"
+)
+
+// Tokens that make the fake gateway decline a payment or leave it pending. Any other
+// non-empty token is authorized.
+const (
+	FakeTokenDecline = "tok_decline"
+	FakeTokenPending = "tok_pending"
+)
+
+// FakeGateway is an in-process PaymentGateway for local runs and tests. It keeps its payments
+// in memory; pending payments stay pending until Confirm settles them.
+type FakeGateway struct {
+	mu       sync.Mutex
+	payments map[string]*fakePayment
+	// byKey maps idempotency keys to the reference they were answered with
+	byKey map[string]string
+}
+
+type fakePayment struct {
+	status   Status
+	amount   money.Money
+	captured money.Money
+}
+
+func NewFakeGateway() *FakeGateway {
+	return &FakeGateway{payments: map[string]*fakePayment{}, byKey: map[string]string{}}
+}
+
+func (g *FakeGateway) Name() string {
+	return "fake"
+}
+
+func (g *FakeGateway) Authorize(ctx context.Context, req AuthorizeRequest) (*GatewayResult, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if ref, ok := g.byKey[req.IdempotencyKey]; ok && req.IdempotencyKey != "" {
+		return g.result(ref), nil
+	}
+
+	ref := "fake_" + uuid.NewString()
+	p := &fakePayment{status: StatusAuthorized, amount: req.Amount}
+	switch req.Token {
+	case "", FakeTokenDecline:
+		p.status = StatusFailed
+	case FakeTokenPending:
+		p.status = StatusPending
+	}
+
+	g.payments[ref] = p
+	if req.IdempotencyKey != "" {
+		g.byKey[req.IdempotencyKey] = ref
+	}
+
+	return g.result(ref), nil
+}
+
+func (g *FakeGateway) Capture(ctx context.Context, reference string, amount money.Money) (*GatewayResult, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	p, err := g.payment(reference, StatusAuthorized)
+	if err != nil {
+		return nil, err
+	}
+
+	if amount.Currency != p.amount.Currency || amount.Amount > p.amount.Amount {
+		return nil, fmt.Errorf("%w: cannot capture %s of %s", ErrDeclined, amount, p.amount)
+	}
+
+	p.status = StatusCaptured
+	p.captured = amount
+	return g.result(reference), nil
+}
+
+func (g *FakeGateway) Void(ctx context.Context, reference string) (*GatewayResult, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	p, err := g.payment(reference, StatusAuthorized, StatusPending)
+	if err != nil {
+		return nil, err
+	}
+
+	p.status = StatusVoided
+	return g.result(reference), nil
+}
+
+func (g *FakeGateway) Refund(ctx context.Context, reference string, amount money.Money) (*GatewayResult, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	p, err := g.payment(reference, StatusCaptured)
+	if err != nil {
+		return nil, err
+	}
+
+	if amount.Currency != p.captured.Currency || amount.Amount > p.captured.Amount {
+		return nil, fmt.Errorf("%w: cannot refund %s of %s", ErrDeclined, amount, p.captured)
+	}
+
+	p.status = StatusRefunded
+	return g.result(reference), nil
+}
+
+// Confirm settles the pending payment reference, authorizing it or failing it, and returns
+// the webhook event a real gateway would send about it.
+func (g *FakeGateway) Confirm(reference string, authorized bool) (*Event, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	p, err := g.payment(reference, StatusPending)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Event{ID: "evt_" + uuid.NewString(), Type: EventAuthorized, Reference: reference}
+	p.status = StatusAuthorized
+	if !authorized {
+		p.status = StatusFailed
+		e.Type = EventFailed
+		e.FailureReason = "declined by the fake gateway"
+	}
+
+	return e, nil
+}
+
+// payment returns the payment reference, which must be in one of statuses.
+func (g *FakeGateway) payment(reference string, statuses ...Status) (*fakePayment, error) {
+	p, ok := g.payments[reference]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown reference %s", ErrNotFound, reference)
+	}
+
+	for _, s := range statuses {
+		if p.status == s {
+			return p, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: payment is %s", ErrInvalidState, p.status)
+}
+
+func (g *FakeGateway) result(reference string) *GatewayResult {
+	res := &GatewayResult{Reference: reference, Status: g.payments[reference].status}
+	if res.Status == StatusFailed {
+		res.FailureReason = "declined by the fake gateway"
+	}
+
+	return res
+}
@@ -0,0 +1,143 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/rovilay/ecommerce-service/common/money"
+)
+
+var ErrNotFound = errors.New("payment not found")
+var ErrTokenRequired = errors.New("payment_token is required")
+var ErrDeclined = errors.New("payment declined")
+var ErrInvalidState = errors.New("payment is not in a state that allows this operation")
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+var ErrInvalidEvent = errors.New("invalid webhook event")
+
+type Status string
+
+const (
+	// StatusPending payments await an asynchronous authorization from the gateway.
+	StatusPending    Status = "pending"
+	StatusAuthorized Status = "authorized"
+	StatusCaptured   Status = "captured"
+	StatusVoided     Status = "voided"
+	StatusRefunded   Status = "refunded"
+	StatusFailed     Status = "failed"
+)
+
+// PaymentGateway moves money with a payment provider. Authorize holds the amount of an order on
+// the customer's payment method; the hold is then either captured or voided, and captured
+// amounts can be refunded. Gateways may answer Authorize with StatusPending and confirm the
+// outcome later through a webhook.
+type PaymentGateway interface {
+	// Name identifies the gateway payments were made with.
+	Name() string
+	// Authorize must be idempotent on the request's IdempotencyKey.
+	Authorize(ctx context.Context, req AuthorizeRequest) (*GatewayResult, error)
+	Capture(ctx context.Context, reference string, amount money.Money) (*GatewayResult, error)
+	Void(ctx context.Context, reference string) (*GatewayResult, error)
+	Refund(ctx context.Context, reference string, amount money.Money) (*GatewayResult, error)
+}
+
+// AuthorizeRequest asks a gateway to hold Amount on the payment method Token stands for.
+type AuthorizeRequest struct {
+	OrderID        int
+	Amount         money.Money
+	Token          string
+	IdempotencyKey string
+}
+
+// GatewayResult is the answer of a gateway. Reference identifies the payment at the gateway.
+type GatewayResult struct {
+	Reference     string
+	Status        Status
+	FailureReason string
+}
+
+// Payment is the payment of an order. Orders have at most one payment.
+type Payment struct {
+	ID             int         `json:"id" db:"id"`
+	OrderID        int         `json:"order_id" db:"order_id"`
+	Gateway        string      `json:"gateway" db:"gateway"`
+	Reference      string      `json:"reference" db:"reference"`
+	Status         Status      `json:"status" db:"status"`
+	Amount         money.Money `json:"amount" db:"amount"`
+	CapturedAmount money.Money `json:"captured_amount" db:"captured_amount"`
+	RefundedAmount money.Money `json:"refunded_amount" db:"refunded_amount"`
+	FailureReason  string      `json:"failure_reason,omitempty" db:"failure_reason"`
+	CreatedAt      time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time   `json:"updated_at" db:"updated_at"`
+}
+
+func (p *Payment) ToJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(p)
+}
+
+type EventType string
+
+const (
+	EventAuthorized EventType = "payment.authorized"
+	EventFailed     EventType = "payment.failed"
+	EventCaptured   EventType = "payment.captured"
+	EventVoided     EventType = "payment.voided"
+	EventRefunded   EventType = "payment.refunded"
+)
+
+// Event is an asynchronous notification from the gateway about the payment Reference. Amount
+// is the amount captured or refunded, and defaults to what is left to capture or refund.
+type Event struct {
+	ID            string       `json:"id"`
+	Type          EventType    `json:"type"`
+	Reference     string       `json:"reference"`
+	Amount        *money.Money `json:"amount,omitempty"`
+	FailureReason string       `json:"failure_reason,omitempty"`
+}
+
+// apply moves the payment to the state the event reports. It returns false when the event
+// does not apply to the payment as it is, e.g. when it arrives after a later one.
+func (p *Payment) apply(e *Event) bool {
+	switch e.Type {
+	case EventAuthorized:
+		if p.Status != StatusPending {
+			return false
+		}
+		p.Status = StatusAuthorized
+	case EventFailed:
+		if p.Status != StatusPending {
+			return false
+		}
+		p.Status = StatusFailed
+		p.FailureReason = e.FailureReason
+	case EventCaptured:
+		if p.Status != StatusPending && p.Status != StatusAuthorized {
+			return false
+		}
+		p.Status = StatusCaptured
+		p.CapturedAmount = p.Amount
+		if e.Amount != nil {
+			p.CapturedAmount = *e.Amount
+		}
+	case EventVoided:
+		if p.Status != StatusPending && p.Status != StatusAuthorized {
+			return false
+		}
+		p.Status = StatusVoided
+	case EventRefunded:
+		if p.Status != StatusCaptured {
+			return false
+		}
+		p.Status = StatusRefunded
+		p.RefundedAmount = p.CapturedAmount
+		if e.Amount != nil {
+			p.RefundedAmount = *e.Amount
+		}
+	default:
+		return false
+	}
+
+	return true
+}
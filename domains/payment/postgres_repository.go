@@ -0,0 +1,135 @@
+package payment
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog"
+)
+
+type Repository interface {
+	GetPaymentByOrder(ctx context.Context, orderID int) (*Payment, error)
+	CreatePayment(ctx context.Context, p *Payment) (*Payment, error)
+	UpdatePayment(ctx context.Context, p *Payment) (*Payment, error)
+	// ApplyEvent applies the webhook event e to the payment it is about, once per event id.
+	// It returns the payment and whether the event changed it.
+	ApplyEvent(ctx context.Context, e *Event) (*Payment, bool, error)
+}
+
+type postgresRepository struct {
+	db  *sqlx.DB
+	log zerolog.Logger
+}
+
+// paymentColumns selects a payment; amounts are read with their currency as "12.34 USD".
+const paymentColumns = `id, order_id, gateway, reference, status, amount::text || ' ' || currency AS amount,
+	captured_amount::text || ' ' || currency AS captured_amount, refunded_amount::text || ' ' || currency AS refunded_amount,
+	failure_reason, created_at, updated_at`
+
+func NewPostgresRepository(ctx context.Context, db *sqlx.DB, log zerolog.Logger) *postgresRepository {
+	logger := log.With().Str("repository", "paymentRepository").Logger()
+
+	// ping db
+	if err := db.PingContext(ctx); err != nil {
+		logger.Fatal().Err(fmt.Errorf("failed to connect to postgres: %w", err)).Msg("something went wrong!")
+	}
+
+	return &postgresRepository{db: db, log: logger}
+}
+
+func (r *postgresRepository) GetPaymentByOrder(ctx context.Context, orderID int) (*Payment, error) {
+	return r.getPayment(ctx, r.db, `WHERE order_id = $1`, orderID)
+}
+
+func (r *postgresRepository) getPayment(ctx context.Context, q sqlx.QueryerContext, where string, args ...any) (*Payment, error) {
+	var p Payment
+	query := `SELECT ` + paymentColumns + ` FROM payments ` + where
+
+	err := sqlx.GetContext(ctx, q, &p, query, args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		r.log.Err(err).Str("method", "getPayment").Msg(err.Error())
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+func (r *postgresRepository) CreatePayment(ctx context.Context, p *Payment) (*Payment, error) {
+	query := `INSERT INTO payments (order_id, gateway, reference, status, amount, currency, failure_reason)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING ` + paymentColumns
+
+	var created Payment
+	err := r.db.GetContext(ctx, &created, query,
+		p.OrderID, p.Gateway, p.Reference, p.Status, p.Amount, p.Amount.Currency, p.FailureReason,
+	)
+	if err != nil {
+		r.log.Err(err).Str("method", "CreatePayment").Msg(err.Error())
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+func (r *postgresRepository) UpdatePayment(ctx context.Context, p *Payment) (*Payment, error) {
+	return r.updatePayment(ctx, r.db, p)
+}
+
+func (r *postgresRepository) updatePayment(ctx context.Context, q sqlx.QueryerContext, p *Payment) (*Payment, error) {
+	query := `UPDATE payments
+		SET status = $2, captured_amount = $3, refunded_amount = $4, failure_reason = $5, updated_at = NOW()
+		WHERE id = $1
+		RETURNING ` + paymentColumns
+
+	var updated Payment
+	err := sqlx.GetContext(ctx, q, &updated, query, p.ID, p.Status, p.CapturedAmount, p.RefundedAmount, p.FailureReason)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		r.log.Err(err).Str("method", "updatePayment").Msg(err.Error())
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+func (r *postgresRepository) ApplyEvent(ctx context.Context, e *Event) (*Payment, bool, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	// the row lock orders concurrent events about the same payment
+	p, err := r.getPayment(ctx, tx, `WHERE reference = $1 FOR UPDATE`, e.Reference)
+	if err != nil {
+		return nil, false, err
+	}
+
+	query := `INSERT INTO payment_events (id, payment_id, type) VALUES ($1, $2, $3) ON CONFLICT (id) DO NOTHING`
+	res, err := tx.ExecContext(ctx, query, e.ID, p.ID, e.Type)
+	if err != nil {
+		r.log.Err(err).Str("method", "ApplyEvent").Msg(err.Error())
+		return nil, false, err
+	}
+
+	// a redelivered event was applied the first time
+	if n, _ := res.RowsAffected(); n == 0 || !p.apply(e) {
+		return p, false, tx.Commit()
+	}
+
+	if p, err = r.updatePayment(ctx, tx, p); err != nil {
+		return nil, false, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, false, err
+	}
+
+	return p, true, nil
+}
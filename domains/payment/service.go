@@ -0,0 +1,172 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rovilay/ecommerce-service/common/money"
+	"github.com/rs/zerolog"
+)
+
+type Service struct {
+	repo          Repository
+	gateway       PaymentGateway
+	webhookSecret []byte
+	log           *zerolog.Logger
+}
+
+func NewService(repo Repository, g PaymentGateway, webhookSecret string, l *zerolog.Logger) *Service {
+	logger := l.With().Str("service", "PaymentService").Logger()
+
+	return &Service{repo: repo, gateway: g, webhookSecret: []byte(webhookSecret), log: &logger}
+}
+
+func (s *Service) GetOrderPayment(ctx context.Context, orderID int) (*Payment, error) {
+	return s.repo.GetPaymentByOrder(ctx, orderID)
+}
+
+// Authorize holds amount for the order on the payment method token stands for. An order is
+// only authorized once: later calls return its payment as it is. Declined payments are
+// stored with StatusFailed.
+func (s *Service) Authorize(ctx context.Context, orderID int, amount money.Money, token string) (*Payment, error) {
+	p, err := s.repo.GetPaymentByOrder(ctx, orderID)
+	if err == nil || !errors.Is(err, ErrNotFound) {
+		return p, err
+	}
+
+	res, err := s.gateway.Authorize(ctx, AuthorizeRequest{
+		OrderID:        orderID,
+		Amount:         amount,
+		Token:          token,
+		IdempotencyKey: fmt.Sprintf("order-%d", orderID),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.CreatePayment(ctx, &Payment{
+		OrderID:       orderID,
+		Gateway:       s.gateway.Name(),
+		Reference:     res.Reference,
+		Status:        res.Status,
+		Amount:        amount,
+		FailureReason: res.FailureReason,
+	})
+}
+
+// Capture takes the authorized amount of the order. Orders without a payment, placed before
+// payments were taken, have nothing to capture and return nil.
+func (s *Service) Capture(ctx context.Context, orderID int) (*Payment, error) {
+	p, err := s.repo.GetPaymentByOrder(ctx, orderID)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	switch p.Status {
+	case StatusCaptured:
+		return p, nil
+	case StatusAuthorized:
+	default:
+		return nil, fmt.Errorf("%w: payment is %s", ErrInvalidState, p.Status)
+	}
+
+	if _, err = s.gateway.Capture(ctx, p.Reference, p.Amount); err != nil {
+		return nil, err
+	}
+
+	p.Status = StatusCaptured
+	p.CapturedAmount = p.Amount
+	return s.repo.UpdatePayment(ctx, p)
+}
+
+// Void releases the payment of a cancelled order. Payments already captured are refunded.
+func (s *Service) Void(ctx context.Context, orderID int) (*Payment, error) {
+	p, err := s.repo.GetPaymentByOrder(ctx, orderID)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	switch p.Status {
+	case StatusVoided, StatusFailed, StatusRefunded:
+		return p, nil
+	case StatusCaptured:
+		return s.refund(ctx, p)
+	}
+
+	if _, err = s.gateway.Void(ctx, p.Reference); err != nil {
+		return nil, err
+	}
+
+	p.Status = StatusVoided
+	return s.repo.UpdatePayment(ctx, p)
+}
+
+// Refund gives back the captured amount of the order.
+func (s *Service) Refund(ctx context.Context, orderID int) (*Payment, error) {
+	p, err := s.repo.GetPaymentByOrder(ctx, orderID)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	switch p.Status {
+	case StatusRefunded:
+		return p, nil
+	case StatusCaptured:
+		return s.refund(ctx, p)
+	default:
+		return nil, fmt.Errorf("%w: payment is %s", ErrInvalidState, p.Status)
+	}
+}
+
+func (s *Service) refund(ctx context.Context, p *Payment) (*Payment, error) {
+	amount, err := p.CapturedAmount.Sub(p.RefundedAmount)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = s.gateway.Refund(ctx, p.Reference, amount); err != nil {
+		return nil, err
+	}
+
+	p.Status = StatusRefunded
+	p.RefundedAmount = p.CapturedAmount
+	return s.repo.UpdatePayment(ctx, p)
+}
+
+// HandleWebhook verifies the signature of a gateway webhook and applies its event. It returns
+// the payment the event is about, and whether the event changed it; redelivered and stale
+// events change nothing.
+func (s *Service) HandleWebhook(ctx context.Context, body []byte, signature string) (*Payment, bool, error) {
+	if err := VerifySignature(s.webhookSecret, body, signature, time.Now()); err != nil {
+		return nil, false, err
+	}
+
+	var e Event
+	if err := json.Unmarshal(body, &e); err != nil {
+		return nil, false, fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+	}
+
+	if e.ID == "" || e.Reference == "" {
+		return nil, false, fmt.Errorf("%w: id and reference are required", ErrInvalidEvent)
+	}
+
+	p, changed, err := s.repo.ApplyEvent(ctx, &e)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if changed {
+		s.log.Info().Int("order", p.OrderID).Str("event", string(e.Type)).Str("status", string(p.Status)).Msg("payment updated by webhook")
+	}
+
+	return p, changed, nil
+}
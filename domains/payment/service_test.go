@@ -0,0 +1,327 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rovilay/ecommerce-service/common/money"
+	"github.com/rs/zerolog"
+)
+
+// memoryRepository is a Repository that keeps payments in memory.
+type memoryRepository struct {
+	mu       sync.Mutex
+	payments map[int]Payment
+	events   map[string]bool
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{payments: map[int]Payment{}, events: map[string]bool{}}
+}
+
+func (r *memoryRepository) GetPaymentByOrder(ctx context.Context, orderID int) (*Payment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p, ok := r.payments[orderID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return &p, nil
+}
+
+func (r *memoryRepository) CreatePayment(ctx context.Context, p *Payment) (*Payment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	created := *p
+	created.ID = len(r.payments) + 1
+	created.CapturedAmount = money.New(0, p.Amount.Currency)
+	created.RefundedAmount = money.New(0, p.Amount.Currency)
+	r.payments[p.OrderID] = created
+
+	return &created, nil
+}
+
+func (r *memoryRepository) UpdatePayment(ctx context.Context, p *Payment) (*Payment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.payments[p.OrderID]; !ok {
+		return nil, ErrNotFound
+	}
+	r.payments[p.OrderID] = *p
+
+	return p, nil
+}
+
+func (r *memoryRepository) ApplyEvent(ctx context.Context, e *Event) (*Payment, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for orderID, p := range r.payments {
+		if p.Reference != e.Reference {
+			continue
+		}
+
+		if r.events[e.ID] {
+			return &p, false, nil
+		}
+		r.events[e.ID] = true
+
+		if !p.apply(e) {
+			return &p, false, nil
+		}
+		r.payments[orderID] = p
+
+		return &p, true, nil
+	}
+
+	return nil, false, ErrNotFound
+}
+
+const testWebhookSecret = "webhook-secret"
+
+func newTestService() (*Service, *FakeGateway) {
+	gateway := NewFakeGateway()
+	logger := zerolog.Nop()
+
+	return NewService(newMemoryRepository(), gateway, testWebhookSecret, &logger), gateway
+}
+
+func TestAuthorize(t *testing.T) {
+	amount := money.New(2599, "USD")
+
+	tests := []struct {
+		token string
+		want  Status
+	}{
+		{token: "tok_visa", want: StatusAuthorized},
+		{token: FakeTokenPending, want: StatusPending},
+		{token: FakeTokenDecline, want: StatusFailed},
+		{token: "", want: StatusFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.token, func(t *testing.T) {
+			s, _ := newTestService()
+
+			p, err := s.Authorize(context.Background(), 1, amount, tt.token)
+			if err != nil {
+				t.Fatalf("Authorize() unexpected error: %v", err)
+			}
+			if p.Status != tt.want || p.Amount != amount || p.Gateway != "fake" || p.Reference == "" {
+				t.Errorf("Authorize() = %+v, want a %s fake payment of %v", p, tt.want, amount)
+			}
+			if tt.want == StatusFailed && p.FailureReason == "" {
+				t.Errorf("declined payment has no failure reason")
+			}
+		})
+	}
+}
+
+func TestAuthorizeOnce(t *testing.T) {
+	s, _ := newTestService()
+	ctx := context.Background()
+
+	first, err := s.Authorize(ctx, 1, money.New(1000, "USD"), "tok_visa")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	again, err := s.Authorize(ctx, 1, money.New(5000, "USD"), FakeTokenDecline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ID != first.ID || again.Reference != first.Reference || again.Status != StatusAuthorized {
+		t.Errorf("second Authorize() = %+v, want the first payment %+v", again, first)
+	}
+}
+
+func TestSettlement(t *testing.T) {
+	amount := money.New(4200, "EUR")
+
+	type step struct {
+		op      func(s *Service, ctx context.Context) (*Payment, error)
+		want    Status
+		wantErr error
+	}
+	capture := func(s *Service, ctx context.Context) (*Payment, error) { return s.Capture(ctx, 1) }
+	void := func(s *Service, ctx context.Context) (*Payment, error) { return s.Void(ctx, 1) }
+	refund := func(s *Service, ctx context.Context) (*Payment, error) { return s.Refund(ctx, 1) }
+
+	tests := []struct {
+		name  string
+		token string
+		steps []step
+	}{
+		{name: "capture", token: "tok_visa", steps: []step{{op: capture, want: StatusCaptured}, {op: capture, want: StatusCaptured}}},
+		{name: "void", token: "tok_visa", steps: []step{{op: void, want: StatusVoided}, {op: void, want: StatusVoided}}},
+		{name: "void pending", token: FakeTokenPending, steps: []step{{op: void, want: StatusVoided}}},
+		{name: "void after capture refunds", token: "tok_visa", steps: []step{{op: capture, want: StatusCaptured}, {op: void, want: StatusRefunded}}},
+		{name: "refund", token: "tok_visa", steps: []step{{op: capture, want: StatusCaptured}, {op: refund, want: StatusRefunded}, {op: refund, want: StatusRefunded}}},
+		{name: "void declined", token: FakeTokenDecline, steps: []step{{op: void, want: StatusFailed}}},
+		{name: "capture declined", token: FakeTokenDecline, steps: []step{{op: capture, wantErr: ErrInvalidState}}},
+		{name: "capture pending", token: FakeTokenPending, steps: []step{{op: capture, wantErr: ErrInvalidState}}},
+		{name: "capture voided", token: "tok_visa", steps: []step{{op: void, want: StatusVoided}, {op: capture, wantErr: ErrInvalidState}}},
+		{name: "refund uncaptured", token: "tok_visa", steps: []step{{op: refund, wantErr: ErrInvalidState}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestService()
+			ctx := context.Background()
+
+			if _, err := s.Authorize(ctx, 1, amount, tt.token); err != nil {
+				t.Fatal(err)
+			}
+
+			for i, st := range tt.steps {
+				p, err := st.op(s, ctx)
+				if st.wantErr != nil {
+					if !errors.Is(err, st.wantErr) {
+						t.Fatalf("step %d: error = %v, want %v", i, err, st.wantErr)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("step %d: unexpected error: %v", i, err)
+				}
+				if p.Status != st.want {
+					t.Fatalf("step %d: status = %s, want %s", i, p.Status, st.want)
+				}
+			}
+
+			stored, err := s.GetOrderPayment(ctx, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			switch stored.Status {
+			case StatusCaptured:
+				if stored.CapturedAmount != amount {
+					t.Errorf("captured %v, want %v", stored.CapturedAmount, amount)
+				}
+			case StatusRefunded:
+				if stored.RefundedAmount != amount {
+					t.Errorf("refunded %v, want %v", stored.RefundedAmount, amount)
+				}
+			}
+		})
+	}
+}
+
+func TestSettleWithoutPayment(t *testing.T) {
+	s, _ := newTestService()
+	ctx := context.Background()
+
+	ops := map[string]func(context.Context, int) (*Payment, error){"capture": s.Capture, "void": s.Void, "refund": s.Refund}
+	for name, op := range ops {
+		if p, err := op(ctx, 99); p != nil || err != nil {
+			t.Errorf("%s without a payment = %v, %v, want nil, nil", name, p, err)
+		}
+	}
+}
+
+func TestHandleWebhook(t *testing.T) {
+	s, gateway := newTestService()
+	ctx := context.Background()
+
+	p, err := s.Authorize(ctx, 1, money.New(1500, "USD"), FakeTokenPending)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := gateway.Confirm(p.Reference, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature := Sign([]byte(testWebhookSecret), body, time.Now())
+
+	updated, changed, err := s.HandleWebhook(ctx, body, signature)
+	if err != nil {
+		t.Fatalf("HandleWebhook() unexpected error: %v", err)
+	}
+	if !changed || updated.Status != StatusAuthorized {
+		t.Fatalf("HandleWebhook() = %+v, %v, want an authorized payment that changed", updated, changed)
+	}
+
+	// a redelivered event changes nothing
+	updated, changed, err = s.HandleWebhook(ctx, body, signature)
+	if err != nil || changed || updated.Status != StatusAuthorized {
+		t.Errorf("redelivered HandleWebhook() = %+v, %v, %v, want the authorized payment, unchanged", updated, changed, err)
+	}
+
+	// once authorized by the webhook, the payment can be captured
+	if p, err = s.Capture(ctx, 1); err != nil || p.Status != StatusCaptured {
+		t.Errorf("Capture() after webhook = %+v, %v, want a captured payment", p, err)
+	}
+
+	// an event that no longer applies changes nothing
+	late, _ := json.Marshal(Event{ID: "evt_late", Type: EventFailed, Reference: p.Reference})
+	updated, changed, err = s.HandleWebhook(ctx, late, Sign([]byte(testWebhookSecret), late, time.Now()))
+	if err != nil || changed || updated.Status != StatusCaptured {
+		t.Errorf("late HandleWebhook() = %+v, %v, %v, want the captured payment, unchanged", updated, changed, err)
+	}
+}
+
+func TestHandleWebhookFailedPayment(t *testing.T) {
+	s, gateway := newTestService()
+	ctx := context.Background()
+
+	p, err := s.Authorize(ctx, 1, money.New(1500, "USD"), FakeTokenPending)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := gateway.Confirm(p.Reference, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := json.Marshal(e)
+
+	updated, changed, err := s.HandleWebhook(ctx, body, Sign([]byte(testWebhookSecret), body, time.Now()))
+	if err != nil || !changed || updated.Status != StatusFailed || updated.FailureReason == "" {
+		t.Errorf("HandleWebhook() = %+v, %v, %v, want a failed payment with a reason", updated, changed, err)
+	}
+}
+
+func TestHandleWebhookRejects(t *testing.T) {
+	s, _ := newTestService()
+	secret := []byte(testWebhookSecret)
+	now := time.Now()
+
+	valid, _ := json.Marshal(Event{ID: "evt_1", Type: EventAuthorized, Reference: "fake_1"})
+	noID, _ := json.Marshal(Event{Type: EventAuthorized, Reference: "fake_1"})
+	notJSON := []byte("not json")
+
+	tests := []struct {
+		name      string
+		body      []byte
+		signature string
+		wantErr   error
+	}{
+		{name: "bad signature", body: valid, signature: Sign([]byte("other-secret"), valid, now), wantErr: ErrInvalidSignature},
+		{name: "stale signature", body: valid, signature: Sign(secret, valid, now.Add(-time.Hour)), wantErr: ErrInvalidSignature},
+		{name: "no signature", body: valid, wantErr: ErrInvalidSignature},
+		{name: "not json", body: notJSON, signature: Sign(secret, notJSON, now), wantErr: ErrInvalidEvent},
+		{name: "no event id", body: noID, signature: Sign(secret, noID, now), wantErr: ErrInvalidEvent},
+		{name: "unknown payment", body: valid, signature: Sign(secret, valid, now), wantErr: ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := s.HandleWebhook(context.Background(), tt.body, tt.signature); !errors.Is(err, tt.wantErr) {
+				t.Errorf("HandleWebhook() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package payment
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifySignature(t *testing.T) {
+	secret := []byte("webhook-secret")
+	body := []byte(`{"id":"evt_1","type":"payment.authorized","reference":"fake_1"}`)
+	now := time.Unix(1700000000, 0)
+	valid := Sign(secret, body, now)
+
+	tests := []struct {
+		name    string
+		secret  []byte
+		body    []byte
+		header  string
+		wantErr bool
+	}{
+		{name: "valid", secret: secret, body: body, header: valid},
+		{name: "signed within the tolerance", secret: secret, body: body, header: Sign(secret, body, now.Add(-4*time.Minute))},
+		{name: "one of several signatures", secret: secret, body: body, header: valid + ",v1=00ff"},
+		{name: "tampered body", secret: secret, body: []byte(`{"id":"evt_1","type":"payment.captured","reference":"fake_1"}`), header: valid, wantErr: true},
+		{name: "tampered signature", secret: secret, body: body, header: strings.Replace(valid, "v1=", "v1=00", 1), wantErr: true},
+		{name: "tampered timestamp", secret: secret, body: body, header: strings.Replace(valid, "t=1700000000", "t=1700000001", 1), wantErr: true},
+		{name: "wrong secret", secret: []byte("other-secret"), body: body, header: valid, wantErr: true},
+		{name: "stale", secret: secret, body: body, header: Sign(secret, body, now.Add(-6*time.Minute)), wantErr: true},
+		{name: "from the future", secret: secret, body: body, header: Sign(secret, body, now.Add(6*time.Minute)), wantErr: true},
+		{name: "missing timestamp", secret: secret, body: body, header: valid[strings.Index(valid, ",")+1:], wantErr: true},
+		{name: "missing signature", secret: secret, body: body, header: "t=1700000000", wantErr: true},
+		{name: "empty header", secret: secret, body: body, header: "", wantErr: true},
+		{name: "empty secret", secret: nil, body: body, header: Sign(nil, body, now), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifySignature(tt.secret, tt.body, tt.header, now)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidSignature) {
+					t.Errorf("VerifySignature() error = %v, want %v", err, ErrInvalidSignature)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("VerifySignature() unexpected error: %v", err)
+			}
+		})
+	}
+}
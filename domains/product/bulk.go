@@ -0,0 +1,272 @@
+package product
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/rovilay/ecommerce-service/common/money"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported format, use csv or ndjson")
+var ErrDeletedSKU = errors.New("sku belongs to a deleted product")
+
+// BulkFormat is the encoding of a product import or export.
+type BulkFormat string
+
+const (
+	FormatCSV    BulkFormat = "csv"
+	FormatNDJSON BulkFormat = "ndjson"
+)
+
+func (f BulkFormat) IsValid() bool {
+	switch f {
+	case FormatCSV, FormatNDJSON:
+		return true
+	}
+	return false
+}
+
+func (f BulkFormat) ContentType() string {
+	if f == FormatCSV {
+		return "text/csv"
+	}
+
+	return "application/x-ndjson"
+}
+
+// csvHeader lists the columns of a CSV import or export. Imports may order them freely and
+// leave out the optional ones (description, currency, image_url and weight_grams).
+var csvHeader = []string{"sku", "name", "description", "price", "currency", "image_url", "category_id", "weight_grams"}
+
+// RowError is a row of an import that could not be read or stored. Rows are the records of
+// the file numbered from 1, not counting the CSV header or blank NDJSON lines.
+type RowError struct {
+	Row   int    `json:"row"`
+	SKU   string `json:"sku,omitempty"`
+	Error string `json:"error"`
+}
+
+// ImportReport summarises an import. Rows listed in Errors were skipped; all other rows were
+// stored. Error is set when the import stopped early, in which case the rows before it were
+// still stored.
+type ImportReport struct {
+	Rows    int        `json:"rows"`
+	Created int        `json:"created"`
+	Updated int        `json:"updated"`
+	Failed  int        `json:"failed"`
+	Errors  []RowError `json:"errors"`
+	Error   string     `json:"error,omitempty"`
+}
+
+func (r *ImportReport) fail(row int, sku string, err error) {
+	r.Failed++
+	r.Errors = append(r.Errors, RowError{Row: row, SKU: sku, Error: err.Error()})
+}
+
+// rowReadError is a row that could not be decoded. Reading carries on with the next row.
+type rowReadError struct {
+	err error
+}
+
+func (e *rowReadError) Error() string {
+	return e.err.Error()
+}
+
+func (e *rowReadError) Unwrap() error {
+	return e.err
+}
+
+// ProductReader reads the products of an import one row at a time. Next returns io.EOF after
+// the last row and a *rowReadError for a row it could not decode; any other error ends the import.
+type ProductReader interface {
+	Next() (*Product, error)
+}
+
+func NewProductReader(format BulkFormat, r io.Reader) (ProductReader, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVProductReader(r)
+	case FormatNDJSON:
+		return newNDJSONProductReader(r), nil
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+type csvProductReader struct {
+	r       *csv.Reader
+	columns map[string]int
+}
+
+func newCSVProductReader(r io.Reader) (*csvProductReader, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	cr.ReuseRecord = true
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("csv header is missing")
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	for _, name := range []string{"sku", "name", "price", "category_id"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("csv header is missing the %s column", name)
+		}
+	}
+
+	return &csvProductReader{r: cr, columns: columns}, nil
+}
+
+func (c *csvProductReader) Next() (*Product, error) {
+	record, err := c.r.Read()
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return nil, &rowReadError{err}
+	} else if err != nil {
+		return nil, err
+	}
+
+	field := func(name string) string {
+		i, ok := c.columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	p := &Product{
+		SKU:         field("sku"),
+		Name:        field("name"),
+		Description: field("description"),
+		ImageURL:    field("image_url"),
+	}
+
+	currency := strings.ToUpper(field("currency"))
+	if currency == "" {
+		currency = money.DefaultCurrency
+	}
+
+	p.Price, err = money.Parse(field("price"), currency)
+	if err != nil {
+		return p, &rowReadError{err}
+	}
+
+	p.CategoryID, err = strconv.Atoi(field("category_id"))
+	if err != nil {
+		return p, &rowReadError{errors.New("category_id must be a number")}
+	}
+
+	if w := field("weight_grams"); w != "" {
+		if p.WeightGrams, err = strconv.Atoi(w); err != nil {
+			return p, &rowReadError{errors.New("weight_grams must be a number")}
+		}
+	}
+
+	return p, nil
+}
+
+type ndjsonProductReader struct {
+	s *bufio.Scanner
+}
+
+// maxNDJSONLine bounds the size of a single NDJSON row.
+const maxNDJSONLine = 1 << 20
+
+func newNDJSONProductReader(r io.Reader) *ndjsonProductReader {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), maxNDJSONLine)
+
+	return &ndjsonProductReader{s: s}
+}
+
+func (n *ndjsonProductReader) Next() (*Product, error) {
+	for n.s.Scan() {
+		line := bytes.TrimSpace(n.s.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var p Product
+		if err := json.Unmarshal(line, &p); err != nil {
+			return nil, &rowReadError{err}
+		}
+
+		return &p, nil
+	}
+
+	if err := n.s.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, io.EOF
+}
+
+// ProductWriter writes the products of an export. Flush must be called after the last product.
+type ProductWriter interface {
+	Write(p *Product) error
+	Flush() error
+}
+
+func NewProductWriter(format BulkFormat, w io.Writer) (ProductWriter, error) {
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvHeader); err != nil {
+			return nil, err
+		}
+		return &csvProductWriter{w: cw}, nil
+	case FormatNDJSON:
+		return &ndjsonProductWriter{enc: json.NewEncoder(w)}, nil
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+type csvProductWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvProductWriter) Write(p *Product) error {
+	return c.w.Write([]string{
+		p.SKU,
+		p.Name,
+		p.Description,
+		p.Price.Decimal(),
+		p.Price.Currency,
+		p.ImageURL,
+		strconv.Itoa(p.CategoryID),
+		strconv.Itoa(p.WeightGrams),
+	})
+}
+
+func (c *csvProductWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type ndjsonProductWriter struct {
+	enc *json.Encoder
+}
+
+func (n *ndjsonProductWriter) Write(p *Product) error {
+	return n.enc.Encode(p)
+}
+
+func (n *ndjsonProductWriter) Flush() error {
+	return nil
+}
@@ -0,0 +1,160 @@
+package product
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"time"
+)
+
+var ErrInvalidImage = errors.New("invalid image")
+var ErrImageTooLarge = errors.New("image is too large")
+var ErrTooManyImages = errors.New("product has too many images")
+var ErrInvalidImageOrder = errors.New("image order must list every image of the product exactly once")
+
+const (
+	// MaxImageBytes is the largest image file that can be uploaded.
+	MaxImageBytes = 5 << 20
+	// MaxProductImages is the most images a product can have.
+	MaxProductImages = 10
+	// maxImagePixels guards against small files that decode into huge images.
+	maxImagePixels = 25_000_000
+	// thumbnailSize is the longest side of a thumbnail in pixels.
+	thumbnailSize = 320
+)
+
+// imageExtensions maps the sniffed content types accepted for product images to the
+// extension their blobs are stored with.
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// ProductImage is an uploaded image of a product. Images are shown in Position order, and
+// the first one is mirrored into Product.ImageURL.
+type ProductImage struct {
+	ID           int       `json:"id" db:"id"`
+	ProductID    int       `json:"product_id" db:"product_id"`
+	Position     int       `json:"position" db:"position"`
+	URL          string    `json:"url" db:"url"`
+	ThumbnailURL string    `json:"thumbnail_url" db:"thumbnail_url"`
+	ContentType  string    `json:"content_type" db:"content_type"`
+	Size         int64     `json:"size" db:"size_bytes"`
+	Width        int       `json:"width" db:"width"`
+	Height       int       `json:"height" db:"height"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+
+	Key          string `json:"-" db:"blob_key"`
+	ThumbnailKey string `json:"-" db:"thumbnail_key"`
+}
+
+// ImageUpload is an image file as uploaded, before it is checked.
+type ImageUpload struct {
+	Filename string
+	Data     []byte
+}
+
+// processedImage is an upload that was recognised as an image, with its thumbnail.
+type processedImage struct {
+	contentType   string
+	ext           string
+	width, height int
+
+	thumbnail            []byte
+	thumbnailContentType string
+	thumbnailExt         string
+}
+
+// processImage sniffs the content type of data, which must be a JPEG, PNG or GIF image,
+// and renders its thumbnail. The type declared by the client is not trusted.
+func processImage(data []byte) (*processedImage, error) {
+	if len(data) > MaxImageBytes {
+		return nil, ErrImageTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	ext, ok := imageExtensions[contentType]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is not a supported image type", ErrInvalidImage, contentType)
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+
+	if cfg.Width*cfg.Height > maxImagePixels {
+		return nil, fmt.Errorf("%w: %dx%d pixels", ErrImageTooLarge, cfg.Width, cfg.Height)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+
+	p := &processedImage{contentType: contentType, ext: ext, width: cfg.Width, height: cfg.Height}
+
+	// photos stay JPEG, anything that may be transparent becomes PNG
+	var buf bytes.Buffer
+	thumb := thumbnail(img, thumbnailSize)
+	if contentType == "image/jpeg" {
+		err = jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 85})
+		p.thumbnailContentType, p.thumbnailExt = "image/jpeg", ".jpg"
+	} else {
+		err = png.Encode(&buf, thumb)
+		p.thumbnailContentType, p.thumbnailExt = "image/png", ".png"
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p.thumbnail = buf.Bytes()
+	return p, nil
+}
+
+// thumbnail scales img down to fit a size x size box, keeping its aspect ratio. Each pixel of
+// the thumbnail averages the pixels of img it covers. Images that already fit keep their size.
+func thumbnail(img image.Image, size int) *image.RGBA64 {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	tw, th := w, h
+	if w > size || h > size {
+		if w >= h {
+			tw, th = size, max(1, h*size/w)
+		} else {
+			tw, th = max(1, w*size/h), size
+		}
+	}
+
+	dst := image.NewRGBA64(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0 := b.Min.Y + y*h/th
+		y1 := max(y0+1, b.Min.Y+(y+1)*h/th)
+
+		for x := 0; x < tw; x++ {
+			x0 := b.Min.X + x*w/tw
+			x1 := max(x0+1, b.Min.X+(x+1)*w/tw)
+
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+
+			dst.SetRGBA64(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(bl / n), A: uint16(a / n)})
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,147 @@
+package product
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func encodeImage(t *testing.T, format string, w, h int) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case "png":
+		err = png.Encode(&buf, img)
+	case "jpeg":
+		err = jpeg.Encode(&buf, img, nil)
+	case "gif":
+		err = gif.Encode(&buf, img, nil)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+// pngHeader returns the start of a PNG that claims to be w x h pixels, without any pixel data.
+func pngHeader(w, h uint32) []byte {
+	ihdr := make([]byte, 17)
+	copy(ihdr, "IHDR")
+	binary.BigEndian.PutUint32(ihdr[4:], w)
+	binary.BigEndian.PutUint32(ihdr[8:], h)
+	ihdr[12], ihdr[13] = 8, 2 // 8 bit RGB
+
+	b := []byte("\x89PNG\r\n\x1a\n")
+	b = binary.BigEndian.AppendUint32(b, 13)
+	b = append(b, ihdr...)
+	return binary.BigEndian.AppendUint32(b, crc32.ChecksumIEEE(ihdr))
+}
+
+func TestProcessImageRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{name: "oversize file", data: make([]byte, MaxImageBytes+1), wantErr: ErrImageTooLarge},
+		{name: "text", data: []byte("definitely not an image"), wantErr: ErrInvalidImage},
+		{name: "pdf", data: []byte("%PDF-1.7\n"), wantErr: ErrInvalidImage},
+		{name: "svg", data: []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`), wantErr: ErrInvalidImage},
+		{name: "empty", data: nil, wantErr: ErrInvalidImage},
+		{name: "truncated png", data: []byte("\x89PNG\r\n\x1a\n"), wantErr: ErrInvalidImage},
+		{name: "too many pixels", data: pngHeader(6000, 5000), wantErr: ErrImageTooLarge},
+		{name: "header without pixels", data: pngHeader(100, 100), wantErr: ErrInvalidImage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := processImage(tt.data); !errors.Is(err, tt.wantErr) {
+				t.Errorf("processImage() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProcessImage(t *testing.T) {
+	tests := []struct {
+		format                 string
+		w, h                   int
+		wantType, wantExt      string
+		wantThumbType          string
+		wantThumbW, wantThumbH int
+	}{
+		{format: "png", w: 640, h: 480, wantType: "image/png", wantExt: ".png", wantThumbType: "image/png", wantThumbW: 320, wantThumbH: 240},
+		{format: "jpeg", w: 200, h: 800, wantType: "image/jpeg", wantExt: ".jpg", wantThumbType: "image/jpeg", wantThumbW: 80, wantThumbH: 320},
+		{format: "gif", w: 10, h: 12, wantType: "image/gif", wantExt: ".gif", wantThumbType: "image/png", wantThumbW: 10, wantThumbH: 12},
+		{format: "png", w: 320, h: 320, wantType: "image/png", wantExt: ".png", wantThumbType: "image/png", wantThumbW: 320, wantThumbH: 320},
+		{format: "png", w: 1000, h: 1, wantType: "image/png", wantExt: ".png", wantThumbType: "image/png", wantThumbW: 320, wantThumbH: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			p, err := processImage(encodeImage(t, tt.format, tt.w, tt.h))
+			if err != nil {
+				t.Fatalf("processImage() unexpected error: %v", err)
+			}
+
+			if p.contentType != tt.wantType || p.ext != tt.wantExt || p.width != tt.w || p.height != tt.h {
+				t.Errorf("processImage() = %s %s %dx%d, want %s %s %dx%d", p.contentType, p.ext, p.width, p.height, tt.wantType, tt.wantExt, tt.w, tt.h)
+			}
+			if p.thumbnailContentType != tt.wantThumbType || p.thumbnailExt != imageExtensions[tt.wantThumbType] {
+				t.Errorf("thumbnail type = %s %s, want %s", p.thumbnailContentType, p.thumbnailExt, tt.wantThumbType)
+			}
+
+			cfg, format, err := image.DecodeConfig(bytes.NewReader(p.thumbnail))
+			if err != nil {
+				t.Fatalf("thumbnail does not decode: %v", err)
+			}
+			if "image/"+format != tt.wantThumbType || cfg.Width != tt.wantThumbW || cfg.Height != tt.wantThumbH {
+				t.Errorf("thumbnail = %s %dx%d, want %s %dx%d", format, cfg.Width, cfg.Height, tt.wantThumbType, tt.wantThumbW, tt.wantThumbH)
+			}
+		})
+	}
+}
+
+func TestThumbnailAveragesPixels(t *testing.T) {
+	// a 4x2 image whose left half is black and right half white, offset from the origin
+	img := image.NewGray(image.Rect(10, 10, 14, 12))
+	for y := 10; y < 12; y++ {
+		img.Set(10, y, color.Gray{Y: 0})
+		img.Set(11, y, color.Gray{Y: 0})
+		img.Set(12, y, color.Gray{Y: 255})
+		img.Set(13, y, color.Gray{Y: 255})
+	}
+
+	thumb := thumbnail(img, 2)
+	if b := thumb.Bounds(); b.Dx() != 2 || b.Dy() != 1 {
+		t.Fatalf("thumbnail is %dx%d, want 2x1", b.Dx(), b.Dy())
+	}
+	if c := thumb.RGBA64At(0, 0); c.R != 0 || c.A != 0xffff {
+		t.Errorf("left pixel = %v, want black", c)
+	}
+	if c := thumb.RGBA64At(1, 0); c.R != 0xffff || c.A != 0xffff {
+		t.Errorf("right pixel = %v, want white", c)
+	}
+
+	// the whole image in one pixel is the average grey
+	if c := thumbnail(img, 1).RGBA64At(0, 0); c.R != 0xffff/2 || c.G != 0xffff/2 || c.B != 0xffff/2 {
+		t.Errorf("single pixel = %v, want mid grey", c)
+	}
+}
@@ -0,0 +1,113 @@
+package product
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jmoiron/sqlx"
+	"github.com/rovilay/ecommerce-service/common/events"
+)
+
+var ErrInvalidCategory = errors.New("category does not exist")
+
+// UpsertResult is the outcome of storing one product of a batch. Err is set when the product
+// was skipped; the rest of the batch is stored regardless.
+type UpsertResult struct {
+	Product *Product
+	Created bool
+	Err     error
+}
+
+// UpsertProducts creates or updates the products by SKU in one transaction, with the
+// product.created or product.updated event of each. A product that cannot be stored, e.g.
+// because its category does not exist, is rolled back on its own and reported in its result.
+func (r *postgresRepository) UpsertProducts(ctx context.Context, products []*Product) ([]UpsertResult, error) {
+	log := r.log.With().Str("method", "UpsertProducts").Logger()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	results := make([]UpsertResult, len(products))
+	for i, p := range products {
+		if _, err = tx.ExecContext(ctx, `SAVEPOINT upsert_product`); err != nil {
+			return nil, err
+		}
+
+		results[i], err = upsertProduct(ctx, tx, p)
+		if err != nil {
+			log.Err(err).Str("sku", p.SKU).Msg("failed to upsert product")
+			results[i].Err = err
+
+			if _, err = tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT upsert_product`); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if _, err = tx.ExecContext(ctx, `RELEASE SAVEPOINT upsert_product`); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func upsertProduct(ctx context.Context, tx *sqlx.Tx, p *Product) (UpsertResult, error) {
+	// xmax is 0 for a freshly inserted row
+	query := `
+		INSERT INTO products (name, description, price, currency, sku, image_url, category_id, weight_grams)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (sku) DO UPDATE
+		SET name = EXCLUDED.name, description = EXCLUDED.description, price = EXCLUDED.price,
+			currency = EXCLUDED.currency, image_url = EXCLUDED.image_url, category_id = EXCLUDED.category_id,
+			weight_grams = EXCLUDED.weight_grams, updated_at = NOW()
+		WHERE products.deleted_at IS NULL
+		RETURNING ` + productColumns + `, xmax = 0 AS created
+	`
+
+	var row struct {
+		Product
+		Created bool `db:"created"`
+	}
+	err := tx.GetContext(ctx, &row, query, p.Name, p.Description, p.Price, p.Price.Currency, p.SKU, p.ImageURL, p.CategoryID, p.WeightGrams)
+	var pgErr *pgconn.PgError
+	if errors.Is(err, sql.ErrNoRows) {
+		return UpsertResult{}, ErrDeletedSKU
+	} else if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		return UpsertResult{}, ErrInvalidCategory
+	} else if err != nil {
+		return UpsertResult{}, err
+	}
+
+	stored := &row.Product
+	stored.Price, err = setBasePrice(ctx, tx, stored.ID, stored.Price, time.Now())
+	if err != nil {
+		return UpsertResult{}, err
+	}
+
+	key := events.ProductCreated
+	if !row.Created {
+		// updated products keep their variants, which consumers expect on the event
+		key = events.ProductUpdated
+		stored.Variants, err = getProductVariants(ctx, tx, stored.ID)
+		if err != nil {
+			return UpsertResult{}, err
+		}
+	}
+
+	if err = events.WriteOutbox(ctx, tx, events.Product, key, stored); err != nil {
+		return UpsertResult{}, err
+	}
+
+	return UpsertResult{Product: stored, Created: row.Created}, nil
+}
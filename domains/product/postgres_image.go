@@ -0,0 +1,183 @@
+package product
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const imageColumns = `id, product_id, position, url, thumbnail_url, content_type, size_bytes, width, height, created_at, blob_key, thumbnail_key`
+
+func (r *postgresRepository) GetProductImages(ctx context.Context, productID int) ([]*ProductImage, error) {
+	return getProductImages(ctx, r.db, productID)
+}
+
+func getProductImages(ctx context.Context, q sqlx.QueryerContext, productID int) ([]*ProductImage, error) {
+	query := `SELECT ` + imageColumns + ` FROM product_images WHERE product_id = $1 ORDER BY position, id`
+
+	images := []*ProductImage{}
+	err := sqlx.SelectContext(ctx, q, &images, query, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	return images, nil
+}
+
+// AddProductImages appends images to the product, after the ones it already has.
+func (r *postgresRepository) AddProductImages(ctx context.Context, productID int, images []*ProductImage) ([]*ProductImage, error) {
+	log := r.log.With().Str("method", "AddProductImages").Logger()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err = lockProduct(ctx, tx, productID); err != nil {
+		return nil, err
+	}
+
+	var count, last int
+	query := `SELECT count(*), coalesce(max(position), 0) FROM product_images WHERE product_id = $1`
+	if err = tx.QueryRowContext(ctx, query, productID).Scan(&count, &last); err != nil {
+		return nil, err
+	}
+
+	if count+len(images) > MaxProductImages {
+		return nil, ErrTooManyImages
+	}
+
+	query = `INSERT INTO product_images
+		(product_id, position, blob_key, thumbnail_key, url, thumbnail_url, content_type, size_bytes, width, height)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+	for i, img := range images {
+		_, err = tx.ExecContext(ctx, query, productID, last+i+1, img.Key, img.ThumbnailKey, img.URL, img.ThumbnailURL,
+			img.ContentType, img.Size, img.Width, img.Height)
+		if err != nil {
+			log.Err(err).Msg(err.Error())
+			return nil, err
+		}
+	}
+
+	return r.commitImageChange(ctx, tx, productID)
+}
+
+// ReorderProductImages moves the images of a product into the order of imageIDs, which must
+// list each of them once.
+func (r *postgresRepository) ReorderProductImages(ctx context.Context, productID int, imageIDs []int) ([]*ProductImage, error) {
+	log := r.log.With().Str("method", "ReorderProductImages").Logger()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err = lockProduct(ctx, tx, productID); err != nil {
+		return nil, err
+	}
+
+	current, err := getProductImages(ctx, tx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !sameImageSet(current, imageIDs) {
+		return nil, ErrInvalidImageOrder
+	}
+
+	query := `UPDATE product_images i SET position = o.position
+		FROM unnest($2::int[]) WITH ORDINALITY AS o(id, position)
+		WHERE i.id = o.id AND i.product_id = $1
+	`
+	if _, err = tx.ExecContext(ctx, query, productID, imageIDs); err != nil {
+		log.Err(err).Msg(err.Error())
+		return nil, err
+	}
+
+	return r.commitImageChange(ctx, tx, productID)
+}
+
+// DeleteProductImage removes an image from its product and returns it, so its blobs can be deleted.
+func (r *postgresRepository) DeleteProductImage(ctx context.Context, productID, imageID int) (*ProductImage, error) {
+	log := r.log.With().Str("method", "DeleteProductImage").Logger()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err = lockProduct(ctx, tx, productID); err != nil {
+		return nil, err
+	}
+
+	var deleted ProductImage
+	query := `DELETE FROM product_images WHERE id = $1 AND product_id = $2 RETURNING ` + imageColumns
+	err = tx.GetContext(ctx, &deleted, query, imageID, productID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotExist
+	} else if err != nil {
+		log.Err(err).Msg(err.Error())
+		return nil, err
+	}
+
+	if _, err = r.commitImageChange(ctx, tx, productID); err != nil {
+		return nil, err
+	}
+
+	return &deleted, nil
+}
+
+// commitImageChange mirrors the first image into the product's image_url, records
+// product.updated and commits tx. It returns the images of the product in order.
+func (r *postgresRepository) commitImageChange(ctx context.Context, tx *sqlx.Tx, productID int) ([]*ProductImage, error) {
+	query := `UPDATE products SET image_url = coalesce((
+			SELECT url FROM product_images WHERE product_id = $1 ORDER BY position, id LIMIT 1
+		), ''), updated_at = NOW()
+		WHERE id = $1
+	`
+	if _, err := tx.ExecContext(ctx, query, productID); err != nil {
+		return nil, err
+	}
+
+	if err := writeProductUpdated(ctx, tx, productID); err != nil {
+		r.log.Err(err).Str("method", "commitImageChange").Msg("failed to write outbox")
+		return nil, err
+	}
+
+	images, err := getProductImages(ctx, tx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return images, nil
+}
+
+func sameImageSet(images []*ProductImage, ids []int) bool {
+	if len(images) != len(ids) {
+		return false
+	}
+
+	want := make(map[int]bool, len(images))
+	for _, img := range images {
+		want[img.ID] = true
+	}
+
+	for _, id := range ids {
+		if !want[id] {
+			return false
+		}
+		delete(want, id)
+	}
+
+	return true
+}
@@ -0,0 +1,312 @@
+package product
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rovilay/ecommerce-service/common/events"
+	eventdatatypes "github.com/rovilay/ecommerce-service/common/events/datatypes"
+	"github.com/rovilay/ecommerce-service/common/money"
+)
+
+const priceColumns = `pp.id, pp.product_id, pp.price::text || ' ' || pp.currency AS price, pp.starts_at, pp.ends_at, pp.applied_at, pp.reverted_at, pp.created_at`
+
+// duePrice matches prices of product p that the scheduler has yet to activate or revert.
+const duePrice = `pp.product_id = p.id AND (
+		(pp.applied_at IS NULL AND pp.starts_at <= $1) OR
+		(pp.ends_at <= $1 AND pp.reverted_at IS NULL)
+	)`
+
+// GetProductPrices returns the price history of a product, with its scheduled prices, latest
+// start first.
+func (r *postgresRepository) GetProductPrices(ctx context.Context, productID int) ([]*ProductPrice, error) {
+	query := `SELECT ` + priceColumns + `
+		FROM product_prices pp
+		WHERE pp.product_id = $1
+		ORDER BY pp.starts_at DESC, pp.id DESC
+	`
+
+	prices := []*ProductPrice{}
+	err := r.db.SelectContext(ctx, &prices, query, productID)
+	if err != nil {
+		r.log.Err(err).Str("method", "GetProductPrices").Msg(err.Error())
+		return nil, err
+	}
+
+	return prices, nil
+}
+
+// SchedulePrice adds a price to a product. A price that starts by now takes effect at once.
+func (r *postgresRepository) SchedulePrice(ctx context.Context, productID int, p *ProductPrice, now time.Time) (*ProductPrice, error) {
+	log := r.log.With().Str("method", "SchedulePrice").Logger()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var currency string
+	query := `SELECT currency FROM products WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`
+	err = tx.GetContext(ctx, &currency, query, productID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotExist
+	} else if err != nil {
+		log.Err(err).Msg(err.Error())
+		return nil, err
+	}
+
+	if p.Price.Currency != currency {
+		return nil, ErrPriceCurrency
+	}
+
+	var id int
+	query = `INSERT INTO product_prices (product_id, price, currency, starts_at, ends_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`
+	err = tx.GetContext(ctx, &id, query, productID, p.Price, p.Price.Currency, p.StartsAt, p.EndsAt)
+	if err != nil {
+		log.Err(err).Msg(err.Error())
+		return nil, err
+	}
+
+	if err = applyDuePrices(ctx, tx, productID, now); err != nil {
+		log.Err(err).Msg(err.Error())
+		return nil, err
+	}
+
+	scheduled := &ProductPrice{}
+	query = `SELECT ` + priceColumns + ` FROM product_prices pp WHERE pp.id = $1`
+	if err = tx.GetContext(ctx, scheduled, query, id); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return scheduled, nil
+}
+
+// CancelPrice removes a scheduled price that has not taken effect yet.
+func (r *postgresRepository) CancelPrice(ctx context.Context, productID, priceID int) error {
+	log := r.log.With().Str("method", "CancelPrice").Logger()
+
+	var applied bool
+	query := `SELECT applied_at IS NOT NULL FROM product_prices WHERE id = $1 AND product_id = $2`
+	err := r.db.GetContext(ctx, &applied, query, priceID, productID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotExist
+	} else if err != nil {
+		log.Err(err).Msg(err.Error())
+		return err
+	}
+
+	if applied {
+		return ErrPriceStarted
+	}
+
+	// the scheduler may have applied it in the meantime
+	query = `DELETE FROM product_prices WHERE id = $1 AND product_id = $2 AND applied_at IS NULL`
+	res, err := r.db.ExecContext(ctx, query, priceID, productID)
+	if err != nil {
+		log.Err(err).Msg(err.Error())
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrPriceStarted
+	}
+
+	return nil
+}
+
+// ApplyScheduledPrices activates and reverts the prices that are due by now, for up to limit
+// products, and returns how many products it went through.
+func (r *postgresRepository) ApplyScheduledPrices(ctx context.Context, now time.Time, limit int) (int, error) {
+	log := r.log.With().Str("method", "ApplyScheduledPrices").Logger()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// prices of deleted products wait until they are restored
+	var productIDs []int
+	query := `SELECT p.id FROM products p
+		WHERE p.deleted_at IS NULL AND EXISTS (SELECT 1 FROM product_prices pp WHERE ` + duePrice + `)
+		ORDER BY p.id
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`
+	if err = tx.SelectContext(ctx, &productIDs, query, now, limit); err != nil {
+		log.Err(err).Msg(err.Error())
+		return 0, err
+	}
+
+	for _, id := range productIDs {
+		if err = applyDuePrices(ctx, tx, id, now); err != nil {
+			log.Err(err).Int("product_id", id).Msg(err.Error())
+			return 0, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return len(productIDs), nil
+}
+
+// effectivePrice is the price in effect for a product at a point in time.
+type effectivePrice struct {
+	ID      int         `db:"id"`
+	Price   money.Money `db:"price"`
+	Applied bool        `db:"applied"`
+}
+
+// getEffectivePrice returns the price in effect for a product at the given time: the running
+// sale that started last, or else the base price that started last. Prices in a currency the
+// product is no longer sold in are ignored. It returns nil when the product has no price history.
+func getEffectivePrice(ctx context.Context, q sqlx.QueryerContext, productID int, at time.Time) (*effectivePrice, error) {
+	query := `SELECT pp.id, pp.price::text || ' ' || pp.currency AS price, pp.applied_at IS NOT NULL AS applied
+		FROM product_prices pp
+		JOIN products p ON p.id = pp.product_id AND p.currency = pp.currency
+		WHERE pp.product_id = $1 AND pp.starts_at <= $2 AND (pp.ends_at IS NULL OR pp.ends_at > $2)
+		ORDER BY pp.ends_at IS NOT NULL DESC, pp.starts_at DESC, pp.id DESC
+		LIMIT 1
+	`
+
+	var eff effectivePrice
+	err := sqlx.GetContext(ctx, q, &eff, query, productID, at)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &eff, nil
+}
+
+// effectivePriceJoin joins the price in effect now, picked as getEffectivePrice picks it, to
+// the products aliased as p. Products without price history join no row.
+const effectivePriceJoin = `LEFT JOIN LATERAL (
+		SELECT pp.price FROM product_prices pp
+		WHERE pp.product_id = p.id AND pp.currency = p.currency
+			AND pp.starts_at <= now() AND (pp.ends_at IS NULL OR pp.ends_at > now())
+		ORDER BY pp.ends_at IS NOT NULL DESC, pp.starts_at DESC, pp.id DESC
+		LIMIT 1
+	) ep ON true`
+
+// effectivePriceColumn is what a product joined with effectivePriceJoin sells at. Until the
+// scheduler applies it, the stored price lags behind the schedule.
+const effectivePriceColumn = `coalesce(ep.price, p.price)`
+
+// applyDuePrices brings the price of a locked product up to date with its schedule. When the
+// price changes, it records product.price_changed.
+func applyDuePrices(ctx context.Context, tx *sqlx.Tx, productID int, now time.Time) error {
+	var current money.Money
+	query := `SELECT price::text || ' ' || currency FROM products WHERE id = $1`
+	if err := tx.GetContext(ctx, &current, query, productID); err != nil {
+		return err
+	}
+
+	eff, err := getEffectivePrice(ctx, tx, productID, now)
+	if err != nil {
+		return err
+	}
+
+	query = `UPDATE product_prices SET applied_at = $2
+		WHERE product_id = $1 AND applied_at IS NULL AND starts_at <= $2
+	`
+	if _, err = tx.ExecContext(ctx, query, productID, now); err != nil {
+		return err
+	}
+
+	query = `UPDATE product_prices SET reverted_at = $2
+		WHERE product_id = $1 AND ends_at <= $2 AND reverted_at IS NULL
+	`
+	if _, err = tx.ExecContext(ctx, query, productID, now); err != nil {
+		return err
+	}
+
+	if eff == nil || eff.Price == current {
+		return nil
+	}
+
+	query = `UPDATE products SET price = $2, updated_at = NOW() WHERE id = $1`
+	if _, err = tx.ExecContext(ctx, query, productID, eff.Price); err != nil {
+		return err
+	}
+
+	changed := eventdatatypes.ProductPriceChanged{
+		ProductID: productID,
+		PriceID:   eff.ID,
+		OldPrice:  current,
+		NewPrice:  eff.Price,
+		Reason:    eventdatatypes.PriceActivated,
+		ChangedAt: now,
+	}
+	// a price that was in effect before, e.g. the base price once a sale ends
+	if eff.Applied {
+		changed.Reason = eventdatatypes.PriceReverted
+	}
+
+	return events.WriteOutbox(ctx, tx, events.Product, events.ProductPriceChanged, changed)
+}
+
+// setBasePrice records a base price set directly on a product, unless it is already the base
+// price or the price the product sells at, and returns the price the product sells at, which
+// differs while a sale is running. Products are read at the price they sell at, so a product
+// written back as it was read during a sale carries the sale price, which must not become its
+// base price. The product row must be locked by tx and already hold the new price and currency.
+func setBasePrice(ctx context.Context, tx *sqlx.Tx, productID int, price money.Money, now time.Time) (money.Money, error) {
+	var base money.Money
+	query := `SELECT price::text || ' ' || currency FROM product_prices
+		WHERE product_id = $1 AND ends_at IS NULL AND applied_at IS NOT NULL
+		ORDER BY starts_at DESC, id DESC
+		LIMIT 1
+	`
+	err := tx.GetContext(ctx, &base, query, productID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return money.Money{}, err
+	}
+	hasBase := err == nil
+
+	selling, err := getEffectivePrice(ctx, tx, productID, now)
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	if !hasBase || (base != price && (selling == nil || selling.Price != price)) {
+		query = `INSERT INTO product_prices (product_id, price, currency, starts_at, applied_at)
+			VALUES ($1, $2, $3, $4, $4)
+		`
+		if _, err = tx.ExecContext(ctx, query, productID, price, price.Currency, now); err != nil {
+			return money.Money{}, err
+		}
+	}
+
+	eff, err := getEffectivePrice(ctx, tx, productID, now)
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	if eff == nil || eff.Price == price {
+		return price, nil
+	}
+
+	query = `UPDATE products SET price = $2 WHERE id = $1`
+	if _, err = tx.ExecContext(ctx, query, productID, eff.Price); err != nil {
+		return money.Money{}, err
+	}
+
+	return eff.Price, nil
+}
@@ -0,0 +1,151 @@
+package product
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid pagination cursor")
+var ErrInvalidSort = errors.New("invalid sort field")
+
+// queryBuilder collects the conditions and positional arguments of a query.
+type queryBuilder struct {
+	conds []string
+	args  []any
+}
+
+// bind adds an argument and returns its placeholder.
+func (b *queryBuilder) bind(v any) string {
+	b.args = append(b.args, v)
+	return fmt.Sprintf("$%d", len(b.args))
+}
+
+func (b *queryBuilder) where(cond string) {
+	b.conds = append(b.conds, cond)
+}
+
+func (b *queryBuilder) whereClause() string {
+	if len(b.conds) == 0 {
+		return ""
+	}
+
+	return "WHERE " + strings.Join(b.conds, " AND ")
+}
+
+// inStockCondition holds for products with a variant, or the product itself, whose active
+// inventory is not fully held by reservations.
+const inStockCondition = `EXISTS (
+	SELECT 1 FROM inventory_items i
+	WHERE i.product_id = p.id AND i.active AND i.quantity > (
+		SELECT coalesce(sum(ir.quantity), 0) FROM inventory_reservations ir
+		WHERE ir.product_id = i.product_id AND ir.variant_id = i.variant_id
+			AND ir.status = 'held' AND ir.expires_at > now()
+	)
+)`
+
+// applyProductFilter adds the conditions of f to b. Products are aliased as p and joined with
+// effectivePriceJoin, so prices are filtered at the price in effect now.
+func applyProductFilter(b *queryBuilder, f ProductFilter) {
+	b.where("p.deleted_at IS NULL")
+
+	if len(f.CategoryIDs) > 0 && f.IncludeDescendants {
+		b.where(`p.category_id IN (
+			WITH RECURSIVE subtree AS (
+				SELECT id FROM categories WHERE id = ANY(` + b.bind(f.CategoryIDs) + `)
+				UNION
+				SELECT c.id FROM categories c JOIN subtree s ON c.parent_category_id = s.id
+			)
+			SELECT id FROM subtree
+		)`)
+	} else if len(f.CategoryIDs) > 0 {
+		b.where("p.category_id = ANY(" + b.bind(f.CategoryIDs) + ")")
+	}
+
+	if f.MinPrice != nil {
+		b.where(effectivePriceColumn + " >= " + b.bind(f.MinPrice.Decimal()) + "::numeric")
+	}
+
+	if f.MaxPrice != nil {
+		b.where(effectivePriceColumn + " <= " + b.bind(f.MaxPrice.Decimal()) + "::numeric")
+	}
+
+	if f.Currency != "" {
+		b.where("p.currency = " + b.bind(f.Currency))
+	}
+
+	if f.SKUPrefix != "" {
+		b.where("p.sku LIKE " + b.bind(escapeLike(f.SKUPrefix)+"%"))
+	}
+
+	if f.InStock != nil {
+		if *f.InStock {
+			b.where(inStockCondition)
+		} else {
+			b.where("NOT " + inStockCondition)
+		}
+	}
+}
+
+// escapeLike escapes the LIKE wildcards in s.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+type sortColumn struct {
+	name string
+	cast string // type of the cursor value
+}
+
+var productSortColumns = map[ProductSort]sortColumn{
+	SortByCreatedAt: {name: "p.created_at", cast: "timestamptz"},
+	SortByPrice:     {name: effectivePriceColumn, cast: "numeric"},
+	SortByName:      {name: "p.name", cast: "text"},
+}
+
+// productCursor points at the last product of a page. It is tied to the sort it was made for.
+type productCursor struct {
+	Sort       ProductSort `json:"s"`
+	Descending bool        `json:"d,omitempty"`
+	Value      string      `json:"v"`
+	ID         int         `json:"id"`
+}
+
+func encodeProductCursor(sort ProductSort, descending bool, p *Product) (string, error) {
+	c := productCursor{Sort: sort, Descending: descending, ID: p.ID}
+
+	switch sort {
+	case SortByCreatedAt:
+		c.Value = p.CreatedAt.Format(time.RFC3339Nano)
+	case SortByPrice:
+		c.Value = p.Price.Decimal()
+	case SortByName:
+		c.Value = p.Name
+	default:
+		return "", ErrInvalidSort
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeProductCursor(cursor string) (*productCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	var c productCursor
+	if err = json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
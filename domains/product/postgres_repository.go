@@ -2,10 +2,14 @@ package product
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/rovilay/ecommerce-service/common/events"
+	eventdatatypes "github.com/rovilay/ecommerce-service/common/events/datatypes"
 	"github.com/rs/zerolog"
 )
 
@@ -15,6 +19,17 @@ type postgresRepository struct {
 }
 
 var ErrNotExist = errors.New("resource does not exist")
+var ErrInvalidParentCategory = errors.New("parent category does not exist")
+var ErrCategoryCycle = errors.New("category cannot be moved under itself or its subcategories")
+
+// productColumns selects a product; the price is read with its currency as "12.34 USD".
+const productColumns = `id, name, description, price::text || ' ' || currency AS price, sku, image_url, category_id, weight_grams,
+	created_at, updated_at`
+
+// listedProductColumns selects a product aliased as p and joined with effectivePriceJoin, at
+// the price in effect now.
+const listedProductColumns = `p.id, p.name, p.description, ` + effectivePriceColumn + `::text || ' ' || p.currency AS price,
+	p.sku, p.image_url, p.category_id, p.weight_grams, p.created_at, p.updated_at`
 
 func NewPostgresRepository(ctx context.Context, db *sqlx.DB, log zerolog.Logger) *postgresRepository {
 	logger := log.With().Str("repository", "postgresRepository").Logger()
@@ -29,7 +44,7 @@ func NewPostgresRepository(ctx context.Context, db *sqlx.DB, log zerolog.Logger)
 
 func (r *postgresRepository) GetProductByID(ctx context.Context, id int) (*Product, error) {
 	var product Product
-	query := `SELECT id, name, description, price, sku, image_url, category_id, created_at, updated_at
+	query := `SELECT ` + productColumns + `
 		FROM products
 		WHERE id = $1 AND deleted_at IS NULL
 	`
@@ -38,93 +53,291 @@ func (r *postgresRepository) GetProductByID(ctx context.Context, id int) (*Produ
 		r.log.Err(err).Str("method", "GetProductByID").Msg(err.Error())
 		return nil, ErrNotExist
 	}
+
+	// the stored price catches up with the schedule on the next scheduler run
+	eff, err := getEffectivePrice(ctx, r.db, id, time.Now())
+	if err != nil {
+		r.log.Err(err).Str("method", "GetProductByID").Msg(err.Error())
+		return nil, err
+	} else if eff != nil {
+		product.Price = eff.Price
+	}
+
+	product.Variants, err = r.GetProductVariants(ctx, id)
+	if err != nil {
+		r.log.Err(err).Str("method", "GetProductByID").Msg(err.Error())
+		return nil, err
+	}
+
+	product.Images, err = r.GetProductImages(ctx, id)
+	if err != nil {
+		r.log.Err(err).Str("method", "GetProductByID").Msg(err.Error())
+		return nil, err
+	}
+
 	return &product, nil
 }
 
+// GetProductsByIDs returns the products among ids, including deleted ones. Unknown ids are skipped.
+func (r *postgresRepository) GetProductsByIDs(ctx context.Context, ids []int) ([]*ProductSummary, error) {
+	query := `SELECT p.id, p.name, p.sku, ` + effectivePriceColumn + `::text || ' ' || p.currency AS price,
+			p.deleted_at IS NOT NULL AS deleted, p.category_id, p.weight_grams
+		FROM products p
+		` + effectivePriceJoin + `
+		WHERE p.id = ANY($1)
+		ORDER BY p.id
+	`
+
+	products := []*ProductSummary{}
+	err := r.db.SelectContext(ctx, &products, query, ids)
+	if err != nil {
+		r.log.Err(err).Str("method", "GetProductsByIDs").Msg(err.Error())
+		return nil, err
+	}
+
+	variants, err := r.getVariantSummaries(ctx, ids)
+	if err != nil {
+		r.log.Err(err).Str("method", "GetProductsByIDs").Msg(err.Error())
+		return nil, err
+	}
+
+	categoryIDs := make([]int, 0, len(products))
+	for _, p := range products {
+		categoryIDs = append(categoryIDs, p.CategoryID)
+	}
+
+	paths, err := r.getCategoryPaths(ctx, categoryIDs)
+	if err != nil {
+		r.log.Err(err).Str("method", "GetProductsByIDs").Msg(err.Error())
+		return nil, err
+	}
+
+	for _, p := range products {
+		p.Variants = variants[p.ID]
+		p.CategoryIDs = paths[p.CategoryID]
+	}
+
+	return products, nil
+}
+
+// getCategoryPaths maps each of ids to the category itself and all of its ancestors.
+func (r *postgresRepository) getCategoryPaths(ctx context.Context, ids []int) (map[int][]int, error) {
+	query := `
+		WITH RECURSIVE path AS (
+			SELECT id AS category_id, id, parent_category_id FROM categories WHERE id = ANY($1)
+			UNION ALL
+			SELECT p.category_id, c.id, c.parent_category_id
+			FROM categories c
+			JOIN path p ON c.id = p.parent_category_id
+		)
+		SELECT category_id, id FROM path
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	paths := make(map[int][]int, len(ids))
+	for rows.Next() {
+		var categoryID, id int
+		if err := rows.Scan(&categoryID, &id); err != nil {
+			return nil, err
+		}
+		paths[categoryID] = append(paths[categoryID], id)
+	}
+
+	return paths, rows.Err()
+}
+
 // gets product, deleted or not.
 func (r *postgresRepository) getProductByID(ctx context.Context, id int) (*Product, error) {
 	var product Product
-	query := `SELECT *
+	query := `SELECT ` + productColumns + `, deleted_at
 		FROM products
 		WHERE id = $1
 	`
 	err := r.db.GetContext(ctx, &product, query, id)
 	if err != nil {
-		r.log.Err(err).Str("method", "GetProductByID").Msg(err.Error())
+		r.log.Err(err).Str("method", "getProductByID").Msg(err.Error())
 		return nil, ErrNotExist
 	}
 	return &product, nil
 }
 
-func (r *postgresRepository) GetAllProducts(ctx context.Context, limit, offset int) ([]*Product, error) {
-	query := `
-        SELECT id, name, description, price, sku 
-        FROM products
-		WHERE deleted_at IS NULL
-        LIMIT $1 OFFSET $2
-    `
+// ListProducts returns a page of products for q and the cursor of the next page, which is
+// empty on the last page.
+func (r *postgresRepository) ListProducts(ctx context.Context, q ProductListQuery) ([]*Product, string, error) {
+	sortColumn, ok := productSortColumns[q.Sort]
+	if !ok {
+		return nil, "", ErrInvalidSort
+	}
 
-	var products []*Product
-	err := r.db.SelectContext(ctx, &products, query, limit, offset)
+	b := &queryBuilder{}
+	applyProductFilter(b, q.Filter)
+
+	direction, comparison := "ASC", ">"
+	if q.Descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	if q.Cursor != "" {
+		c, err := decodeProductCursor(q.Cursor)
+		if err != nil || c.Sort != q.Sort || c.Descending != q.Descending {
+			return nil, "", ErrInvalidCursor
+		}
+
+		// keyset: continue after the last product of the previous page
+		b.where(fmt.Sprintf("(%s, p.id) %s (%s::%s, %s)",
+			sortColumn.name, comparison, b.bind(c.Value), sortColumn.cast, b.bind(c.ID)))
+	}
+
+	query := `SELECT ` + listedProductColumns + `
+		FROM products p
+		` + effectivePriceJoin + `
+		` + b.whereClause() + `
+		ORDER BY ` + sortColumn.name + ` ` + direction + `, p.id ` + direction + `
+		LIMIT ` + b.bind(q.Limit+1)
+	if q.Cursor == "" && q.Offset > 0 {
+		query += ` OFFSET ` + b.bind(q.Offset)
+	}
+
+	products := []*Product{}
+	err := r.db.SelectContext(ctx, &products, query, b.args...)
 	if err != nil {
-		return nil, err
+		r.log.Err(err).Str("method", "ListProducts").Msg(err.Error())
+		return nil, "", err
 	}
 
-	return products, nil
+	// one extra row is fetched to know whether there is a next page
+	if len(products) <= q.Limit {
+		return products, "", nil
+	}
+
+	products = products[:q.Limit]
+	next, err := encodeProductCursor(q.Sort, q.Descending, products[len(products)-1])
+	if err != nil {
+		return nil, "", err
+	}
+
+	return products, next, nil
 }
 
 func (r *postgresRepository) CreateProduct(ctx context.Context, p *Product) (*Product, error) {
 	query := `
-        INSERT INTO products (name, description, price, sku, image_url, category_id) 
-        VALUES ($1, $2, $3, $4, $5, $6) 
-        RETURNING id, name, description, price, sku, image_url, category_id, created_at, updated_at
-    `
+        INSERT INTO products (name, description, price, currency, sku, image_url, category_id, weight_grams)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        RETURNING ` + productColumns
 
-	err := r.db.QueryRowContext(
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(
 		ctx,
 		query,
 		p.Name,
 		p.Description,
 		p.Price,
+		p.Price.Currency,
 		p.SKU,
 		p.ImageURL,
 		p.CategoryID,
+		p.WeightGrams,
 	).Scan(
-		&p.ID, &p.Name, &p.Description, &p.Price, &p.SKU, &p.ImageURL, &p.CategoryID, &p.CreatedAt, &p.UpdatedAt,
+		&p.ID, &p.Name, &p.Description, &p.Price, &p.SKU, &p.ImageURL, &p.CategoryID, &p.WeightGrams, &p.CreatedAt, &p.UpdatedAt,
 	)
 	if err != nil {
 		return nil, err
 	}
 
+	// the first price starts the product's price history
+	if _, err = setBasePrice(ctx, tx, p.ID, p.Price, time.Now()); err != nil {
+		r.log.Err(err).Str("method", "CreateProduct").Msg(err.Error())
+		return nil, err
+	}
+
+	for i, v := range p.Variants {
+		p.Variants[i], err = insertVariant(ctx, tx, p.ID, v)
+		if err != nil {
+			r.log.Err(err).Str("method", "CreateProduct").Msg(err.Error())
+			return nil, err
+		}
+	}
+
+	// the event is committed with the product and published by the outbox relay
+	err = events.WriteOutbox(ctx, tx, events.Product, events.ProductCreated, p)
+	if err != nil {
+		r.log.Err(err).Str("method", "CreateProduct").Msg("failed to write outbox")
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
 	return p, nil
 }
 
 func (r *postgresRepository) UpdateProduct(ctx context.Context, p *Product) (*Product, error) {
 	query := `
         UPDATE products 
-        SET name = $1, description = $2, price = $3, sku = $4, image_url = $5, category_id = $6, updated_at = NOW()
-        WHERE id = $7 AND deleted_at IS NULL
-    `
+        SET name = $1, description = $2, price = $3, currency = $4, sku = $5, image_url = $6, category_id = $7, weight_grams = $8,
+            updated_at = NOW()
+        WHERE id = $9 AND deleted_at IS NULL
+        RETURNING ` + productColumns
 
-	_, err := r.db.ExecContext(ctx, query, p.Name, p.Description, p.Price, p.SKU, p.ImageURL, p.CategoryID, p.ID)
+	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback()
 
-	up, err := r.GetProductByID(ctx, p.ID)
+	var up Product
+	err = tx.GetContext(ctx, &up, query, p.Name, p.Description, p.Price, p.Price.Currency, p.SKU, p.ImageURL, p.CategoryID, p.WeightGrams, p.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotExist
+	} else if err != nil {
+		r.log.Err(err).Str("method", "UpdateProduct").Msg(err.Error())
+		return nil, err
+	}
+
+	// a running sale keeps its price; the new price applies once it ends
+	up.Price, err = setBasePrice(ctx, tx, up.ID, up.Price, time.Now())
 	if err != nil {
 		r.log.Err(err).Str("method", "UpdateProduct").Msg(err.Error())
-		return nil, ErrNotExist
+		return nil, err
+	}
+
+	// the event carries the variants so consumers keep their per-variant data
+	up.Variants, err = getProductVariants(ctx, tx, up.ID)
+	if err != nil {
+		r.log.Err(err).Str("method", "UpdateProduct").Msg(err.Error())
+		return nil, err
+	}
+
+	err = events.WriteOutbox(ctx, tx, events.Product, events.ProductUpdated, up)
+	if err != nil {
+		r.log.Err(err).Str("method", "UpdateProduct").Msg("failed to write outbox")
+		return nil, err
 	}
 
-	return up, nil
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &up, nil
 }
 
 func (r *postgresRepository) GetProductsByCategory(ctx context.Context, categoryID int) ([]*Product, error) {
 	query := `
-        SELECT id, name, description, price, sku, image_url, category_id, created_at, updated_at
-        FROM products
-        WHERE category_id = $1 AND deleted_at IS NULL
+        SELECT ` + listedProductColumns + `
+        FROM products p
+        ` + effectivePriceJoin + `
+        WHERE p.category_id = $1 AND p.deleted_at IS NULL
     `
 	var products []*Product
 	err := r.db.SelectContext(ctx, &products, query, categoryID)
@@ -136,40 +349,44 @@ func (r *postgresRepository) GetProductsByCategory(ctx context.Context, category
 }
 
 func (r *postgresRepository) DeleteProduct(ctx context.Context, id int) error {
-	query := `UPDATE products SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL`
-	_, err := r.db.ExecContext(ctx, query, id)
-	if err != nil {
-		return err
-	}
+	query := `UPDATE products SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL RETURNING deleted_at`
 
-	p, err := r.getProductByID(ctx, id)
+	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
-		r.log.Err(err).Str("method", "DeleteProduct").Msg(err.Error())
 		return err
 	}
+	defer tx.Rollback()
 
-	if p == nil {
+	deleted := eventdatatypes.ProductDeleted{ID: id}
+	err = tx.QueryRowContext(ctx, query, id).Scan(&deleted.DeletedAt)
+	if errors.Is(err, sql.ErrNoRows) {
 		return ErrNotExist
+	} else if err != nil {
+		r.log.Err(err).Str("method", "DeleteProduct").Msg(err.Error())
+		return err
 	}
 
-	r.log.Info().Msgf("My struct: %v", p)
-
-	if p.DeletedAt == "" {
-		return errors.New("delete failed")
+	err = events.WriteOutbox(ctx, tx, events.Product, events.ProductDeleted, deleted)
+	if err != nil {
+		r.log.Err(err).Str("method", "DeleteProduct").Msg("failed to write outbox")
+		return err
 	}
 
-	return nil
+	return tx.Commit()
 }
 
-func (r *postgresRepository) CountProducts(ctx context.Context) (int, error) {
+func (r *postgresRepository) CountProducts(ctx context.Context, filter ProductFilter) (int, error) {
+	b := &queryBuilder{}
+	applyProductFilter(b, filter)
+
 	query := `
 		SELECT count(*) as total_products
-		FROM products
-        WHERE deleted_at IS NULL;
-	`
+		FROM products p
+		` + effectivePriceJoin + `
+		` + b.whereClause()
 
 	var count int
-	err := r.db.GetContext(ctx, &count, query)
+	err := r.db.GetContext(ctx, &count, query, b.args...)
 	if err != nil {
 		return 0, err
 	}
@@ -177,25 +394,89 @@ func (r *postgresRepository) CountProducts(ctx context.Context) (int, error) {
 	return count, nil
 }
 
-func (r *postgresRepository) SearchProductsByName(ctx context.Context, searchTerm string) ([]*Product, error) {
-	query := `SELECT id, name, description, price, sku, image_url, category_id, created_at, updated_at
-		FROM products
-		WHERE name ILIKE $1 AND deleted_at IS NULL
+// searchQuery is the full-text query of a search term; websearch syntax ("quoted phrases",
+// or, -exclusions) is supported.
+const searchQuery = `websearch_to_tsquery('english', $1)`
+
+// headlineOptions wrap matched terms in <mark> tags.
+const headlineOptions = `'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=20, MinWords=5'`
+
+func (r *postgresRepository) SearchProducts(ctx context.Context, term string, limit, offset int) ([]*ProductSearchHit, int, error) {
+	log := r.log.With().Str("method", "SearchProducts").Logger()
+
+	var total int
+	query := `SELECT count(*) FROM products WHERE deleted_at IS NULL AND search_vector @@ ` + searchQuery
+	if err := r.db.GetContext(ctx, &total, query, term); err != nil {
+		log.Err(err).Msg(err.Error())
+		return nil, 0, err
+	}
+
+	hits := []*ProductSearchHit{}
+	if total == 0 || offset >= total {
+		return hits, total, nil
+	}
+
+	query = `SELECT ` + listedProductColumns + `,
+			ts_rank_cd(p.search_vector, query) AS rank,
+			ts_headline('english', p.name, query, 'StartSel=<mark>, StopSel=</mark>, HighlightAll=true') AS highlight,
+			ts_headline('english', coalesce(p.description, ''), query, ` + headlineOptions + `) AS snippet
+		FROM products p
+		CROSS JOIN ` + searchQuery + ` query
+		` + effectivePriceJoin + `
+		WHERE p.deleted_at IS NULL AND p.search_vector @@ query
+		ORDER BY rank DESC, p.id
+		LIMIT $2 OFFSET $3
 	`
-	searchTerm = "%" + searchTerm + "%"
+	if err := r.db.SelectContext(ctx, &hits, query, term, limit, offset); err != nil {
+		log.Err(err).Msg(err.Error())
+		return nil, 0, err
+	}
 
-	var products []*Product
-	err := r.db.SelectContext(ctx, &products, query, searchTerm)
-	if err != nil {
-		return nil, err
+	return hits, total, nil
+}
+
+// FuzzySearchProducts matches product names that are similar to term, so misspelt searches
+// still find something. Hits are ranked by word similarity.
+func (r *postgresRepository) FuzzySearchProducts(ctx context.Context, term string, limit, offset int) ([]*ProductSearchHit, int, error) {
+	log := r.log.With().Str("method", "FuzzySearchProducts").Logger()
+
+	var total int
+	query := `SELECT count(*) FROM products WHERE deleted_at IS NULL AND $1 <% name`
+	if err := r.db.GetContext(ctx, &total, query, term); err != nil {
+		log.Err(err).Msg(err.Error())
+		return nil, 0, err
 	}
 
-	return products, nil
+	hits := []*ProductSearchHit{}
+	if total == 0 || offset >= total {
+		return hits, total, nil
+	}
+
+	query = `SELECT ` + listedProductColumns + `,
+			word_similarity($1, p.name) AS rank,
+			p.name AS highlight,
+			left(coalesce(p.description, ''), 200) AS snippet
+		FROM products p
+		` + effectivePriceJoin + `
+		WHERE p.deleted_at IS NULL AND $1 <% p.name
+		ORDER BY rank DESC, p.id
+		LIMIT $2 OFFSET $3
+	`
+	if err := r.db.SelectContext(ctx, &hits, query, term, limit, offset); err != nil {
+		log.Err(err).Msg(err.Error())
+		return nil, 0, err
+	}
+
+	for _, hit := range hits {
+		hit.Fuzzy = true
+	}
+
+	return hits, total, nil
 }
 
 func (r *postgresRepository) GetCategoryByID(ctx context.Context, id int) (*Category, error) {
 	c := &Category{}
-	query := `SELECT id, name, created_at, updated_at
+	query := `SELECT id, name, parent_category_id, created_at, updated_at
 		FROM categories
 		WHERE id = $1
 	`
@@ -209,8 +490,9 @@ func (r *postgresRepository) GetCategoryByID(ctx context.Context, id int) (*Cate
 
 func (r *postgresRepository) GetAllCategories(ctx context.Context, limit, offset int) ([]*Category, error) {
 	query := `
-        SELECT id, name, created_at, updated_at
+        SELECT id, name, parent_category_id, created_at, updated_at
         FROM categories
+        ORDER BY id
         LIMIT $1 OFFSET $2
     `
 
@@ -223,28 +505,177 @@ func (r *postgresRepository) GetAllCategories(ctx context.Context, limit, offset
 	return ctgry, nil
 }
 
-func (r *postgresRepository) CreateCategory(ctx context.Context, name string) (*Category, error) {
+func (r *postgresRepository) CreateCategory(ctx context.Context, data *Category) (*Category, error) {
+	log := r.log.With().Str("method", "CreateCategory").Logger()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if data.ParentCategoryID != nil {
+		if err = lockCategory(ctx, tx, *data.ParentCategoryID); errors.Is(err, ErrNotExist) {
+			return nil, ErrInvalidParentCategory
+		} else if err != nil {
+			log.Err(err).Msg(err.Error())
+			return nil, err
+		}
+	}
+
 	c := &Category{}
 	query := `
-        INSERT INTO categories (name) 
-        VALUES ($1) 
-        RETURNING id, name, created_at, updated_at
+        INSERT INTO categories (name, parent_category_id)
+        VALUES ($1, $2)
+        RETURNING id, name, parent_category_id, created_at, updated_at
     `
+	err = tx.GetContext(ctx, c, query, data.Name, data.ParentCategoryID)
+	if err != nil {
+		log.Err(err).Msg(err.Error())
+		return nil, err
+	}
 
-	err := r.db.QueryRowContext(
-		ctx,
-		query,
-		name,
-	).Scan(
-		&c.ID, &c.Name, &c.CreatedAt, &c.UpdatedAt,
-	)
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// lockCategory locks a category row so it cannot be deleted while it is being referenced.
+func lockCategory(ctx context.Context, tx *sqlx.Tx, id int) error {
+	var found int
+	err := tx.GetContext(ctx, &found, `SELECT id FROM categories WHERE id = $1 FOR SHARE`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotExist
+	}
+
+	return err
+}
+
+func (r *postgresRepository) MoveCategory(ctx context.Context, id int, parentID *int) (*Category, error) {
+	log := r.log.With().Str("method", "MoveCategory").Logger()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback()
+
+	// moves are serialised, otherwise two concurrent moves could each pass the cycle check
+	// and together form a cycle
+	if _, err = tx.ExecContext(ctx, `LOCK TABLE categories IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+		log.Err(err).Msg(err.Error())
+		return nil, err
+	}
+
+	if parentID != nil {
+		var inSubtree bool
+		query := `
+			WITH RECURSIVE subtree AS (
+				SELECT id FROM categories WHERE id = $1
+				UNION
+				SELECT c.id FROM categories c JOIN subtree s ON c.parent_category_id = s.id
+			)
+			SELECT EXISTS (SELECT 1 FROM subtree WHERE id = $2)
+		`
+		if err = tx.GetContext(ctx, &inSubtree, query, id, *parentID); err != nil {
+			log.Err(err).Msg(err.Error())
+			return nil, err
+		}
+
+		if inSubtree {
+			return nil, ErrCategoryCycle
+		}
+
+		if err = lockCategory(ctx, tx, *parentID); errors.Is(err, ErrNotExist) {
+			return nil, ErrInvalidParentCategory
+		} else if err != nil {
+			log.Err(err).Msg(err.Error())
+			return nil, err
+		}
+	}
+
+	c := &Category{}
+	query := `
+		UPDATE categories SET parent_category_id = $1, updated_at = NOW()
+		WHERE id = $2
+		RETURNING id, name, parent_category_id, created_at, updated_at
+	`
+	err = tx.GetContext(ctx, c, query, parentID, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotExist
+	} else if err != nil {
+		log.Err(err).Msg(err.Error())
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
 
 	return c, nil
 }
 
+// GetCategoryBreadcrumbs returns the ancestors of a category, root first, ending with the category.
+func (r *postgresRepository) GetCategoryBreadcrumbs(ctx context.Context, id int) ([]*CategoryRef, error) {
+	query := `
+		WITH RECURSIVE ancestors AS (
+			SELECT id, name, parent_category_id, 0 AS depth FROM categories WHERE id = $1
+			UNION ALL
+			SELECT c.id, c.name, c.parent_category_id, a.depth + 1
+			FROM categories c JOIN ancestors a ON c.id = a.parent_category_id
+		)
+		SELECT id, name FROM ancestors ORDER BY depth DESC
+	`
+
+	crumbs := []*CategoryRef{}
+	err := r.db.SelectContext(ctx, &crumbs, query, id)
+	if err != nil {
+		r.log.Err(err).Str("method", "GetCategoryBreadcrumbs").Msg(err.Error())
+		return nil, err
+	}
+
+	return crumbs, nil
+}
+
+// GetCategoryTree loads every category reachable from a root and nests them by parent.
+func (r *postgresRepository) GetCategoryTree(ctx context.Context) ([]*Category, error) {
+	query := `
+		WITH RECURSIVE tree AS (
+			SELECT id, name, parent_category_id, created_at, updated_at, ARRAY[name::text] AS path
+			FROM categories
+			WHERE parent_category_id IS NULL
+			UNION ALL
+			SELECT c.id, c.name, c.parent_category_id, c.created_at, c.updated_at, t.path || c.name::text
+			FROM categories c JOIN tree t ON c.parent_category_id = t.id
+		)
+		SELECT id, name, parent_category_id, created_at, updated_at FROM tree ORDER BY path
+	`
+
+	var categories []*Category
+	err := r.db.SelectContext(ctx, &categories, query)
+	if err != nil {
+		r.log.Err(err).Str("method", "GetCategoryTree").Msg(err.Error())
+		return nil, err
+	}
+
+	// rows come in path order, so a parent is always seen before its children
+	roots := []*Category{}
+	byID := make(map[int]*Category, len(categories))
+	for _, c := range categories {
+		byID[c.ID] = c
+
+		if c.ParentCategoryID == nil {
+			roots = append(roots, c)
+		} else if parent, ok := byID[*c.ParentCategoryID]; ok {
+			parent.Children = append(parent.Children, c)
+		}
+	}
+
+	return roots, nil
+}
+
 func (r *postgresRepository) UpdateCategory(ctx context.Context, id int, name string) (*Category, error) {
 	query := `
         UPDATE categories 
@@ -271,7 +702,7 @@ func (r *postgresRepository) UpdateCategory(ctx context.Context, id int, name st
 }
 
 func (r *postgresRepository) SearchCategoriesByName(ctx context.Context, searchTerm string) ([]*Category, error) {
-	query := `SELECT *
+	query := `SELECT id, name, parent_category_id, created_at, updated_at
 		FROM categories
 		WHERE name ILIKE $1
 	`
@@ -0,0 +1,170 @@
+package product
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/rovilay/ecommerce-service/common/events"
+)
+
+var ErrNotDeleted = errors.New("product is not deleted")
+
+// PurgeResult lists the products a purge removed, with their images so the image blobs can
+// be deleted too.
+type PurgeResult struct {
+	ProductIDs []int
+	Images     []*ProductImage
+}
+
+// ListDeletedProducts returns a page of soft-deleted products, most recently deleted first,
+// and how many there are in total.
+func (r *postgresRepository) ListDeletedProducts(ctx context.Context, limit, offset int) ([]*Product, int, error) {
+	log := r.log.With().Str("method", "ListDeletedProducts").Logger()
+
+	var total int
+	query := `SELECT count(*) FROM products WHERE deleted_at IS NOT NULL`
+	if err := r.db.GetContext(ctx, &total, query); err != nil {
+		log.Err(err).Msg(err.Error())
+		return nil, 0, err
+	}
+
+	products := []*Product{}
+	if offset >= total {
+		return products, total, nil
+	}
+
+	query = `SELECT ` + productColumns + `, deleted_at
+		FROM products
+		WHERE deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC, id DESC
+		LIMIT $1 OFFSET $2
+	`
+	if err := r.db.SelectContext(ctx, &products, query, limit, offset); err != nil {
+		log.Err(err).Msg(err.Error())
+		return nil, 0, err
+	}
+
+	return products, total, nil
+}
+
+// RestoreProduct clears deleted_at and records product.updated, which makes the inventory
+// service track the product's stock again. Its variants and images come back with it.
+func (r *postgresRepository) RestoreProduct(ctx context.Context, id int) (*Product, error) {
+	log := r.log.With().Str("method", "RestoreProduct").Logger()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var p Product
+	query := `UPDATE products SET deleted_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NOT NULL
+		RETURNING ` + productColumns
+	err = tx.GetContext(ctx, &p, query, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		// either there is no such product or it is live
+		if _, err = r.getProductByID(ctx, id); err != nil {
+			return nil, err
+		}
+		return nil, ErrNotDeleted
+	} else if err != nil {
+		log.Err(err).Msg(err.Error())
+		return nil, err
+	}
+
+	p.Variants, err = getProductVariants(ctx, tx, id)
+	if err != nil {
+		log.Err(err).Msg(err.Error())
+		return nil, err
+	}
+
+	if err = events.WriteOutbox(ctx, tx, events.Product, events.ProductUpdated, p); err != nil {
+		log.Err(err).Msg("failed to write outbox")
+		return nil, err
+	}
+
+	p.Images, err = getProductImages(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+// PurgeProducts hard-deletes up to limit products that were soft-deleted before the given
+// time. Variants, images, inventory and cart items go with them. Products that are still
+// referenced by an order item or held by a stock reservation are kept, since order history
+// must survive and an in-flight checkout may still commit.
+func (r *postgresRepository) PurgeProducts(ctx context.Context, deletedBefore time.Time, limit int) (*PurgeResult, error) {
+	log := r.log.With().Str("method", "PurgeProducts").Logger()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	const purgeable = `
+		NOT EXISTS (SELECT 1 FROM order_items oi WHERE oi.product_id = p.id)
+		AND NOT EXISTS (
+			SELECT 1 FROM inventory_reservations ir WHERE ir.product_id = p.id AND ir.status = 'held'
+		)
+	`
+
+	// locking the products stops new order items from referencing them until the purge is done
+	var candidates []int
+	query := `SELECT id FROM products p
+		WHERE deleted_at < $1 AND ` + purgeable + `
+		ORDER BY deleted_at, id
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`
+	if err = tx.SelectContext(ctx, &candidates, query, deletedBefore, limit); err != nil {
+		log.Err(err).Msg(err.Error())
+		return nil, err
+	}
+
+	result := &PurgeResult{ProductIDs: []int{}}
+	if len(candidates) == 0 {
+		return result, nil
+	}
+
+	// images are read first, the delete cascades to them
+	var images []*ProductImage
+	query = `SELECT ` + imageColumns + ` FROM product_images WHERE product_id = ANY($1)`
+	if err = tx.SelectContext(ctx, &images, query, candidates); err != nil {
+		log.Err(err).Msg(err.Error())
+		return nil, err
+	}
+
+	// references are checked again, as they may have been added before the rows were locked
+	query = `DELETE FROM products p WHERE id = ANY($1) AND ` + purgeable + ` RETURNING id`
+	if err = tx.SelectContext(ctx, &result.ProductIDs, query, candidates); err != nil {
+		log.Err(err).Msg(err.Error())
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	purged := make(map[int]bool, len(result.ProductIDs))
+	for _, id := range result.ProductIDs {
+		purged[id] = true
+	}
+	for _, img := range images {
+		if purged[img.ProductID] {
+			result.Images = append(result.Images, img)
+		}
+	}
+
+	return result, nil
+}
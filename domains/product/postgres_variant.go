@@ -0,0 +1,241 @@
+package product
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jmoiron/sqlx"
+	"github.com/rovilay/ecommerce-service/common/events"
+)
+
+// variantColumns selects a variant of a product aliased as p; the price override is read in
+// the product currency and is null when the variant sells at the product price.
+const variantColumns = `v.id, v.product_id, v.sku, v.options, v.price::text || ' ' || p.currency AS price, v.image_urls, v.created_at, v.updated_at`
+
+func (r *postgresRepository) GetProductVariants(ctx context.Context, productID int) ([]*Variant, error) {
+	return getProductVariants(ctx, r.db, productID)
+}
+
+func getProductVariants(ctx context.Context, q sqlx.QueryerContext, productID int) ([]*Variant, error) {
+	query := `SELECT ` + variantColumns + `
+		FROM product_variants v
+		JOIN products p ON p.id = v.product_id
+		WHERE v.product_id = $1 AND v.deleted_at IS NULL
+		ORDER BY v.id
+	`
+
+	variants := []*Variant{}
+	err := sqlx.SelectContext(ctx, q, &variants, query, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	return variants, nil
+}
+
+// getVariantSummaries returns the variants of the given products, deleted or not, keyed by product ID.
+func (r *postgresRepository) getVariantSummaries(ctx context.Context, productIDs []int) (map[int][]*VariantSummary, error) {
+	query := `SELECT v.product_id, v.id, v.sku,
+			coalesce(v.price, ` + effectivePriceColumn + `)::text || ' ' || p.currency AS price,
+			v.deleted_at IS NOT NULL AS deleted
+		FROM product_variants v
+		JOIN products p ON p.id = v.product_id
+		` + effectivePriceJoin + `
+		WHERE v.product_id = ANY($1)
+		ORDER BY v.id
+	`
+
+	var rows []struct {
+		ProductID int `db:"product_id"`
+		VariantSummary
+	}
+	err := r.db.SelectContext(ctx, &rows, query, productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	variants := make(map[int][]*VariantSummary, len(productIDs))
+	for i := range rows {
+		variants[rows[i].ProductID] = append(variants[rows[i].ProductID], &rows[i].VariantSummary)
+	}
+
+	return variants, nil
+}
+
+func insertVariant(ctx context.Context, tx *sqlx.Tx, productID int, v *Variant) (*Variant, error) {
+	var price any
+	if v.Price != nil {
+		price = *v.Price
+	}
+
+	query := `
+		WITH v AS (
+			INSERT INTO product_variants (product_id, sku, options, price, image_urls)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING *
+		)
+		SELECT ` + variantColumns + ` FROM v JOIN products p ON p.id = v.product_id
+	`
+
+	var created Variant
+	err := tx.GetContext(ctx, &created, query, productID, v.SKU, v.Options, price, v.ImageURLs)
+	if isUniqueViolation(err) {
+		return nil, ErrDuplicateVariant
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+func (r *postgresRepository) CreateVariant(ctx context.Context, productID int, v *Variant) (*Variant, error) {
+	log := r.log.With().Str("method", "CreateVariant").Logger()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err = lockProduct(ctx, tx, productID); err != nil {
+		return nil, err
+	}
+
+	created, err := insertVariant(ctx, tx, productID, v)
+	if err != nil {
+		log.Err(err).Msg(err.Error())
+		return nil, err
+	}
+
+	if err = writeProductUpdated(ctx, tx, productID); err != nil {
+		log.Err(err).Msg("failed to write outbox")
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+func (r *postgresRepository) UpdateVariant(ctx context.Context, v *Variant) (*Variant, error) {
+	log := r.log.With().Str("method", "UpdateVariant").Logger()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err = lockProduct(ctx, tx, v.ProductID); err != nil {
+		return nil, err
+	}
+
+	var price any
+	if v.Price != nil {
+		price = *v.Price
+	}
+
+	query := `
+		WITH v AS (
+			UPDATE product_variants
+			SET sku = $1, options = $2, price = $3, image_urls = $4, updated_at = NOW()
+			WHERE id = $5 AND product_id = $6 AND deleted_at IS NULL
+			RETURNING *
+		)
+		SELECT ` + variantColumns + ` FROM v JOIN products p ON p.id = v.product_id
+	`
+
+	var updated Variant
+	err = tx.GetContext(ctx, &updated, query, v.SKU, v.Options, price, v.ImageURLs, v.ID, v.ProductID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotExist
+	} else if isUniqueViolation(err) {
+		return nil, ErrDuplicateVariant
+	} else if err != nil {
+		log.Err(err).Msg(err.Error())
+		return nil, err
+	}
+
+	if err = writeProductUpdated(ctx, tx, v.ProductID); err != nil {
+		log.Err(err).Msg("failed to write outbox")
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+func (r *postgresRepository) DeleteVariant(ctx context.Context, productID, variantID int) error {
+	log := r.log.With().Str("method", "DeleteVariant").Logger()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = lockProduct(ctx, tx, productID); err != nil {
+		return err
+	}
+
+	query := `UPDATE product_variants SET deleted_at = NOW()
+		WHERE id = $1 AND product_id = $2 AND deleted_at IS NULL
+	`
+	result, err := tx.ExecContext(ctx, query, variantID, productID)
+	if err != nil {
+		log.Err(err).Msg(err.Error())
+		return err
+	}
+
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return ErrNotExist
+	}
+
+	if err = writeProductUpdated(ctx, tx, productID); err != nil {
+		log.Err(err).Msg("failed to write outbox")
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// lockProduct locks a live product so its variants change one transaction at a time.
+func lockProduct(ctx context.Context, tx *sqlx.Tx, id int) error {
+	var found int
+	err := tx.GetContext(ctx, &found, `SELECT id FROM products WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotExist
+	}
+
+	return err
+}
+
+// writeProductUpdated records product.updated with the product's current variants, so
+// consumers can keep per-variant data such as stock in sync.
+func writeProductUpdated(ctx context.Context, tx *sqlx.Tx, productID int) error {
+	var p Product
+	query := `SELECT ` + productColumns + ` FROM products WHERE id = $1`
+	if err := tx.GetContext(ctx, &p, query, productID); err != nil {
+		return err
+	}
+
+	variants, err := getProductVariants(ctx, tx, productID)
+	if err != nil {
+		return err
+	}
+	p.Variants = variants
+
+	return events.WriteOutbox(ctx, tx, events.Product, events.ProductUpdated, p)
+}
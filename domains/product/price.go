@@ -0,0 +1,61 @@
+package product
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/rovilay/ecommerce-service/common/money"
+)
+
+var ErrPriceCurrency = errors.New("price must be in the product currency")
+var ErrPriceInPast = errors.New("price cannot start in the past")
+var ErrInvalidPriceWindow = errors.New("ends_at must be after starts_at")
+var ErrPriceStarted = errors.New("price has already taken effect and cannot be cancelled")
+
+// ProductPrice is a price a product had, has or is scheduled to have. A price without EndsAt
+// replaces the product's base price from StartsAt on. A price with EndsAt is a sale: it
+// takes precedence while it runs, after which the base price applies again.
+//
+// AppliedAt and RevertedAt record when the scheduler put the price into effect and when it
+// took a sale off again.
+type ProductPrice struct {
+	ID         int         `json:"id" db:"id"`
+	ProductID  int         `json:"product_id" db:"product_id"`
+	Price      money.Money `json:"price" db:"price"`
+	StartsAt   time.Time   `json:"starts_at" db:"starts_at"`
+	EndsAt     *time.Time  `json:"ends_at,omitempty" db:"ends_at"`
+	AppliedAt  *time.Time  `json:"applied_at,omitempty" db:"applied_at"`
+	RevertedAt *time.Time  `json:"reverted_at,omitempty" db:"reverted_at"`
+	CreatedAt  time.Time   `json:"created_at" db:"created_at"`
+}
+
+func (p *ProductPrice) FromJSON(r io.Reader) error {
+	return json.NewDecoder(r).Decode(p)
+}
+
+func (p *ProductPrice) ToJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(p)
+}
+
+func (p *ProductPrice) Validate() error {
+	if !p.Price.IsPositive() {
+		return errors.New("price must be greater than 0")
+	}
+
+	if err := p.Price.Validate(); err != nil {
+		return err
+	}
+
+	if p.EndsAt != nil && !p.StartsAt.IsZero() && !p.EndsAt.After(p.StartsAt) {
+		return ErrInvalidPriceWindow
+	}
+
+	return nil
+}
+
+// IsSale reports whether the price is temporary.
+func (p *ProductPrice) IsSale() bool {
+	return p.EndsAt != nil
+}
@@ -4,51 +4,164 @@ import (
 	"context"
 	"encoding/json"
 	"io"
+	"reflect"
 	"time"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/rovilay/ecommerce-service/common/money"
 )
 
 type Product struct {
-	ID          int       `json:"id"`
-	Name        string    `json:"name" validate:"required"`
-	Description string    `json:"description"`
-	Price       float32   `json:"price" db:"price" validate:"gt=0"`
-	SKU         string    `json:"sku" db:"sku" validate:"required,len=5"`
-	ImageURL    string    `json:"image_url" db:"image_url"`
-	CategoryID  int       `json:"category_id" db:"category_id" validate:"required"`
-	CreatedAt   time.Time `json:"created_at,omitempty" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at,omitempty" db:"updated_at"`
-	DeletedAt   string    `json:"deleted_at,omitempty" db:"deleted_at"`
+	ID          int         `json:"id"`
+	Name        string      `json:"name" validate:"required"`
+	Description string      `json:"description"`
+	Price       money.Money `json:"price" db:"price" validate:"gt=0"`
+	SKU         string      `json:"sku" db:"sku" validate:"required,len=5"`
+	ImageURL    string      `json:"image_url" db:"image_url"`
+	CategoryID  int         `json:"category_id" db:"category_id" validate:"required"`
+	WeightGrams int         `json:"weight_grams" db:"weight_grams" validate:"min=0"`
+	CreatedAt   time.Time   `json:"created_at,omitempty" db:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at,omitempty" db:"updated_at"`
+	DeletedAt   *time.Time  `json:"deleted_at,omitempty" db:"deleted_at"`
+
+	Variants []*Variant `json:"variants,omitempty" db:"-" validate:"dive"`
+	// Images are set by GetProduct; ImageURL mirrors the first of them.
+	Images []*ProductImage `json:"images,omitempty" db:"-"`
+}
+
+// ProductSummary is what the batch lookup returns for a product, deleted or not.
+type ProductSummary struct {
+	ID      int         `json:"id" db:"id"`
+	Name    string      `json:"name" db:"name"`
+	SKU     string      `json:"sku" db:"sku"`
+	Price   money.Money `json:"price" db:"price"`
+	Deleted bool        `json:"deleted" db:"deleted"`
+	// WeightGrams is the shipping weight of one unit.
+	WeightGrams int `json:"weight_grams" db:"weight_grams"`
+
+	CategoryID int `json:"category_id" db:"category_id"`
+	// CategoryIDs holds CategoryID and the IDs of all its ancestors.
+	CategoryIDs []int `json:"category_ids" db:"-"`
+
+	Variants []*VariantSummary `json:"variants,omitempty" db:"-"`
+}
+
+// ProductSearchHit is a product matched by a search. Highlight is the product name and Snippet
+// an excerpt of its description, with the matched terms wrapped in <mark> tags. Fuzzy hits come
+// from the trigram fallback and are not highlighted.
+type ProductSearchHit struct {
+	Product
+	Rank      float64 `json:"rank" db:"rank"`
+	Highlight string  `json:"highlight" db:"highlight"`
+	Snippet   string  `json:"snippet" db:"snippet"`
+	Fuzzy     bool    `json:"fuzzy" db:"-"`
 }
 
 type Category struct {
-	ID        int       `json:"id"`
-	Name      string    `json:"name" validate:"required,min=3"`
-	CreatedAt time.Time `json:"created_at,omitempty" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at,omitempty" db:"updated_at"`
+	ID               int       `json:"id"`
+	Name             string    `json:"name" validate:"required,min=3"`
+	ParentCategoryID *int      `json:"parent_category_id" db:"parent_category_id"`
+	CreatedAt        time.Time `json:"created_at,omitempty" db:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at,omitempty" db:"updated_at"`
+
+	// Breadcrumbs is the path from the root category down to this one, set by GetCategory.
+	Breadcrumbs []*CategoryRef `json:"breadcrumbs,omitempty" db:"-"`
+	// Children is only set in the category tree.
+	Children []*Category `json:"children,omitempty" db:"-"`
+}
+
+type CategoryRef struct {
+	ID   int    `json:"id" db:"id"`
+	Name string `json:"name" db:"name"`
 }
 
 type PaginationResult[T any] struct {
-	Items  []T `json:"items"`
-	Limit  int `json:"limit"`
-	Offset int `json:"offset"`
-	Total  int `json:"total"`
+	Items   []T  `json:"items"`
+	Limit   int  `json:"limit"`
+	Offset  int  `json:"offset"`
+	Total   int  `json:"total"`
+	HasMore bool `json:"has_more"`
+	// NextCursor fetches the next page of a keyset paginated listing.
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+type ProductSort string
+
+const (
+	SortByCreatedAt ProductSort = "created_at"
+	SortByPrice     ProductSort = "price"
+	SortByName      ProductSort = "name"
+)
+
+func (s ProductSort) IsValid() bool {
+	switch s {
+	case SortByCreatedAt, SortByPrice, SortByName:
+		return true
+	}
+	return false
+}
+
+// ProductFilter narrows a product listing. Zero fields do not filter.
+type ProductFilter struct {
+	CategoryIDs []int
+	// IncludeDescendants also matches products in the subcategories of CategoryIDs.
+	IncludeDescendants bool
+	MinPrice           *money.Money
+	MaxPrice           *money.Money
+	Currency           string
+	SKUPrefix          string
+	InStock            *bool
+}
+
+// ProductListQuery describes a page of products. When Cursor is set the page starts after the
+// product it points to and Offset is ignored.
+type ProductListQuery struct {
+	Filter     ProductFilter
+	Sort       ProductSort
+	Descending bool
+	Limit      int
+	Offset     int
+	Cursor     string
 }
 
 type Repository interface {
 	GetProductByID(ctx context.Context, id int) (*Product, error)
-	GetAllProducts(ctx context.Context, limit int, offset int) ([]*Product, error)
+	GetProductsByIDs(ctx context.Context, ids []int) ([]*ProductSummary, error)
+	ListProducts(ctx context.Context, q ProductListQuery) (products []*Product, nextCursor string, err error)
 	CreateProduct(ctx context.Context, p *Product) (*Product, error)
 	UpdateProduct(ctx context.Context, p *Product) (*Product, error)
 	GetProductsByCategory(ctx context.Context, categoryID int) ([]*Product, error)
 	DeleteProduct(ctx context.Context, id int) error
-	CountProducts(ctx context.Context) (int, error)
-	SearchProductsByName(ctx context.Context, searchTerm string) ([]*Product, error)
+	ListDeletedProducts(ctx context.Context, limit, offset int) ([]*Product, int, error)
+	RestoreProduct(ctx context.Context, id int) (*Product, error)
+	PurgeProducts(ctx context.Context, deletedBefore time.Time, limit int) (*PurgeResult, error)
+	UpsertProducts(ctx context.Context, products []*Product) ([]UpsertResult, error)
+
+	GetProductVariants(ctx context.Context, productID int) ([]*Variant, error)
+	CreateVariant(ctx context.Context, productID int, v *Variant) (*Variant, error)
+	UpdateVariant(ctx context.Context, v *Variant) (*Variant, error)
+	DeleteVariant(ctx context.Context, productID, variantID int) error
+	CountProducts(ctx context.Context, filter ProductFilter) (int, error)
+
+	SearchProducts(ctx context.Context, term string, limit, offset int) ([]*ProductSearchHit, int, error)
+	FuzzySearchProducts(ctx context.Context, term string, limit, offset int) ([]*ProductSearchHit, int, error)
+
+	GetProductImages(ctx context.Context, productID int) ([]*ProductImage, error)
+	AddProductImages(ctx context.Context, productID int, images []*ProductImage) ([]*ProductImage, error)
+	ReorderProductImages(ctx context.Context, productID int, imageIDs []int) ([]*ProductImage, error)
+	DeleteProductImage(ctx context.Context, productID, imageID int) (*ProductImage, error)
+
+	GetProductPrices(ctx context.Context, productID int) ([]*ProductPrice, error)
+	SchedulePrice(ctx context.Context, productID int, p *ProductPrice, now time.Time) (*ProductPrice, error)
+	CancelPrice(ctx context.Context, productID, priceID int) error
+	ApplyScheduledPrices(ctx context.Context, now time.Time, limit int) (int, error)
 
 	GetCategoryByID(ctx context.Context, id int) (*Category, error)
 	GetAllCategories(ctx context.Context, limit, offset int) ([]*Category, error)
-	CreateCategory(ctx context.Context, name string) (*Category, error)
+	GetCategoryBreadcrumbs(ctx context.Context, id int) ([]*CategoryRef, error)
+	GetCategoryTree(ctx context.Context) ([]*Category, error)
+	CreateCategory(ctx context.Context, c *Category) (*Category, error)
+	MoveCategory(ctx context.Context, id int, parentID *int) (*Category, error)
 	UpdateCategory(ctx context.Context, id int, name string) (*Category, error)
 	SearchCategoriesByName(ctx context.Context, searchTerm string) ([]*Category, error)
 	CountCategories(ctx context.Context) (int, error)
@@ -64,7 +177,30 @@ func (p *Product) FromJSON(r io.Reader) error {
 
 func (p *Product) Validate() error {
 	v := validator.New()
-	return v.Struct(p)
+	// validate prices by their amount in minor units
+	v.RegisterCustomTypeFunc(func(field reflect.Value) interface{} {
+		return field.Interface().(money.Money).Amount
+	}, money.Money{})
+
+	if err := v.Struct(p); err != nil {
+		return err
+	}
+
+	if err := p.Price.Validate(); err != nil {
+		return err
+	}
+
+	for _, variant := range p.Variants {
+		if err := variant.Validate(); err != nil {
+			return err
+		}
+
+		if variant.Price != nil && variant.Price.Currency != p.Price.Currency {
+			return ErrVariantCurrency
+		}
+	}
+
+	return nil
 }
 
 func (c *Category) ToJSON(w io.Writer) error {
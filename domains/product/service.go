@@ -1,23 +1,30 @@
 package product
 
 import (
+	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"strings"
+	"time"
 
-	"github.com/rovilay/ecommerce-service/common/events"
+	"github.com/google/uuid"
+	"github.com/rovilay/ecommerce-service/common/blobstore"
 	"github.com/rs/zerolog"
 )
 
 type Service struct {
-	repo Repository
-	log  *zerolog.Logger
-	rc   *events.RabbitClient
+	repo  Repository
+	blobs blobstore.BlobStore
+	// mediaURL is the base URL image blobs are served from.
+	mediaURL string
+	log      *zerolog.Logger
 }
 
-func NewService(repo Repository, rc *events.RabbitClient, l *zerolog.Logger) (*Service, error) {
+func NewService(repo Repository, blobs blobstore.BlobStore, mediaURL string, l *zerolog.Logger) (*Service, error) {
 	logger := l.With().Str("service", "InventoryService").Logger()
-	s := &Service{repo: repo, rc: rc, log: &logger}
+	s := &Service{repo: repo, blobs: blobs, mediaURL: strings.TrimSuffix(mediaURL, "/"), log: &logger}
 
 	return s, nil
 }
@@ -26,50 +33,55 @@ func (s *Service) GetProduct(ctx context.Context, id int) (*Product, error) {
 	return s.repo.GetProductByID(ctx, id)
 }
 
-func (s *Service) CreateProduct(ctx context.Context, data *Product) (*Product, error) {
-	p, err := s.repo.CreateProduct(ctx, data)
-	if err != nil {
-		return nil, err
-	}
+// MaxLookupIDs is the most products a single batch lookup may ask for.
+const MaxLookupIDs = 100
 
-	b, err := json.Marshal(p)
-	if err != nil {
-		return nil, err
-	}
+var ErrTooManyIDs = errors.New("too many ids to look up at once")
 
-	// publish event
-	e := events.EventData{
-		Event: events.ProductCreated,
-		Data:  b,
-	}
-	err = s.publish(ctx, events.Product, events.ProductCreated, e)
-	if err != nil {
-		return nil, err
+func (s *Service) LookupProducts(ctx context.Context, ids []int) ([]*ProductSummary, error) {
+	if len(ids) > MaxLookupIDs {
+		return nil, ErrTooManyIDs
 	}
 
-	return p, err
+	return s.repo.GetProductsByIDs(ctx, ids)
 }
 
-func (s *Service) ListProducts(ctx context.Context, limit int, offset int) (*PaginationResult[*Product], error) {
-	pwp := &PaginationResult[*Product]{Limit: limit, Offset: offset}
+// CreateProduct stores the product together with its product.created outbox event.
+func (s *Service) CreateProduct(ctx context.Context, data *Product) (*Product, error) {
+	return s.repo.CreateProduct(ctx, data)
+}
 
-	total, err := s.repo.CountProducts(ctx)
+// ListProducts returns a filtered, sorted page of products. Pages are fetched by keyset
+// through NextCursor, so they stay stable while products are added.
+func (s *Service) ListProducts(ctx context.Context, q ProductListQuery) (*PaginationResult[*Product], error) {
+	if q.Sort == "" {
+		q.Sort = SortByCreatedAt
+	}
+	if q.Cursor != "" {
+		q.Offset = 0
+	}
+
+	pwp := &PaginationResult[*Product]{Limit: q.Limit, Offset: q.Offset, Items: []*Product{}}
+
+	total, err := s.repo.CountProducts(ctx, q.Filter)
 	if err != nil {
 		return nil, err
 	}
 
 	pwp.Total = total
 
-	if offset >= total {
+	if q.Cursor == "" && q.Offset >= total {
 		return pwp, nil
 	}
 
-	products, err := s.repo.GetAllProducts(ctx, limit, offset)
+	products, next, err := s.repo.ListProducts(ctx, q)
 	if err != nil {
 		return nil, err
 	}
 
 	pwp.Items = products
+	pwp.NextCursor = next
+	pwp.HasMore = next != ""
 
 	return pwp, nil
 }
@@ -83,16 +95,251 @@ func (s *Service) DeleteProduct(ctx context.Context, id int) error {
 	return s.repo.DeleteProduct(ctx, id)
 }
 
-func (s *Service) SearchProductsByName(ctx context.Context, searchTerm string) ([]*Product, error) {
-	return s.repo.SearchProductsByName(ctx, searchTerm)
+// ListDeletedProducts returns a page of the products in the trash, most recently deleted first.
+func (s *Service) ListDeletedProducts(ctx context.Context, limit, offset int) (*PaginationResult[*Product], error) {
+	products, total, err := s.repo.ListDeletedProducts(ctx, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PaginationResult[*Product]{
+		Items:   products,
+		Limit:   limit,
+		Offset:  offset,
+		Total:   total,
+		HasMore: offset+len(products) < total,
+	}, nil
+}
+
+// RestoreProduct takes a product out of the trash. It fails with ErrNotDeleted when the
+// product is live.
+func (s *Service) RestoreProduct(ctx context.Context, id int) (*Product, error) {
+	return s.repo.RestoreProduct(ctx, id)
+}
+
+// purgeBatchSize is the number of products hard-deleted per transaction.
+const purgeBatchSize = 100
+
+// PurgeDeletedProducts hard-deletes products that have been in the trash for longer than
+// retention, every interval until ctx is done.
+func (s *Service) PurgeDeletedProducts(ctx context.Context, interval, retention time.Duration) {
+	log := s.log.With().Str("method", "PurgeDeletedProducts").Logger()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := s.purgeDeletedBefore(ctx, time.Now().Add(-retention))
+			if err != nil {
+				log.Err(err).Msg("failed to purge deleted products")
+			}
+
+			if purged > 0 {
+				log.Info().Int("purged", purged).Msg("purged deleted products")
+			}
+		}
+	}
+}
+
+// purgeDeletedBefore purges, batch by batch, every product deleted before the given time that
+// is not referenced by an order, then deletes the blobs of their images. It returns how many
+// products were purged.
+func (s *Service) purgeDeletedBefore(ctx context.Context, before time.Time) (int, error) {
+	purged := 0
+	for {
+		res, err := s.repo.PurgeProducts(ctx, before, purgeBatchSize)
+		if err != nil {
+			return purged, err
+		}
+
+		purged += len(res.ProductIDs)
+
+		for _, img := range res.Images {
+			for _, key := range []string{img.Key, img.ThumbnailKey} {
+				if err := s.blobs.Delete(ctx, key); err != nil {
+					s.log.Err(err).Str("key", key).Msg("failed to delete blob of purged product")
+				}
+			}
+		}
+
+		// a short batch means nothing is left to purge
+		if len(res.ProductIDs) < purgeBatchSize {
+			return purged, nil
+		}
+	}
+}
+
+func (s *Service) GetProductVariants(ctx context.Context, productID int) ([]*Variant, error) {
+	p, err := s.repo.GetProductByID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.Variants, nil
+}
+
+// CreateVariant adds a variant to a product. The product.updated event it emits lets the
+// inventory service start tracking the variant's stock.
+func (s *Service) CreateVariant(ctx context.Context, productID int, v *Variant) (*Variant, error) {
+	if err := s.checkVariantPrice(ctx, productID, v); err != nil {
+		return nil, err
+	}
+
+	return s.repo.CreateVariant(ctx, productID, v)
+}
+
+func (s *Service) UpdateVariant(ctx context.Context, productID, variantID int, v *Variant) (*Variant, error) {
+	if err := s.checkVariantPrice(ctx, productID, v); err != nil {
+		return nil, err
+	}
+
+	v.ID = variantID
+	v.ProductID = productID
+	return s.repo.UpdateVariant(ctx, v)
+}
+
+func (s *Service) DeleteVariant(ctx context.Context, productID, variantID int) error {
+	return s.repo.DeleteVariant(ctx, productID, variantID)
 }
 
+// checkVariantPrice makes sure a price override is in the product currency.
+func (s *Service) checkVariantPrice(ctx context.Context, productID int, v *Variant) error {
+	p, err := s.repo.GetProductByID(ctx, productID)
+	if err != nil {
+		return err
+	}
+
+	if v.Price != nil && v.Price.Currency != p.Price.Currency {
+		return ErrVariantCurrency
+	}
+
+	return nil
+}
+
+// SearchProducts runs a ranked full-text search over product names, SKUs and descriptions.
+// When nothing matches, it falls back to a typo-tolerant trigram search on names.
+func (s *Service) SearchProducts(ctx context.Context, term string, limit, offset int) (*PaginationResult[*ProductSearchHit], error) {
+	hits, total, err := s.repo.SearchProducts(ctx, term, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	if total == 0 {
+		hits, total, err = s.repo.FuzzySearchProducts(ctx, term, limit, offset)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &PaginationResult[*ProductSearchHit]{
+		Items:   hits,
+		Limit:   limit,
+		Offset:  offset,
+		Total:   total,
+		HasMore: offset+len(hits) < total,
+	}, nil
+}
+
+// GetProductPrices returns the price history of a product, including scheduled prices.
+func (s *Service) GetProductPrices(ctx context.Context, productID int) ([]*ProductPrice, error) {
+	if _, err := s.repo.GetProductByID(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetProductPrices(ctx, productID)
+}
+
+// SchedulePrice schedules a new base price for a product, or a sale when the price has an end.
+// A price without a start takes effect immediately.
+func (s *Service) SchedulePrice(ctx context.Context, productID int, p *ProductPrice) (*ProductPrice, error) {
+	now := time.Now()
+	if p.StartsAt.IsZero() {
+		p.StartsAt = now
+	} else if p.StartsAt.Before(now.Add(-time.Minute)) {
+		// a minute of leeway for clock skew and slow requests
+		return nil, ErrPriceInPast
+	}
+
+	if p.EndsAt != nil && !p.EndsAt.After(p.StartsAt) {
+		return nil, ErrInvalidPriceWindow
+	}
+
+	return s.repo.SchedulePrice(ctx, productID, p, now)
+}
+
+// CancelPrice removes a scheduled price before it takes effect.
+func (s *Service) CancelPrice(ctx context.Context, productID, priceID int) error {
+	return s.repo.CancelPrice(ctx, productID, priceID)
+}
+
+// priceBatchSize is the number of products whose scheduled prices are applied per transaction.
+const priceBatchSize = 100
+
+// ApplyScheduledPrices activates scheduled prices and sales and reverts ended sales, every
+// interval until ctx is done.
+func (s *Service) ApplyScheduledPrices(ctx context.Context, interval time.Duration) {
+	log := s.log.With().Str("method", "ApplyScheduledPrices").Logger()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			now := time.Now()
+			for {
+				n, err := s.repo.ApplyScheduledPrices(ctx, now, priceBatchSize)
+				if err != nil {
+					log.Err(err).Msg("failed to apply scheduled prices")
+					break
+				}
+
+				if n > 0 {
+					log.Info().Int("products", n).Msg("applied scheduled prices")
+				}
+
+				if n < priceBatchSize {
+					break
+				}
+			}
+		}
+	}
+}
+
+// GetCategory returns the category with its breadcrumbs.
 func (s *Service) GetCategory(ctx context.Context, id int) (*Category, error) {
-	return s.repo.GetCategoryByID(ctx, id)
+	c, err := s.repo.GetCategoryByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Breadcrumbs, err = s.repo.GetCategoryBreadcrumbs(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
 }
 
 func (s *Service) CreateCategory(ctx context.Context, data *Category) (*Category, error) {
-	return s.repo.CreateCategory(ctx, data.Name)
+	return s.repo.CreateCategory(ctx, data)
+}
+
+// GetCategoryTree returns the root categories with their subcategories nested as children.
+func (s *Service) GetCategoryTree(ctx context.Context) ([]*Category, error) {
+	return s.repo.GetCategoryTree(ctx)
+}
+
+// MoveCategory moves a category, with its subcategories, under parentID, or to the root when
+// parentID is nil. A category cannot be moved under itself or one of its descendants.
+func (s *Service) MoveCategory(ctx context.Context, id int, parentID *int) (*Category, error) {
+	return s.repo.MoveCategory(ctx, id, parentID)
 }
 
 func (s *Service) ListCategories(ctx context.Context, limit int, offset int) (*PaginationResult[*Category], error) {
@@ -115,6 +362,7 @@ func (s *Service) ListCategories(ctx context.Context, limit int, offset int) (*P
 	}
 
 	cwp.Items = categories
+	cwp.HasMore = offset+len(categories) < total
 
 	return cwp, nil
 }
@@ -127,12 +375,239 @@ func (s *Service) SearchCategoriesByName(ctx context.Context, searchTerm string)
 	return s.repo.SearchCategoriesByName(ctx, searchTerm)
 }
 
-func (s *Service) publish(ctx context.Context, topic events.Topic, key events.RoutingKey, e events.EventData) error {
-	err := s.rc.Publish(ctx, topic, key, e)
+// importBatchSize is the number of rows stored per transaction during an import.
+const importBatchSize = 500
+
+var errImportVariants = errors.New("variants cannot be imported, manage them through the variant endpoints")
+
+// ImportProducts validates each row read from pr and upserts the valid ones by SKU in batched
+// transactions. Invalid rows are reported and skipped. When reading or storing fails, the
+// import stops and returns the report so far, with the batches before the failure stored.
+func (s *Service) ImportProducts(ctx context.Context, pr ProductReader) (*ImportReport, error) {
+	report := &ImportReport{Errors: []RowError{}}
+
+	batch := make([]*Product, 0, importBatchSize)
+	rows := make([]int, 0, importBatchSize)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		results, err := s.repo.UpsertProducts(ctx, batch)
+		if err != nil {
+			return err
+		}
+
+		for i, res := range results {
+			switch {
+			case res.Err != nil:
+				report.fail(rows[i], batch[i].SKU, res.Err)
+			case res.Created:
+				report.Created++
+			default:
+				report.Updated++
+			}
+		}
+
+		batch, rows = batch[:0], rows[:0]
+		return nil
+	}
+
+	stop := func(err error) (*ImportReport, error) {
+		report.Error = err.Error()
+		return report, err
+	}
+
+	for row := 1; ; row++ {
+		p, err := pr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		var readErr *rowReadError
+		if errors.As(err, &readErr) {
+			report.Rows++
+			sku := ""
+			if p != nil {
+				sku = p.SKU
+			}
+			report.fail(row, sku, err)
+			continue
+		} else if err != nil {
+			return stop(fmt.Errorf("import stopped at row %d: %w", row, err))
+		}
+
+		report.Rows++
+
+		if len(p.Variants) > 0 {
+			report.fail(row, p.SKU, errImportVariants)
+			continue
+		}
+
+		if err = p.Validate(); err != nil {
+			report.fail(row, p.SKU, err)
+			continue
+		}
+
+		batch = append(batch, p)
+		rows = append(rows, row)
+
+		if len(batch) == importBatchSize {
+			if err = flush(); err != nil {
+				return stop(fmt.Errorf("import stopped at row %d: %w", row, err))
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return stop(fmt.Errorf("import stopped at the last batch: %w", err))
+	}
+
+	return report, nil
+}
+
+// exportPageSize is the number of products read per query during an export.
+const exportPageSize = 500
+
+// ExportProducts writes every live product matching filter to w, oldest first, reading the
+// catalogue page by page so it is never held in memory.
+func (s *Service) ExportProducts(ctx context.Context, filter ProductFilter, w ProductWriter) error {
+	q := ProductListQuery{Filter: filter, Sort: SortByCreatedAt, Limit: exportPageSize}
+
+	for {
+		products, next, err := s.repo.ListProducts(ctx, q)
+		if err != nil {
+			return err
+		}
+
+		for _, p := range products {
+			if err = w.Write(p); err != nil {
+				return err
+			}
+		}
+
+		if next == "" {
+			break
+		}
+		q.Cursor = next
+	}
+
+	return w.Flush()
+}
+
+func (s *Service) GetProductImages(ctx context.Context, productID int) ([]*ProductImage, error) {
+	p, err := s.repo.GetProductByID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.Images, nil
+}
+
+// AddProductImages checks the uploads, stores each image and its thumbnail in the blob store
+// and appends them to the product's images. Nothing is added if any upload is rejected, and
+// blobs that were stored are removed again when the images cannot be saved.
+func (s *Service) AddProductImages(ctx context.Context, productID int, uploads []ImageUpload) ([]*ProductImage, error) {
+	log := s.log.With().Str("method", "AddProductImages").Logger()
+
+	if len(uploads) == 0 {
+		return nil, fmt.Errorf("%w: no image was uploaded", ErrInvalidImage)
+	}
+
+	if len(uploads) > MaxProductImages {
+		return nil, ErrTooManyImages
+	}
+
+	if _, err := s.repo.GetProductByID(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	processed := make([]*processedImage, len(uploads))
+	for i, u := range uploads {
+		p, err := processImage(u.Data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", u.Filename, err)
+		}
+		processed[i] = p
+	}
+
+	images := make([]*ProductImage, 0, len(uploads))
+	var stored []string
+	cleanup := func() {
+		for _, key := range stored {
+			if err := s.blobs.Delete(context.WithoutCancel(ctx), key); err != nil {
+				log.Err(err).Str("key", key).Msg("failed to delete orphaned blob")
+			}
+		}
+	}
+
+	for i, p := range processed {
+		name := fmt.Sprintf("products/%d/%s", productID, uuid.NewString())
+		img := &ProductImage{
+			ProductID:    productID,
+			Key:          name + p.ext,
+			ThumbnailKey: name + "_thumb" + p.thumbnailExt,
+			ContentType:  p.contentType,
+			Size:         int64(len(uploads[i].Data)),
+			Width:        p.width,
+			Height:       p.height,
+		}
+		img.URL = s.mediaURL + "/" + img.Key
+		img.ThumbnailURL = s.mediaURL + "/" + img.ThumbnailKey
+
+		err := s.blobs.Put(ctx, img.Key, bytes.NewReader(uploads[i].Data), img.Size, img.ContentType)
+		if err != nil {
+			cleanup()
+			return nil, err
+		}
+		stored = append(stored, img.Key)
+
+		err = s.blobs.Put(ctx, img.ThumbnailKey, bytes.NewReader(p.thumbnail), int64(len(p.thumbnail)), p.thumbnailContentType)
+		if err != nil {
+			cleanup()
+			return nil, err
+		}
+		stored = append(stored, img.ThumbnailKey)
+
+		images = append(images, img)
+	}
+
+	saved, err := s.repo.AddProductImages(ctx, productID, images)
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	return saved, nil
+}
+
+// ReorderProductImages puts the images of a product into the order of imageIDs. The first
+// image becomes the product's main image.
+func (s *Service) ReorderProductImages(ctx context.Context, productID int, imageIDs []int) ([]*ProductImage, error) {
+	return s.repo.ReorderProductImages(ctx, productID, imageIDs)
+}
+
+// DeleteProductImage removes an image from a product, then deletes its blobs. A blob that
+// cannot be deleted is logged and left behind rather than failing the request.
+func (s *Service) DeleteProductImage(ctx context.Context, productID, imageID int) error {
+	log := s.log.With().Str("method", "DeleteProductImage").Logger()
+
+	img, err := s.repo.DeleteProductImage(ctx, productID, imageID)
 	if err != nil {
-		s.log.Err(err).Msg(fmt.Sprintf("error publishing %s", events.ProductCreated))
 		return err
 	}
 
+	for _, key := range []string{img.Key, img.ThumbnailKey} {
+		if err := s.blobs.Delete(ctx, key); err != nil {
+			log.Err(err).Str("key", key).Msg("failed to delete blob")
+		}
+	}
+
 	return nil
 }
+
+// OpenMedia opens a stored image blob for serving. The caller must close its Body.
+func (s *Service) OpenMedia(ctx context.Context, key string) (*blobstore.Blob, error) {
+	return s.blobs.Get(ctx, key)
+}
@@ -0,0 +1,110 @@
+package product
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rovilay/ecommerce-service/common/money"
+)
+
+var ErrVariantCurrency = errors.New("variant price must be in the product currency")
+var ErrDuplicateVariant = errors.New("a variant with this sku or these options already exists")
+
+// VariantOptions are the attributes that tell variants of a product apart, e.g. size and colour.
+type VariantOptions map[string]string
+
+func (o *VariantOptions) Scan(value interface{}) error {
+	return scanJSONB(value, o)
+}
+
+func (o VariantOptions) Value() (driver.Value, error) {
+	if o == nil {
+		return []byte(`{}`), nil
+	}
+
+	return json.Marshal(o)
+}
+
+type ImageURLs []string
+
+func (u *ImageURLs) Scan(value interface{}) error {
+	return scanJSONB(value, u)
+}
+
+func (u ImageURLs) Value() (driver.Value, error) {
+	if u == nil {
+		return []byte(`[]`), nil
+	}
+
+	return json.Marshal(u)
+}
+
+func scanJSONB(value interface{}, dst any) error {
+	if value == nil {
+		return nil
+	}
+
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, dst)
+	case string:
+		return json.Unmarshal([]byte(v), dst)
+	default:
+		return fmt.Errorf("failed to unmarshal JSONB value: %v", value)
+	}
+}
+
+// Variant is a sellable version of a product with its own SKU and stock. Price overrides the
+// product price when set and is always in the product currency.
+type Variant struct {
+	ID        int            `json:"id" db:"id"`
+	ProductID int            `json:"product_id" db:"product_id"`
+	SKU       string         `json:"sku" db:"sku" validate:"required,max=100"`
+	Options   VariantOptions `json:"options" db:"options" validate:"required,min=1"`
+	Price     *money.Money   `json:"price,omitempty" db:"price"`
+	ImageURLs ImageURLs      `json:"image_urls" db:"image_urls" validate:"dive,url"`
+	CreatedAt time.Time      `json:"created_at,omitempty" db:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at,omitempty" db:"updated_at"`
+}
+
+// EffectivePrice is what the variant sells for.
+func (v *Variant) EffectivePrice(p money.Money) money.Money {
+	if v.Price != nil {
+		return *v.Price
+	}
+
+	return p
+}
+
+func (v *Variant) ToJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+func (v *Variant) FromJSON(r io.Reader) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+func (v *Variant) Validate() error {
+	if err := validator.New().Struct(v); err != nil {
+		return err
+	}
+
+	if v.Price != nil && !v.Price.IsPositive() {
+		return errors.New("variant price must be greater than 0")
+	}
+
+	return nil
+}
+
+// VariantSummary is what the batch lookup returns for a variant, with its effective price.
+type VariantSummary struct {
+	ID      int         `json:"id" db:"id"`
+	SKU     string      `json:"sku" db:"sku"`
+	Price   money.Money `json:"price" db:"price"`
+	Deleted bool        `json:"deleted" db:"deleted"`
+}
@@ -0,0 +1,156 @@
+package promotion
+
+import (
+	"sort"
+
+	"github.com/rovilay/ecommerce-service/common/money"
+)
+
+// Line is an order line as the engine sees it. CategoryIDs holds the category of the product
+// and all of its ancestors.
+type Line struct {
+	ProductID   int
+	CategoryIDs []int
+	Quantity    int
+	UnitPrice   money.Money
+}
+
+func (l Line) total() int64 {
+	return l.UnitPrice.Amount * int64(l.Quantity)
+}
+
+// Applied is a promotion applied to an order. LineAmounts holds the discount taken off each
+// line, in the order of the lines, and adds up to Amount.
+type Applied struct {
+	Promotion    *Promotion
+	Amount       money.Money
+	LineAmounts  []money.Money
+	FreeShipping bool
+}
+
+// Apply applies promotions to lines in turn and returns the ones that took effect. Each
+// promotion works on what the previous ones left of the lines, so stacked discounts never
+// take a line below zero. Promotions in another currency than the lines are skipped.
+func Apply(promotions []*Promotion, lines []Line) []*Applied {
+	if len(lines) == 0 {
+		return nil
+	}
+
+	currency := lines[0].UnitPrice.Currency
+	remaining := make([]int64, len(lines))
+	for i, l := range lines {
+		remaining[i] = l.total()
+	}
+
+	var applied []*Applied
+	for _, p := range promotions {
+		if (p.AmountOff != nil || p.MinSubtotal != nil) && p.Currency() != currency {
+			continue
+		}
+
+		var eligible []int
+		var eligibleTotal int64
+		for i, l := range lines {
+			if p.Covers(l) {
+				eligible = append(eligible, i)
+				eligibleTotal += l.total()
+			}
+		}
+
+		if len(eligible) == 0 {
+			continue
+		}
+
+		if p.MinSubtotal != nil && eligibleTotal < p.MinSubtotal.Amount {
+			continue
+		}
+
+		amounts := make([]int64, len(lines))
+		switch p.Type {
+		case TypePercentage:
+			for _, i := range eligible {
+				amounts[i] = remaining[i] * int64(*p.PercentOff) / 100
+			}
+		case TypeFixedAmount:
+			spread(amounts, remaining, eligible, p.AmountOff.Amount)
+		case TypeBuyXGetY:
+			freeUnits(amounts, remaining, lines, eligible, *p.BuyQuantity, *p.GetQuantity)
+		}
+
+		a := &Applied{Promotion: p, LineAmounts: make([]money.Money, len(lines)), FreeShipping: p.Type == TypeFreeShipping}
+		var total int64
+		for i, amount := range amounts {
+			remaining[i] -= amount
+			total += amount
+			a.LineAmounts[i] = money.New(amount, currency)
+		}
+		a.Amount = money.New(total, currency)
+
+		if total > 0 || a.FreeShipping {
+			applied = append(applied, a)
+		}
+	}
+
+	return applied
+}
+
+// spread divides amount over the eligible lines in proportion to what is left of them, capped
+// at their sum. Rounding leftovers go to the lines with the most left.
+func spread(amounts, remaining []int64, eligible []int, amount int64) {
+	var base int64
+	for _, i := range eligible {
+		base += remaining[i]
+	}
+
+	if base == 0 {
+		return
+	}
+	amount = min(amount, base)
+
+	var given int64
+	for _, i := range eligible {
+		amounts[i] = amount * remaining[i] / base
+		given += amounts[i]
+	}
+
+	order := append([]int(nil), eligible...)
+	sort.SliceStable(order, func(a, b int) bool { return remaining[order[a]] > remaining[order[b]] })
+	for given < amount {
+		for _, i := range order {
+			if given == amount {
+				break
+			}
+			if amounts[i] < remaining[i] {
+				amounts[i]++
+				given++
+			}
+		}
+	}
+}
+
+// freeUnits makes get of every buy+get eligible units free, the cheapest units first.
+func freeUnits(amounts, remaining []int64, lines []Line, eligible []int, buy, get int) {
+	units := 0
+	for _, i := range eligible {
+		units += lines[i].Quantity
+	}
+
+	free := units / (buy + get) * get
+	if free == 0 {
+		return
+	}
+
+	cheapest := append([]int(nil), eligible...)
+	sort.SliceStable(cheapest, func(a, b int) bool {
+		return lines[cheapest[a]].UnitPrice.Amount < lines[cheapest[b]].UnitPrice.Amount
+	})
+
+	for _, i := range cheapest {
+		n := min(free, lines[i].Quantity)
+		amounts[i] = min(lines[i].UnitPrice.Amount*int64(n), remaining[i])
+		free -= n
+		if free == 0 {
+			break
+		}
+	}
+}
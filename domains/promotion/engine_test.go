@@ -0,0 +1,239 @@
+package promotion
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/rovilay/ecommerce-service/common/money"
+)
+
+func percentage(id, off int) *Promotion {
+	return &Promotion{ID: id, Type: TypePercentage, PercentOff: &off}
+}
+
+func fixedAmount(id int, off int64, currency string) *Promotion {
+	m := money.New(off, currency)
+	return &Promotion{ID: id, Type: TypeFixedAmount, AmountOff: &m}
+}
+
+func buyXGetY(id, buy, get int) *Promotion {
+	return &Promotion{ID: id, Type: TypeBuyXGetY, BuyQuantity: &buy, GetQuantity: &get}
+}
+
+func line(productID int, unitPrice int64, quantity int, categoryIDs ...int) Line {
+	return Line{ProductID: productID, CategoryIDs: categoryIDs, Quantity: quantity, UnitPrice: money.New(unitPrice, "USD")}
+}
+
+func TestApply(t *testing.T) {
+	type applied struct {
+		id           int
+		lines        []int64
+		freeShipping bool
+	}
+
+	minSubtotal := func(p *Promotion, amount int64) *Promotion {
+		m := money.New(amount, "USD")
+		p.MinSubtotal = &m
+		return p
+	}
+	scoped := func(p *Promotion, productIDs, categoryIDs IDList) *Promotion {
+		p.ProductIDs, p.CategoryIDs = productIDs, categoryIDs
+		return p
+	}
+
+	tests := []struct {
+		name       string
+		promotions []*Promotion
+		lines      []Line
+		want       []applied
+	}{
+		{
+			name:       "percentage rounds down per line",
+			promotions: []*Promotion{percentage(1, 10)},
+			lines:      []Line{line(1, 1999, 1), line(2, 505, 3)},
+			want:       []applied{{id: 1, lines: []int64{199, 151}}},
+		},
+		{
+			name:       "fixed amount spread by price",
+			promotions: []*Promotion{fixedAmount(1, 900, "USD")},
+			lines:      []Line{line(1, 1000, 1), line(2, 1000, 2)},
+			want:       []applied{{id: 1, lines: []int64{300, 600}}},
+		},
+		{
+			name:       "rounding leftover goes to the line with the most left",
+			promotions: []*Promotion{fixedAmount(1, 1000, "USD")},
+			lines:      []Line{line(1, 3333, 1), line(2, 3333, 2)},
+			want:       []applied{{id: 1, lines: []int64{333, 667}}},
+		},
+		{
+			name:       "rounding leftovers over equal lines go first come",
+			promotions: []*Promotion{fixedAmount(1, 200, "USD")},
+			lines:      []Line{line(1, 100, 1), line(2, 100, 1), line(3, 100, 1)},
+			want:       []applied{{id: 1, lines: []int64{67, 67, 66}}},
+		},
+		{
+			name:       "fixed amount capped at the eligible total",
+			promotions: []*Promotion{fixedAmount(1, 5000, "USD")},
+			lines:      []Line{line(1, 1000, 1), line(2, 250, 2)},
+			want:       []applied{{id: 1, lines: []int64{1000, 500}}},
+		},
+		{
+			name:       "scoped to a product",
+			promotions: []*Promotion{scoped(fixedAmount(1, 500, "USD"), IDList{2}, nil)},
+			lines:      []Line{line(1, 1000, 1), line(2, 1000, 1)},
+			want:       []applied{{id: 1, lines: []int64{0, 500}}},
+		},
+		{
+			name:       "scoped to an ancestor category",
+			promotions: []*Promotion{scoped(percentage(1, 50), nil, IDList{5})},
+			lines:      []Line{line(1, 1000, 1, 7, 5), line(2, 1000, 1, 8, 6)},
+			want:       []applied{{id: 1, lines: []int64{500, 0}}},
+		},
+		{
+			name:       "nothing in scope",
+			promotions: []*Promotion{scoped(percentage(1, 50), IDList{9}, IDList{9})},
+			lines:      []Line{line(1, 1000, 1, 7, 5)},
+		},
+		{
+			name:       "buy x get y frees the cheapest units",
+			promotions: []*Promotion{buyXGetY(1, 2, 1)},
+			lines:      []Line{line(1, 1000, 2), line(2, 300, 1), line(3, 500, 3)},
+			want:       []applied{{id: 1, lines: []int64{0, 300, 500}}},
+		},
+		{
+			name:       "buy x get y with a partial set",
+			promotions: []*Promotion{buyXGetY(1, 1, 1)},
+			lines:      []Line{line(1, 800, 3)},
+			want:       []applied{{id: 1, lines: []int64{800}}},
+		},
+		{
+			name:       "buy x get y short of a set",
+			promotions: []*Promotion{buyXGetY(1, 2, 1)},
+			lines:      []Line{line(1, 800, 2)},
+		},
+		{
+			name:       "stacking works on what is left",
+			promotions: []*Promotion{percentage(1, 50), fixedAmount(2, 300, "USD")},
+			lines:      []Line{line(1, 1000, 1), line(2, 200, 1)},
+			want:       []applied{{id: 1, lines: []int64{500, 100}}, {id: 2, lines: []int64{250, 50}}},
+		},
+		{
+			name:       "stacking never goes below zero",
+			promotions: []*Promotion{percentage(1, 50), fixedAmount(2, 10000, "USD"), percentage(3, 10), buyXGetY(4, 1, 1)},
+			lines:      []Line{line(1, 1000, 2)},
+			want:       []applied{{id: 1, lines: []int64{1000}}, {id: 2, lines: []int64{1000}}},
+		},
+		{
+			name:       "free units capped at what is left",
+			promotions: []*Promotion{percentage(1, 75), buyXGetY(2, 1, 1)},
+			lines:      []Line{line(1, 1000, 2)},
+			want:       []applied{{id: 1, lines: []int64{1500}}, {id: 2, lines: []int64{500}}},
+		},
+		{
+			name:       "amount in another currency is skipped",
+			promotions: []*Promotion{fixedAmount(1, 500, "EUR"), percentage(2, 10)},
+			lines:      []Line{line(1, 1000, 1)},
+			want:       []applied{{id: 2, lines: []int64{100}}},
+		},
+		{
+			name: "min subtotal in another currency is skipped",
+			promotions: []*Promotion{func() *Promotion {
+				p := percentage(1, 10)
+				m := money.New(100, "GBP")
+				p.MinSubtotal = &m
+				return p
+			}()},
+			lines: []Line{line(1, 1000, 1)},
+		},
+		{
+			name:       "below the min subtotal",
+			promotions: []*Promotion{minSubtotal(percentage(1, 10), 5001)},
+			lines:      []Line{line(1, 2500, 2)},
+		},
+		{
+			name:       "at the min subtotal",
+			promotions: []*Promotion{minSubtotal(percentage(1, 10), 5000)},
+			lines:      []Line{line(1, 2500, 2)},
+			want:       []applied{{id: 1, lines: []int64{500}}},
+		},
+		{
+			name:       "min subtotal counts eligible lines only",
+			promotions: []*Promotion{minSubtotal(scoped(percentage(1, 10), IDList{1}, nil), 5000)},
+			lines:      []Line{line(1, 2500, 1), line(2, 2500, 1)},
+		},
+		{
+			name:       "free shipping",
+			promotions: []*Promotion{{ID: 1, Type: TypeFreeShipping}},
+			lines:      []Line{line(1, 1000, 1)},
+			want:       []applied{{id: 1, lines: []int64{0}, freeShipping: true}},
+		},
+		{
+			name:       "no lines",
+			promotions: []*Promotion{percentage(1, 10)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Apply(tt.promotions, tt.lines)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Apply() applied %d promotions, want %d", len(got), len(tt.want))
+			}
+
+			for i, a := range got {
+				want := tt.want[i]
+
+				lines := make([]int64, len(a.LineAmounts))
+				var total int64
+				for j, m := range a.LineAmounts {
+					if m.Currency != "USD" {
+						t.Errorf("promotion %d line %d in %s, want USD", a.Promotion.ID, j, m.Currency)
+					}
+					lines[j] = m.Amount
+					total += m.Amount
+				}
+
+				if a.Promotion.ID != want.id || !slices.Equal(lines, want.lines) || a.FreeShipping != want.freeShipping {
+					t.Errorf("applied[%d] = promotion %d %v free shipping %v, want promotion %d %v free shipping %v",
+						i, a.Promotion.ID, lines, a.FreeShipping, want.id, want.lines, want.freeShipping)
+				}
+				if a.Amount != money.New(total, "USD") {
+					t.Errorf("applied[%d].Amount = %v, want the sum of its lines %d", i, a.Amount, total)
+				}
+			}
+		})
+	}
+}
+
+func TestSpread(t *testing.T) {
+	tests := []struct {
+		name      string
+		remaining []int64
+		eligible  []int
+		amount    int64
+		want      []int64
+	}{
+		{name: "proportional", remaining: []int64{100, 300}, eligible: []int{0, 1}, amount: 40, want: []int64{10, 30}},
+		{name: "leftover to the largest", remaining: []int64{1, 1, 5}, eligible: []int{0, 1, 2}, amount: 3, want: []int64{0, 0, 3}},
+		{name: "leftovers spread past the largest", remaining: []int64{1, 1, 1}, eligible: []int{0, 1, 2}, amount: 2, want: []int64{1, 1, 0}},
+		{name: "ineligible lines untouched", remaining: []int64{500, 100, 100}, eligible: []int{1, 2}, amount: 101, want: []int64{0, 51, 50}},
+		{name: "capped", remaining: []int64{7, 3}, eligible: []int{0, 1}, amount: 100, want: []int64{7, 3}},
+		{name: "nothing left", remaining: []int64{0, 0}, eligible: []int{0, 1}, amount: 100, want: []int64{0, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			amounts := make([]int64, len(tt.remaining))
+			spread(amounts, tt.remaining, tt.eligible, tt.amount)
+
+			if !slices.Equal(amounts, tt.want) {
+				t.Errorf("spread() = %v, want %v", amounts, tt.want)
+			}
+			for i := range amounts {
+				if amounts[i] > tt.remaining[i] {
+					t.Errorf("line %d got %d, more than the %d left", i, amounts[i], tt.remaining[i])
+				}
+			}
+		})
+	}
+}
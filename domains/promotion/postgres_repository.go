@@ -0,0 +1,209 @@
+package promotion
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog"
+)
+
+type Repository interface {
+	ListPromotions(ctx context.Context, limit, offset int) ([]*Promotion, int, error)
+	GetPromotion(ctx context.Context, id int) (*Promotion, error)
+	GetPromotionByCode(ctx context.Context, code string) (*Promotion, error)
+	// GetAutomaticPromotions returns the live promotions that need no coupon code at t.
+	GetAutomaticPromotions(ctx context.Context, t time.Time) ([]*Promotion, error)
+	CreatePromotion(ctx context.Context, p *Promotion) (*Promotion, error)
+	UpdatePromotion(ctx context.Context, p *Promotion) (*Promotion, error)
+	DeactivatePromotion(ctx context.Context, id int) error
+	// GetUsage counts the live orders each promotion applies to, overall and for userID.
+	GetUsage(ctx context.Context, promotionIDs []int, userID uuid.UUID) (map[int]Usage, error)
+}
+
+type postgresRepository struct {
+	db  *sqlx.DB
+	log zerolog.Logger
+}
+
+// promotionColumns selects a promotion; amounts are read with their currency as "12.34 USD".
+// Orders that were cancelled do not count as uses.
+const promotionColumns = `p.id, p.name, p.code, p.type, p.percent_off,
+	p.amount_off::text || ' ' || p.currency AS amount_off, p.buy_quantity, p.get_quantity,
+	p.min_subtotal::text || ' ' || p.currency AS min_subtotal, p.product_ids, p.category_ids,
+	p.starts_at, p.ends_at, p.usage_limit, p.per_user_limit, p.active, p.created_at, p.updated_at,
+	(SELECT count(*) FROM order_discounts d JOIN orders o ON o.id = d.order_id
+		WHERE d.promotion_id = p.id AND o.status <> 'cancelled') AS times_used`
+
+func NewPostgresRepository(ctx context.Context, db *sqlx.DB, log zerolog.Logger) *postgresRepository {
+	logger := log.With().Str("repository", "promotionRepository").Logger()
+
+	// ping db
+	if err := db.PingContext(ctx); err != nil {
+		logger.Fatal().Err(fmt.Errorf("failed to connect to postgres: %w", err)).Msg("something went wrong!")
+	}
+
+	return &postgresRepository{db: db, log: logger}
+}
+
+func (r *postgresRepository) ListPromotions(ctx context.Context, limit, offset int) ([]*Promotion, int, error) {
+	var total int
+	if err := r.db.GetContext(ctx, &total, `SELECT count(*) FROM promotions`); err != nil {
+		r.log.Err(err).Str("method", "ListPromotions").Msg(err.Error())
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + promotionColumns + ` FROM promotions p ORDER BY p.id DESC LIMIT $1 OFFSET $2`
+
+	promotions := []*Promotion{}
+	if err := r.db.SelectContext(ctx, &promotions, query, limit, offset); err != nil {
+		r.log.Err(err).Str("method", "ListPromotions").Msg(err.Error())
+		return nil, 0, err
+	}
+
+	return promotions, total, nil
+}
+
+func (r *postgresRepository) GetPromotion(ctx context.Context, id int) (*Promotion, error) {
+	return r.getPromotion(ctx, `p.id = $1`, id)
+}
+
+func (r *postgresRepository) GetPromotionByCode(ctx context.Context, code string) (*Promotion, error) {
+	return r.getPromotion(ctx, `upper(p.code) = upper($1)`, code)
+}
+
+func (r *postgresRepository) getPromotion(ctx context.Context, where string, arg any) (*Promotion, error) {
+	var p Promotion
+	query := `SELECT ` + promotionColumns + ` FROM promotions p WHERE ` + where
+
+	err := r.db.GetContext(ctx, &p, query, arg)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		r.log.Err(err).Str("method", "getPromotion").Msg(err.Error())
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+func (r *postgresRepository) GetAutomaticPromotions(ctx context.Context, t time.Time) ([]*Promotion, error) {
+	query := `SELECT ` + promotionColumns + `
+		FROM promotions p
+		WHERE p.code IS NULL AND p.active
+			AND (p.starts_at IS NULL OR p.starts_at <= $1)
+			AND (p.ends_at IS NULL OR p.ends_at > $1)
+		ORDER BY p.id
+	`
+
+	promotions := []*Promotion{}
+	if err := r.db.SelectContext(ctx, &promotions, query, t); err != nil {
+		r.log.Err(err).Str("method", "GetAutomaticPromotions").Msg(err.Error())
+		return nil, err
+	}
+
+	return promotions, nil
+}
+
+func (r *postgresRepository) CreatePromotion(ctx context.Context, p *Promotion) (*Promotion, error) {
+	query := `INSERT INTO promotions
+		(name, code, type, percent_off, amount_off, buy_quantity, get_quantity, min_subtotal, currency,
+			product_ids, category_ids, starts_at, ends_at, usage_limit, per_user_limit, active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+		RETURNING id
+	`
+
+	var id int
+	err := r.db.QueryRowContext(ctx, query, p.Name, p.Code, p.Type, p.PercentOff, p.AmountOff, p.BuyQuantity,
+		p.GetQuantity, p.MinSubtotal, p.Currency(), p.ProductIDs, p.CategoryIDs, p.StartsAt, p.EndsAt,
+		p.UsageLimit, p.PerUserLimit, p.Active,
+	).Scan(&id)
+	if err != nil {
+		return nil, r.mapError(err, "CreatePromotion")
+	}
+
+	return r.GetPromotion(ctx, id)
+}
+
+func (r *postgresRepository) UpdatePromotion(ctx context.Context, p *Promotion) (*Promotion, error) {
+	query := `UPDATE promotions SET
+			name = $2, code = $3, type = $4, percent_off = $5, amount_off = $6, buy_quantity = $7,
+			get_quantity = $8, min_subtotal = $9, currency = $10, product_ids = $11, category_ids = $12,
+			starts_at = $13, ends_at = $14, usage_limit = $15, per_user_limit = $16, active = $17,
+			updated_at = NOW()
+		WHERE id = $1
+	`
+
+	res, err := r.db.ExecContext(ctx, query, p.ID, p.Name, p.Code, p.Type, p.PercentOff, p.AmountOff, p.BuyQuantity,
+		p.GetQuantity, p.MinSubtotal, p.Currency(), p.ProductIDs, p.CategoryIDs, p.StartsAt, p.EndsAt,
+		p.UsageLimit, p.PerUserLimit, p.Active,
+	)
+	if err != nil {
+		return nil, r.mapError(err, "UpdatePromotion")
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, ErrNotFound
+	}
+
+	return r.GetPromotion(ctx, p.ID)
+}
+
+// DeactivatePromotion switches the promotion off. Promotions are kept once created, since
+// the discounts of past orders refer to them.
+func (r *postgresRepository) DeactivatePromotion(ctx context.Context, id int) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE promotions SET active = FALSE, updated_at = NOW() WHERE id = $1`, id)
+	if err != nil {
+		return r.mapError(err, "DeactivatePromotion")
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *postgresRepository) GetUsage(ctx context.Context, promotionIDs []int, userID uuid.UUID) (map[int]Usage, error) {
+	query := `SELECT d.promotion_id, count(*) AS total, count(*) FILTER (WHERE o.user_id = $2) AS by_user
+		FROM order_discounts d
+		JOIN orders o ON o.id = d.order_id
+		WHERE d.promotion_id = ANY($1) AND o.status <> 'cancelled'
+		GROUP BY d.promotion_id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, promotionIDs, userID)
+	if err != nil {
+		r.log.Err(err).Str("method", "GetUsage").Msg(err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+
+	usage := make(map[int]Usage, len(promotionIDs))
+	for rows.Next() {
+		var id int
+		var u Usage
+		if err := rows.Scan(&id, &u.Total, &u.ByUser); err != nil {
+			return nil, err
+		}
+		usage[id] = u
+	}
+
+	return usage, rows.Err()
+}
+
+func (r *postgresRepository) mapError(err error, method string) error {
+	r.log.Err(err).Str("method", method).Msg(err.Error())
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrDuplicateCode
+	}
+
+	return err
+}
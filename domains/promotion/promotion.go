@@ -0,0 +1,276 @@
+package promotion
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rovilay/ecommerce-service/common/money"
+)
+
+var ErrNotFound = errors.New("promotion not found")
+var ErrDuplicateCode = errors.New("a promotion with this code already exists")
+var ErrInvalidRule = errors.New("invalid promotion rule")
+var ErrInvalidCoupon = errors.New("coupon code is invalid or expired")
+var ErrCouponNotApplicable = errors.New("coupon does not apply to this order")
+var ErrUsageLimitReached = errors.New("promotion usage limit reached")
+
+type Type string
+
+const (
+	// TypePercentage takes PercentOff percent off the eligible items.
+	TypePercentage Type = "percentage"
+	// TypeFixedAmount takes AmountOff off the eligible items, spread over them by price.
+	TypeFixedAmount Type = "fixed_amount"
+	// TypeBuyXGetY makes GetQuantity of every BuyQuantity+GetQuantity eligible units free,
+	// the cheapest ones first.
+	TypeBuyXGetY Type = "buy_x_get_y"
+	// TypeFreeShipping waives the shipping charge of the order.
+	TypeFreeShipping Type = "free_shipping"
+)
+
+// Promotion is a discount rule. Promotions without a Code apply to every order they are
+// eligible for; the others only apply to orders placed with their coupon code.
+//
+// A promotion is eligible for items whose product is in ProductIDs or whose category, or one
+// of its ancestors, is in CategoryIDs. Without either scope every item is eligible.
+// MinSubtotal, when set, is the least the eligible items must add up to.
+type Promotion struct {
+	ID          int          `json:"id" db:"id"`
+	Name        string       `json:"name" db:"name" validate:"required,max=255"`
+	Code        *string      `json:"code,omitempty" db:"code" validate:"omitempty,min=3,max=50"`
+	Type        Type         `json:"type" db:"type" validate:"required"`
+	PercentOff  *int         `json:"percent_off,omitempty" db:"percent_off"`
+	AmountOff   *money.Money `json:"amount_off,omitempty" db:"amount_off"`
+	BuyQuantity *int         `json:"buy_quantity,omitempty" db:"buy_quantity"`
+	GetQuantity *int         `json:"get_quantity,omitempty" db:"get_quantity"`
+	MinSubtotal *money.Money `json:"min_subtotal,omitempty" db:"min_subtotal"`
+	ProductIDs  IDList       `json:"product_ids" db:"product_ids"`
+	CategoryIDs IDList       `json:"category_ids" db:"category_ids"`
+	StartsAt    *time.Time   `json:"starts_at,omitempty" db:"starts_at"`
+	EndsAt      *time.Time   `json:"ends_at,omitempty" db:"ends_at"`
+	// UsageLimit caps the orders the promotion applies to, PerUserLimit the orders of each
+	// customer. Cancelled orders give their use back.
+	UsageLimit   *int      `json:"usage_limit,omitempty" db:"usage_limit" validate:"omitempty,gt=0"`
+	PerUserLimit *int      `json:"per_user_limit,omitempty" db:"per_user_limit" validate:"omitempty,gt=0"`
+	Active       bool      `json:"active" db:"active"`
+	TimesUsed    int       `json:"times_used" db:"times_used"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+}
+
+func (p *Promotion) FromJSON(r io.Reader) error {
+	return json.NewDecoder(r).Decode(p)
+}
+
+func (p *Promotion) ToJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(p)
+}
+
+// Validate checks that the promotion has the settings its type needs and no others.
+func (p *Promotion) Validate() error {
+	v := validator.New()
+	if err := v.Struct(p); err != nil {
+		return err
+	}
+
+	switch p.Type {
+	case TypePercentage:
+		if p.PercentOff == nil || *p.PercentOff < 1 || *p.PercentOff > 100 {
+			return fmt.Errorf("%w: percent_off must be between 1 and 100", ErrInvalidRule)
+		}
+	case TypeFixedAmount:
+		if p.AmountOff == nil || !p.AmountOff.IsPositive() {
+			return fmt.Errorf("%w: amount_off must be greater than 0", ErrInvalidRule)
+		}
+	case TypeBuyXGetY:
+		if p.BuyQuantity == nil || *p.BuyQuantity < 1 || p.GetQuantity == nil || *p.GetQuantity < 1 {
+			return fmt.Errorf("%w: buy_quantity and get_quantity must be at least 1", ErrInvalidRule)
+		}
+	case TypeFreeShipping:
+	default:
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidRule, p.Type)
+	}
+
+	if p.PercentOff != nil && p.Type != TypePercentage {
+		return fmt.Errorf("%w: percent_off only applies to %s promotions", ErrInvalidRule, TypePercentage)
+	}
+	if p.AmountOff != nil && p.Type != TypeFixedAmount {
+		return fmt.Errorf("%w: amount_off only applies to %s promotions", ErrInvalidRule, TypeFixedAmount)
+	}
+	if (p.BuyQuantity != nil || p.GetQuantity != nil) && p.Type != TypeBuyXGetY {
+		return fmt.Errorf("%w: buy_quantity and get_quantity only apply to %s promotions", ErrInvalidRule, TypeBuyXGetY)
+	}
+
+	if p.MinSubtotal != nil && p.MinSubtotal.IsNegative() {
+		return fmt.Errorf("%w: min_subtotal must not be negative", ErrInvalidRule)
+	}
+
+	currency := p.Currency()
+	for _, m := range []*money.Money{p.AmountOff, p.MinSubtotal} {
+		if m == nil {
+			continue
+		}
+		if err := m.Validate(); err != nil {
+			return err
+		}
+		if m.Currency != currency {
+			return fmt.Errorf("%w: amount_off and min_subtotal must be in the same currency", ErrInvalidRule)
+		}
+	}
+
+	if p.StartsAt != nil && p.EndsAt != nil && !p.EndsAt.After(*p.StartsAt) {
+		return fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidRule)
+	}
+
+	return nil
+}
+
+// Normalize trims the coupon code and upper cases it, codes are matched case-insensitively.
+func (p *Promotion) Normalize() {
+	if p.Code != nil {
+		code := NormalizeCode(*p.Code)
+		p.Code = &code
+		if code == "" {
+			p.Code = nil
+		}
+	}
+
+	if p.ProductIDs == nil {
+		p.ProductIDs = IDList{}
+	}
+	if p.CategoryIDs == nil {
+		p.CategoryIDs = IDList{}
+	}
+}
+
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Currency is the currency of the amounts of the promotion, USD when it has none.
+func (p *Promotion) Currency() string {
+	if p.AmountOff != nil {
+		return p.AmountOff.Currency
+	}
+	if p.MinSubtotal != nil {
+		return p.MinSubtotal.Currency
+	}
+
+	return money.DefaultCurrency
+}
+
+// IsAutomatic reports whether the promotion applies without a coupon code.
+func (p *Promotion) IsAutomatic() bool {
+	return p.Code == nil
+}
+
+// IsLiveAt reports whether the promotion is active and within its date window at t.
+func (p *Promotion) IsLiveAt(t time.Time) bool {
+	if !p.Active {
+		return false
+	}
+	if p.StartsAt != nil && t.Before(*p.StartsAt) {
+		return false
+	}
+	if p.EndsAt != nil && !t.Before(*p.EndsAt) {
+		return false
+	}
+
+	return true
+}
+
+// Covers reports whether the promotion's scope includes the line.
+func (p *Promotion) Covers(l Line) bool {
+	if len(p.ProductIDs) == 0 && len(p.CategoryIDs) == 0 {
+		return true
+	}
+
+	if slices.Contains(p.ProductIDs, l.ProductID) {
+		return true
+	}
+
+	for _, id := range l.CategoryIDs {
+		if slices.Contains(p.CategoryIDs, id) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Usage is how many live orders a promotion was applied to, overall and for one customer.
+type Usage struct {
+	Total  int
+	ByUser int
+}
+
+// Allows reports whether the usage is still below the promotion's limits.
+func (p *Promotion) Allows(u Usage) bool {
+	if p.UsageLimit != nil && u.Total >= *p.UsageLimit {
+		return false
+	}
+	if p.PerUserLimit != nil && u.ByUser >= *p.PerUserLimit {
+		return false
+	}
+
+	return true
+}
+
+// IDList is a list of IDs stored as a Postgres INTEGER[] column.
+type IDList []int
+
+// Scan reads an array in its text form, e.g. {1,2,3}.
+func (l *IDList) Scan(src any) error {
+	var s string
+	switch v := src.(type) {
+	case nil:
+		*l = IDList{}
+		return nil
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return fmt.Errorf("cannot scan %T into an id list", src)
+	}
+
+	s = strings.Trim(strings.TrimSpace(s), "{}")
+	ids := IDList{}
+	if s != "" {
+		for _, part := range strings.Split(s, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil {
+				return fmt.Errorf("invalid id list %q: %w", s, err)
+			}
+			ids = append(ids, id)
+		}
+	}
+
+	*l = ids
+	return nil
+}
+
+// Value writes the list as an array literal, which Postgres casts to INTEGER[].
+func (l IDList) Value() (driver.Value, error) {
+	parts := make([]string, len(l))
+	for i, id := range l {
+		parts[i] = strconv.Itoa(id)
+	}
+
+	return "{" + strings.Join(parts, ",") + "}", nil
+}
+
+type PaginationResult[T any] struct {
+	Items  []T `json:"items"`
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+	Total  int `json:"total"`
+}
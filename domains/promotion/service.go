@@ -0,0 +1,133 @@
+package promotion
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+type Service struct {
+	repo Repository
+	log  *zerolog.Logger
+}
+
+func NewService(repo Repository, l *zerolog.Logger) *Service {
+	logger := l.With().Str("service", "PromotionService").Logger()
+
+	return &Service{repo: repo, log: &logger}
+}
+
+func (s *Service) ListPromotions(ctx context.Context, limit, offset int) (*PaginationResult[*Promotion], error) {
+	promotions, total, err := s.repo.ListPromotions(ctx, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PaginationResult[*Promotion]{Items: promotions, Limit: limit, Offset: offset, Total: total}, nil
+}
+
+func (s *Service) GetPromotion(ctx context.Context, id int) (*Promotion, error) {
+	return s.repo.GetPromotion(ctx, id)
+}
+
+func (s *Service) CreatePromotion(ctx context.Context, p *Promotion) (*Promotion, error) {
+	p.Normalize()
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	return s.repo.CreatePromotion(ctx, p)
+}
+
+// UpdatePromotion replaces the settings of a promotion. Orders already placed keep the
+// discounts they were given.
+func (s *Service) UpdatePromotion(ctx context.Context, p *Promotion) (*Promotion, error) {
+	p.Normalize()
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	return s.repo.UpdatePromotion(ctx, p)
+}
+
+func (s *Service) DeactivatePromotion(ctx context.Context, id int) error {
+	return s.repo.DeactivatePromotion(ctx, id)
+}
+
+// Evaluate works out the discounts of an order of userID. The live automatic promotions
+// apply first, in the order they were created, then the promotion of couponCode if one is
+// given. Automatic promotions that do not apply or are used up are left out, whereas a
+// coupon that cannot be used is an error.
+//
+// Usage limits are only checked here to reject orders early; they are enforced again when
+// the order is stored.
+func (s *Service) Evaluate(ctx context.Context, userID uuid.UUID, couponCode string, lines []Line) ([]*Applied, error) {
+	log := s.log.With().Str("method", "Evaluate").Logger()
+	now := time.Now()
+
+	promotions, err := s.repo.GetAutomaticPromotions(ctx, now)
+	if err != nil {
+		log.Err(err).Msg("failed to load automatic promotions")
+		return nil, err
+	}
+
+	var coupon *Promotion
+	if code := NormalizeCode(couponCode); code != "" {
+		coupon, err = s.repo.GetPromotionByCode(ctx, code)
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrInvalidCoupon
+		} else if err != nil {
+			return nil, err
+		}
+
+		if !coupon.IsLiveAt(now) {
+			return nil, ErrInvalidCoupon
+		}
+
+		promotions = append(promotions, coupon)
+	}
+
+	if len(promotions) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]int, len(promotions))
+	for i, p := range promotions {
+		ids[i] = p.ID
+	}
+
+	usage, err := s.repo.GetUsage(ctx, ids, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	usable := promotions[:0]
+	for _, p := range promotions {
+		if p.Allows(usage[p.ID]) {
+			usable = append(usable, p)
+		} else if p == coupon {
+			return nil, ErrUsageLimitReached
+		}
+	}
+
+	applied := Apply(usable, lines)
+
+	if coupon != nil && !containsPromotion(applied, coupon.ID) {
+		return nil, ErrCouponNotApplicable
+	}
+
+	return applied, nil
+}
+
+func containsPromotion(applied []*Applied, id int) bool {
+	for _, a := range applied {
+		if a.Promotion.ID == id {
+			return true
+		}
+	}
+
+	return false
+}
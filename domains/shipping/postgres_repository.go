@@ -0,0 +1,142 @@
+package shipping
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog"
+)
+
+type Repository interface {
+	ListMethods(ctx context.Context, limit, offset int) ([]*Method, int, error)
+	// ListActiveMethods returns every method orders can currently be shipped with.
+	ListActiveMethods(ctx context.Context) ([]*Method, error)
+	GetMethod(ctx context.Context, id int) (*Method, error)
+	CreateMethod(ctx context.Context, m *Method) (*Method, error)
+	UpdateMethod(ctx context.Context, m *Method) (*Method, error)
+	DeactivateMethod(ctx context.Context, id int) error
+}
+
+type postgresRepository struct {
+	db  *sqlx.DB
+	log zerolog.Logger
+}
+
+// methodColumns selects a method; amounts are read with their currency as "12.34 USD".
+const methodColumns = `id, name, type, price::text || ' ' || currency AS price, tiers,
+	free_over::text || ' ' || currency AS free_over, countries, active, created_at, updated_at`
+
+func NewPostgresRepository(ctx context.Context, db *sqlx.DB, log zerolog.Logger) *postgresRepository {
+	logger := log.With().Str("repository", "shippingRepository").Logger()
+
+	// ping db
+	if err := db.PingContext(ctx); err != nil {
+		logger.Fatal().Err(fmt.Errorf("failed to connect to postgres: %w", err)).Msg("something went wrong!")
+	}
+
+	return &postgresRepository{db: db, log: logger}
+}
+
+func (r *postgresRepository) ListMethods(ctx context.Context, limit, offset int) ([]*Method, int, error) {
+	var total int
+	if err := r.db.GetContext(ctx, &total, `SELECT count(*) FROM shipping_methods`); err != nil {
+		r.log.Err(err).Str("method", "ListMethods").Msg(err.Error())
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + methodColumns + ` FROM shipping_methods ORDER BY id LIMIT $1 OFFSET $2`
+
+	methods := []*Method{}
+	if err := r.db.SelectContext(ctx, &methods, query, limit, offset); err != nil {
+		r.log.Err(err).Str("method", "ListMethods").Msg(err.Error())
+		return nil, 0, err
+	}
+
+	return methods, total, nil
+}
+
+func (r *postgresRepository) ListActiveMethods(ctx context.Context) ([]*Method, error) {
+	query := `SELECT ` + methodColumns + ` FROM shipping_methods WHERE active ORDER BY id`
+
+	methods := []*Method{}
+	if err := r.db.SelectContext(ctx, &methods, query); err != nil {
+		r.log.Err(err).Str("method", "ListActiveMethods").Msg(err.Error())
+		return nil, err
+	}
+
+	return methods, nil
+}
+
+func (r *postgresRepository) GetMethod(ctx context.Context, id int) (*Method, error) {
+	var m Method
+	query := `SELECT ` + methodColumns + ` FROM shipping_methods WHERE id = $1`
+
+	err := r.db.GetContext(ctx, &m, query, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		r.log.Err(err).Str("method", "GetMethod").Msg(err.Error())
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+func (r *postgresRepository) CreateMethod(ctx context.Context, m *Method) (*Method, error) {
+	query := `INSERT INTO shipping_methods (name, type, price, currency, tiers, free_over, countries, active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`
+
+	var id int
+	err := r.db.QueryRowContext(ctx, query,
+		m.Name, m.Type, m.Price, m.Currency(), m.Tiers, m.FreeOver, m.Countries, m.Active,
+	).Scan(&id)
+	if err != nil {
+		r.log.Err(err).Str("method", "CreateMethod").Msg(err.Error())
+		return nil, err
+	}
+
+	return r.GetMethod(ctx, id)
+}
+
+// UpdateMethod replaces a method. Orders already placed keep the charge they were quoted.
+func (r *postgresRepository) UpdateMethod(ctx context.Context, m *Method) (*Method, error) {
+	query := `UPDATE shipping_methods
+		SET name = $2, type = $3, price = $4, currency = $5, tiers = $6, free_over = $7,
+			countries = $8, active = $9, updated_at = NOW()
+		WHERE id = $1
+	`
+
+	res, err := r.db.ExecContext(ctx, query,
+		m.ID, m.Name, m.Type, m.Price, m.Currency(), m.Tiers, m.FreeOver, m.Countries, m.Active,
+	)
+	if err != nil {
+		r.log.Err(err).Str("method", "UpdateMethod").Msg(err.Error())
+		return nil, err
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, ErrNotFound
+	}
+
+	return r.GetMethod(ctx, m.ID)
+}
+
+// DeactivateMethod stops offering a method; orders keep referencing it.
+func (r *postgresRepository) DeactivateMethod(ctx context.Context, id int) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE shipping_methods SET active = FALSE, updated_at = NOW() WHERE id = $1`, id)
+	if err != nil {
+		r.log.Err(err).Str("method", "DeactivateMethod").Msg(err.Error())
+		return err
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
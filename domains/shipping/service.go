@@ -0,0 +1,105 @@
+package shipping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/rs/zerolog"
+)
+
+type Service struct {
+	repo Repository
+	log  *zerolog.Logger
+}
+
+func NewService(repo Repository, l *zerolog.Logger) *Service {
+	logger := l.With().Str("service", "ShippingService").Logger()
+
+	return &Service{repo: repo, log: &logger}
+}
+
+func (s *Service) ListMethods(ctx context.Context, limit, offset int) (*PaginationResult[*Method], error) {
+	methods, total, err := s.repo.ListMethods(ctx, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PaginationResult[*Method]{Items: methods, Limit: limit, Offset: offset, Total: total}, nil
+}
+
+func (s *Service) GetMethod(ctx context.Context, id int) (*Method, error) {
+	return s.repo.GetMethod(ctx, id)
+}
+
+func (s *Service) CreateMethod(ctx context.Context, m *Method) (*Method, error) {
+	m.Normalize()
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+
+	return s.repo.CreateMethod(ctx, m)
+}
+
+func (s *Service) UpdateMethod(ctx context.Context, m *Method) (*Method, error) {
+	m.Normalize()
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+
+	return s.repo.UpdateMethod(ctx, m)
+}
+
+func (s *Service) DeactivateMethod(ctx context.Context, id int) error {
+	return s.repo.DeactivateMethod(ctx, id)
+}
+
+// Quote prices shipping the parcel with every method that can ship it, cheapest first.
+func (s *Service) Quote(ctx context.Context, p Parcel) ([]Quote, error) {
+	methods, err := s.repo.ListActiveMethods(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	quotes := []Quote{}
+	for _, m := range methods {
+		if q, ok := m.QuoteFor(p); ok {
+			quotes = append(quotes, q)
+		}
+	}
+
+	slices.SortStableFunc(quotes, func(a, b Quote) int {
+		return int(a.Price.Amount - b.Price.Amount)
+	})
+
+	return quotes, nil
+}
+
+// QuoteMethod prices shipping the parcel with the method methodID, or with the cheapest
+// method that can ship it when methodID is nil. It returns nil when no method is given and
+// none can ship the parcel.
+func (s *Service) QuoteMethod(ctx context.Context, methodID *int, p Parcel) (*Quote, error) {
+	if methodID == nil {
+		quotes, err := s.Quote(ctx, p)
+		if err != nil || len(quotes) == 0 {
+			return nil, err
+		}
+
+		return &quotes[0], nil
+	}
+
+	m, err := s.repo.GetMethod(ctx, *methodID)
+	if errors.Is(err, ErrNotFound) {
+		return nil, fmt.Errorf("%w: unknown method %d", ErrUnavailable, *methodID)
+	} else if err != nil {
+		return nil, err
+	}
+
+	q, ok := m.QuoteFor(p)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnavailable, m.Name)
+	}
+
+	return &q, nil
+}
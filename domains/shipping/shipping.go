@@ -0,0 +1,268 @@
+package shipping
+
+import (
+	"cmp"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rovilay/ecommerce-service/common/money"
+)
+
+var ErrNotFound = errors.New("shipping method not found")
+var ErrInvalidMethod = errors.New("invalid shipping method")
+var ErrUnavailable = errors.New("shipping method is not available for this order")
+
+type Type string
+
+const (
+	// TypeFlatRate charges Price whatever the order.
+	TypeFlatRate Type = "flat_rate"
+	// TypeWeightTiered charges the price of the heaviest tier the order weighs at least
+	// MinWeightGrams of.
+	TypeWeightTiered Type = "weight_tiered"
+	// TypePriceTiered charges the price of the highest tier the order costs at least
+	// MinSubtotal of.
+	TypePriceTiered Type = "price_tiered"
+)
+
+// Method is a way orders can be shipped. Methods are only offered to addresses in Countries,
+// or to every address when it is empty, and for orders in their currency. Orders that cost
+// FreeOver or more ship for free.
+type Method struct {
+	ID        int          `json:"id" db:"id"`
+	Name      string       `json:"name" db:"name" validate:"required,max=100"`
+	Type      Type         `json:"type" db:"type" validate:"required"`
+	Price     *money.Money `json:"price,omitempty" db:"price"`
+	Tiers     Tiers        `json:"tiers" db:"tiers"`
+	FreeOver  *money.Money `json:"free_over,omitempty" db:"free_over"`
+	Countries Countries    `json:"countries" db:"countries"`
+	Active    bool         `json:"active" db:"active"`
+	CreatedAt time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at" db:"updated_at"`
+}
+
+// Tier is a price step of a tiered method. Weight tiers set MinWeightGrams, price tiers
+// MinSubtotal.
+type Tier struct {
+	MinWeightGrams int          `json:"min_weight_grams,omitempty"`
+	MinSubtotal    *money.Money `json:"min_subtotal,omitempty"`
+	Price          money.Money  `json:"price"`
+}
+
+func (m *Method) FromJSON(r io.Reader) error {
+	return json.NewDecoder(r).Decode(m)
+}
+
+func (m *Method) ToJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(m)
+}
+
+// Normalize upper cases the country codes and sorts the tiers from the lowest up.
+func (m *Method) Normalize() {
+	countries := Countries{}
+	for _, c := range m.Countries {
+		if c = strings.ToUpper(strings.TrimSpace(c)); c != "" && !slices.Contains(countries, c) {
+			countries = append(countries, c)
+		}
+	}
+	m.Countries = countries
+
+	if m.Tiers == nil {
+		m.Tiers = Tiers{}
+	}
+
+	slices.SortStableFunc(m.Tiers, func(a, b Tier) int {
+		if a.MinSubtotal != nil && b.MinSubtotal != nil {
+			return cmp.Compare(a.MinSubtotal.Amount, b.MinSubtotal.Amount)
+		}
+		return cmp.Compare(a.MinWeightGrams, b.MinWeightGrams)
+	})
+}
+
+// Validate checks that the method has the prices its type needs, all in one currency.
+func (m *Method) Validate() error {
+	v := validator.New()
+	if err := v.Struct(m); err != nil {
+		return err
+	}
+
+	amounts := []*money.Money{m.Price, m.FreeOver}
+
+	switch m.Type {
+	case TypeFlatRate:
+		if m.Price == nil || m.Price.IsNegative() {
+			return fmt.Errorf("%w: flat rate methods need a price", ErrInvalidMethod)
+		}
+		if len(m.Tiers) > 0 {
+			return fmt.Errorf("%w: flat rate methods have no tiers", ErrInvalidMethod)
+		}
+	case TypeWeightTiered, TypePriceTiered:
+		if m.Price != nil {
+			return fmt.Errorf("%w: tiered methods are priced by their tiers", ErrInvalidMethod)
+		}
+		if len(m.Tiers) == 0 {
+			return fmt.Errorf("%w: tiered methods need at least one tier", ErrInvalidMethod)
+		}
+
+		for _, t := range m.Tiers {
+			if t.Price.IsNegative() {
+				return fmt.Errorf("%w: tier prices must not be negative", ErrInvalidMethod)
+			}
+
+			if m.Type == TypeWeightTiered && (t.MinSubtotal != nil || t.MinWeightGrams < 0) {
+				return fmt.Errorf("%w: weight tiers need a min_weight_grams of 0 or more", ErrInvalidMethod)
+			}
+			if m.Type == TypePriceTiered && (t.MinSubtotal == nil || t.MinSubtotal.IsNegative() || t.MinWeightGrams != 0) {
+				return fmt.Errorf("%w: price tiers need a min_subtotal of 0 or more", ErrInvalidMethod)
+			}
+
+			amounts = append(amounts, &t.Price, t.MinSubtotal)
+		}
+	default:
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidMethod, m.Type)
+	}
+
+	if m.FreeOver != nil && m.FreeOver.IsNegative() {
+		return fmt.Errorf("%w: free_over must not be negative", ErrInvalidMethod)
+	}
+
+	currency := m.Currency()
+	for _, a := range amounts {
+		if a == nil {
+			continue
+		}
+		if err := a.Validate(); err != nil {
+			return err
+		}
+		if a.Currency != currency {
+			return fmt.Errorf("%w: all prices must be in the same currency", ErrInvalidMethod)
+		}
+	}
+
+	for _, c := range m.Countries {
+		if len(c) != 2 {
+			return fmt.Errorf("%w: countries must be ISO 3166-1 alpha-2 codes", ErrInvalidMethod)
+		}
+	}
+
+	return nil
+}
+
+// Currency is the currency the method charges in.
+func (m *Method) Currency() string {
+	if m.Price != nil {
+		return m.Price.Currency
+	}
+	if len(m.Tiers) > 0 {
+		return m.Tiers[0].Price.Currency
+	}
+
+	return money.DefaultCurrency
+}
+
+// Parcel is what is shipped: Subtotal is the price of the items after discounts.
+type Parcel struct {
+	Country     string
+	Subtotal    money.Money
+	WeightGrams int
+}
+
+// Quote is the charge of shipping a parcel with a method.
+type Quote struct {
+	MethodID int         `json:"method_id"`
+	Name     string      `json:"name"`
+	Type     Type        `json:"type"`
+	Price    money.Money `json:"price"`
+}
+
+// QuoteFor prices shipping the parcel with the method. It returns false when the method
+// cannot ship the parcel.
+func (m *Method) QuoteFor(p Parcel) (Quote, bool) {
+	if !m.Active || m.Currency() != p.Subtotal.Currency {
+		return Quote{}, false
+	}
+
+	if len(m.Countries) > 0 && !slices.Contains(m.Countries, strings.ToUpper(strings.TrimSpace(p.Country))) {
+		return Quote{}, false
+	}
+
+	var price *money.Money
+	switch m.Type {
+	case TypeFlatRate:
+		price = m.Price
+	case TypeWeightTiered:
+		for i := range m.Tiers {
+			if p.WeightGrams >= m.Tiers[i].MinWeightGrams {
+				price = &m.Tiers[i].Price
+			}
+		}
+	case TypePriceTiered:
+		for i := range m.Tiers {
+			if p.Subtotal.Amount >= m.Tiers[i].MinSubtotal.Amount {
+				price = &m.Tiers[i].Price
+			}
+		}
+	}
+
+	if price == nil {
+		return Quote{}, false
+	}
+
+	q := Quote{MethodID: m.ID, Name: m.Name, Type: m.Type, Price: *price}
+	if m.FreeOver != nil && p.Subtotal.Amount >= m.FreeOver.Amount {
+		q.Price = money.New(0, price.Currency)
+	}
+
+	return q, true
+}
+
+// Tiers is stored as a JSONB column.
+type Tiers []Tier
+
+func (t *Tiers) Scan(src any) error {
+	return scanJSON(src, t)
+}
+
+func (t Tiers) Value() (driver.Value, error) {
+	b, err := json.Marshal(t)
+	return string(b), err
+}
+
+// Countries is stored as a JSONB column.
+type Countries []string
+
+func (c *Countries) Scan(src any) error {
+	return scanJSON(src, c)
+}
+
+func (c Countries) Value() (driver.Value, error) {
+	b, err := json.Marshal(c)
+	return string(b), err
+}
+
+func scanJSON(src any, dst any) error {
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, dst)
+	case string:
+		return json.Unmarshal([]byte(v), dst)
+	default:
+		return fmt.Errorf("failed to unmarshal JSONB value: %v", src)
+	}
+}
+
+type PaginationResult[T any] struct {
+	Items  []T `json:"items"`
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+	Total  int `json:"total"`
+}
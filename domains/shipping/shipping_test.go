@@ -0,0 +1,171 @@
+package shipping
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/rovilay/ecommerce-service/common/money"
+)
+
+func usd(amount int64) *money.Money {
+	m := money.New(amount, "USD")
+	return &m
+}
+
+func weightTier(minGrams int, price int64) Tier {
+	return Tier{MinWeightGrams: minGrams, Price: *usd(price)}
+}
+
+func priceTier(minSubtotal, price int64) Tier {
+	return Tier{MinSubtotal: usd(minSubtotal), Price: *usd(price)}
+}
+
+func TestQuoteFor(t *testing.T) {
+	flat := &Method{ID: 1, Name: "Standard", Type: TypeFlatRate, Price: usd(500), Active: true}
+	byWeight := &Method{ID: 2, Name: "Parcel", Type: TypeWeightTiered, Active: true,
+		Tiers: Tiers{weightTier(0, 400), weightTier(1000, 800), weightTier(5000, 1500)}}
+	byPrice := &Method{ID: 3, Name: "Tiered", Type: TypePriceTiered, Active: true,
+		Tiers: Tiers{priceTier(0, 900), priceTier(5000, 500), priceTier(10000, 200)}}
+	heavyOnly := &Method{ID: 4, Name: "Freight", Type: TypeWeightTiered, Active: true, Tiers: Tiers{weightTier(20000, 5000)}}
+	freeOver := &Method{ID: 5, Name: "Free over 50", Type: TypeFlatRate, Price: usd(500), FreeOver: usd(5000), Active: true}
+	domestic := &Method{ID: 6, Name: "Domestic", Type: TypeFlatRate, Price: usd(300), Countries: Countries{"US", "CA"}, Active: true}
+	inactive := &Method{ID: 7, Name: "Retired", Type: TypeFlatRate, Price: usd(100)}
+
+	parcel := func(country string, subtotal int64, grams int) Parcel {
+		return Parcel{Country: country, Subtotal: *usd(subtotal), WeightGrams: grams}
+	}
+
+	tests := []struct {
+		name   string
+		method *Method
+		parcel Parcel
+		want   int64
+		wantOK bool
+	}{
+		{name: "flat rate", method: flat, parcel: parcel("NG", 100, 10), want: 500, wantOK: true},
+		{name: "lightest tier", method: byWeight, parcel: parcel("NG", 100, 0), want: 400, wantOK: true},
+		{name: "just below a weight tier", method: byWeight, parcel: parcel("NG", 100, 999), want: 400, wantOK: true},
+		{name: "at a weight tier", method: byWeight, parcel: parcel("NG", 100, 1000), want: 800, wantOK: true},
+		{name: "heaviest tier", method: byWeight, parcel: parcel("NG", 100, 90000), want: 1500, wantOK: true},
+		{name: "below every weight tier", method: heavyOnly, parcel: parcel("NG", 100, 19999), wantOK: false},
+		{name: "lowest price tier", method: byPrice, parcel: parcel("NG", 0, 0), want: 900, wantOK: true},
+		{name: "just below a price tier", method: byPrice, parcel: parcel("NG", 4999, 0), want: 900, wantOK: true},
+		{name: "at a price tier", method: byPrice, parcel: parcel("NG", 5000, 0), want: 500, wantOK: true},
+		{name: "top price tier", method: byPrice, parcel: parcel("NG", 25000, 0), want: 200, wantOK: true},
+		{name: "below free over", method: freeOver, parcel: parcel("NG", 4999, 0), want: 500, wantOK: true},
+		{name: "at free over", method: freeOver, parcel: parcel("NG", 5000, 0), want: 0, wantOK: true},
+		{name: "listed country", method: domestic, parcel: parcel("CA", 100, 0), want: 300, wantOK: true},
+		{name: "listed country in another case", method: domestic, parcel: parcel(" us ", 100, 0), want: 300, wantOK: true},
+		{name: "unlisted country", method: domestic, parcel: parcel("NG", 100, 0), wantOK: false},
+		{name: "inactive", method: inactive, parcel: parcel("NG", 100, 0), wantOK: false},
+		{name: "currency mismatch", method: flat, parcel: Parcel{Country: "NG", Subtotal: money.New(100, "EUR")}, wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, ok := tt.method.QuoteFor(tt.parcel)
+			if ok != tt.wantOK {
+				t.Fatalf("QuoteFor() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+
+			want := Quote{MethodID: tt.method.ID, Name: tt.method.Name, Type: tt.method.Type, Price: *usd(tt.want)}
+			if q != want {
+				t.Errorf("QuoteFor() = %+v, want %+v", q, want)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	m := &Method{
+		Countries: Countries{" us", "ca ", "US", ""},
+		Tiers:     Tiers{priceTier(math.MaxInt64, 0), priceTier(5000, 500), priceTier(math.MinInt64+1, 900), priceTier(0, 700)},
+	}
+	m.Normalize()
+
+	if want := (Countries{"US", "CA"}); !slices.Equal(m.Countries, want) {
+		t.Errorf("countries = %v, want %v", m.Countries, want)
+	}
+
+	// amounts far apart would overflow a subtraction
+	var mins []int64
+	for _, tier := range m.Tiers {
+		mins = append(mins, tier.MinSubtotal.Amount)
+	}
+	if want := []int64{math.MinInt64 + 1, 0, 5000, math.MaxInt64}; !slices.Equal(mins, want) {
+		t.Errorf("price tiers sorted as %v, want %v", mins, want)
+	}
+
+	m = &Method{Tiers: Tiers{weightTier(5000, 1), weightTier(0, 2), weightTier(1000, 3)}}
+	m.Normalize()
+
+	var grams []int
+	for _, tier := range m.Tiers {
+		grams = append(grams, tier.MinWeightGrams)
+	}
+	if want := []int{0, 1000, 5000}; !slices.Equal(grams, want) {
+		t.Errorf("weight tiers sorted as %v, want %v", grams, want)
+	}
+
+	m = &Method{}
+	m.Normalize()
+	if m.Tiers == nil || m.Countries == nil {
+		t.Errorf("Normalize() left nil tiers or countries: %+v", m)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	eur := money.New(100, "EUR")
+	negative := money.New(-1, "USD")
+
+	tests := []struct {
+		name    string
+		method  Method
+		wantErr bool
+	}{
+		{name: "flat rate", method: Method{Name: "a", Type: TypeFlatRate, Price: usd(500), FreeOver: usd(5000), Countries: Countries{"US"}}},
+		{name: "free flat rate", method: Method{Name: "a", Type: TypeFlatRate, Price: usd(0)}},
+		{name: "weight tiers", method: Method{Name: "a", Type: TypeWeightTiered, Tiers: Tiers{weightTier(0, 400), weightTier(1000, 800)}}},
+		{name: "price tiers", method: Method{Name: "a", Type: TypePriceTiered, Tiers: Tiers{priceTier(0, 900), priceTier(5000, 0)}}},
+		{name: "no name", method: Method{Type: TypeFlatRate, Price: usd(500)}, wantErr: true},
+		{name: "unknown type", method: Method{Name: "a", Type: "express", Price: usd(500)}, wantErr: true},
+		{name: "flat rate without a price", method: Method{Name: "a", Type: TypeFlatRate}, wantErr: true},
+		{name: "negative flat rate", method: Method{Name: "a", Type: TypeFlatRate, Price: &negative}, wantErr: true},
+		{name: "flat rate with tiers", method: Method{Name: "a", Type: TypeFlatRate, Price: usd(500), Tiers: Tiers{weightTier(0, 1)}}, wantErr: true},
+		{name: "tiered with a price", method: Method{Name: "a", Type: TypeWeightTiered, Price: usd(500), Tiers: Tiers{weightTier(0, 1)}}, wantErr: true},
+		{name: "tiered without tiers", method: Method{Name: "a", Type: TypePriceTiered}, wantErr: true},
+		{name: "negative tier price", method: Method{Name: "a", Type: TypeWeightTiered, Tiers: Tiers{{Price: negative}}}, wantErr: true},
+		{name: "negative weight", method: Method{Name: "a", Type: TypeWeightTiered, Tiers: Tiers{weightTier(-1, 400)}}, wantErr: true},
+		{name: "price tier in weight tiers", method: Method{Name: "a", Type: TypeWeightTiered, Tiers: Tiers{weightTier(0, 400), priceTier(5000, 200)}}, wantErr: true},
+		{name: "weight tier in price tiers", method: Method{Name: "a", Type: TypePriceTiered, Tiers: Tiers{priceTier(0, 900), weightTier(1000, 200)}}, wantErr: true},
+		{name: "price tier with a weight too", method: Method{Name: "a", Type: TypePriceTiered, Tiers: Tiers{{MinSubtotal: usd(0), MinWeightGrams: 10, Price: *usd(1)}}}, wantErr: true},
+		{name: "negative min subtotal", method: Method{Name: "a", Type: TypePriceTiered, Tiers: Tiers{{MinSubtotal: &negative, Price: *usd(1)}}}, wantErr: true},
+		{name: "negative free over", method: Method{Name: "a", Type: TypeFlatRate, Price: usd(500), FreeOver: &negative}, wantErr: true},
+		{name: "mixed currencies", method: Method{Name: "a", Type: TypeFlatRate, Price: usd(500), FreeOver: &eur}, wantErr: true},
+		{name: "mixed tier currencies", method: Method{Name: "a", Type: TypePriceTiered, Tiers: Tiers{priceTier(0, 900), {MinSubtotal: &eur, Price: eur}}}, wantErr: true},
+		{name: "bad country", method: Method{Name: "a", Type: TypeFlatRate, Price: usd(500), Countries: Countries{"USA"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.method.Validate()
+			if !tt.wantErr {
+				if err != nil {
+					t.Errorf("Validate() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Validate() = nil, want an error")
+			}
+			if tt.name != "no name" && !errors.Is(err, ErrInvalidMethod) {
+				t.Errorf("Validate() error = %v, want %v", err, ErrInvalidMethod)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package tax
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog"
+)
+
+type Repository interface {
+	ListTaxRates(ctx context.Context, limit, offset int) ([]*TaxRate, int, error)
+	GetTaxRate(ctx context.Context, id int) (*TaxRate, error)
+	// FindTaxRate returns the rate of the state of country, or else the rate of the country.
+	FindTaxRate(ctx context.Context, country, state string) (*TaxRate, error)
+	CreateTaxRate(ctx context.Context, t *TaxRate) (*TaxRate, error)
+	UpdateTaxRate(ctx context.Context, t *TaxRate) (*TaxRate, error)
+	DeleteTaxRate(ctx context.Context, id int) error
+}
+
+type postgresRepository struct {
+	db  *sqlx.DB
+	log zerolog.Logger
+}
+
+const taxRateColumns = `id, country, state, name, rate, created_at, updated_at`
+
+func NewPostgresRepository(ctx context.Context, db *sqlx.DB, log zerolog.Logger) *postgresRepository {
+	logger := log.With().Str("repository", "taxRepository").Logger()
+
+	// ping db
+	if err := db.PingContext(ctx); err != nil {
+		logger.Fatal().Err(fmt.Errorf("failed to connect to postgres: %w", err)).Msg("something went wrong!")
+	}
+
+	return &postgresRepository{db: db, log: logger}
+}
+
+func (r *postgresRepository) ListTaxRates(ctx context.Context, limit, offset int) ([]*TaxRate, int, error) {
+	var total int
+	if err := r.db.GetContext(ctx, &total, `SELECT count(*) FROM tax_rates`); err != nil {
+		r.log.Err(err).Str("method", "ListTaxRates").Msg(err.Error())
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + taxRateColumns + ` FROM tax_rates ORDER BY country, state LIMIT $1 OFFSET $2`
+
+	rates := []*TaxRate{}
+	if err := r.db.SelectContext(ctx, &rates, query, limit, offset); err != nil {
+		r.log.Err(err).Str("method", "ListTaxRates").Msg(err.Error())
+		return nil, 0, err
+	}
+
+	if err := r.loadExemptions(ctx, r.db, rates...); err != nil {
+		r.log.Err(err).Str("method", "ListTaxRates").Msg(err.Error())
+		return nil, 0, err
+	}
+
+	return rates, total, nil
+}
+
+func (r *postgresRepository) GetTaxRate(ctx context.Context, id int) (*TaxRate, error) {
+	return r.getTaxRate(ctx, r.db, `WHERE id = $1`, id)
+}
+
+func (r *postgresRepository) FindTaxRate(ctx context.Context, country, state string) (*TaxRate, error) {
+	return r.getTaxRate(ctx, r.db, `WHERE country = upper($1) AND (state = '' OR upper(state) = upper($2))
+		ORDER BY state = '' LIMIT 1`, country, state)
+}
+
+func (r *postgresRepository) getTaxRate(ctx context.Context, q sqlx.QueryerContext, where string, args ...any) (*TaxRate, error) {
+	var t TaxRate
+	query := `SELECT ` + taxRateColumns + ` FROM tax_rates ` + where
+
+	err := sqlx.GetContext(ctx, q, &t, query, args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		r.log.Err(err).Str("method", "getTaxRate").Msg(err.Error())
+		return nil, err
+	}
+
+	if err = r.loadExemptions(ctx, q, &t); err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+func (r *postgresRepository) CreateTaxRate(ctx context.Context, t *TaxRate) (*TaxRate, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO tax_rates (country, state, name, rate) VALUES ($1, $2, $3, $4) RETURNING id`
+	if err = tx.QueryRowContext(ctx, query, t.Country, t.State, t.Name, t.Rate).Scan(&t.ID); err != nil {
+		return nil, r.mapError(err, "CreateTaxRate")
+	}
+
+	return r.commitTaxRate(ctx, tx, t)
+}
+
+func (r *postgresRepository) UpdateTaxRate(ctx context.Context, t *TaxRate) (*TaxRate, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE tax_rates SET country = $2, state = $3, name = $4, rate = $5, updated_at = NOW() WHERE id = $1`
+	res, err := tx.ExecContext(ctx, query, t.ID, t.Country, t.State, t.Name, t.Rate)
+	if err != nil {
+		return nil, r.mapError(err, "UpdateTaxRate")
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, ErrNotFound
+	}
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM tax_rate_exemptions WHERE tax_rate_id = $1`, t.ID); err != nil {
+		return nil, r.mapError(err, "UpdateTaxRate")
+	}
+
+	return r.commitTaxRate(ctx, tx, t)
+}
+
+// DeleteTaxRate removes a rate. Orders keep the rate they were taxed at.
+func (r *postgresRepository) DeleteTaxRate(ctx context.Context, id int) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM tax_rates WHERE id = $1`, id)
+	if err != nil {
+		return r.mapError(err, "DeleteTaxRate")
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// commitTaxRate stores the exemptions of t, commits tx and returns the rate as stored.
+func (r *postgresRepository) commitTaxRate(ctx context.Context, tx *sqlx.Tx, t *TaxRate) (*TaxRate, error) {
+	query := `INSERT INTO tax_rate_exemptions (tax_rate_id, category_id)
+		SELECT $1, unnest($2::int[])
+		ON CONFLICT DO NOTHING
+	`
+	if _, err := tx.ExecContext(ctx, query, t.ID, t.ExemptCategoryIDs); err != nil {
+		return nil, r.mapError(err, "commitTaxRate")
+	}
+
+	stored, err := r.getTaxRate(ctx, tx, `WHERE id = $1`, t.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return stored, nil
+}
+
+func (r *postgresRepository) loadExemptions(ctx context.Context, q sqlx.QueryerContext, rates ...*TaxRate) error {
+	if len(rates) == 0 {
+		return nil
+	}
+
+	ids := make([]int, len(rates))
+	byID := make(map[int]*TaxRate, len(rates))
+	for i, t := range rates {
+		ids[i] = t.ID
+		t.ExemptCategoryIDs = []int{}
+		byID[t.ID] = t
+	}
+
+	query := `SELECT tax_rate_id, category_id FROM tax_rate_exemptions WHERE tax_rate_id = ANY($1) ORDER BY category_id`
+	rows, err := q.QueryContext(ctx, query, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rateID, categoryID int
+		if err := rows.Scan(&rateID, &categoryID); err != nil {
+			return err
+		}
+		byID[rateID].ExemptCategoryIDs = append(byID[rateID].ExemptCategoryIDs, categoryID)
+	}
+
+	return rows.Err()
+}
+
+func (r *postgresRepository) mapError(err error, method string) error {
+	r.log.Err(err).Str("method", method).Msg(err.Error())
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23505":
+			return ErrDuplicateRate
+		case "23503":
+			return ErrInvalidCategory
+		}
+	}
+
+	return err
+}
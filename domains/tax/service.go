@@ -0,0 +1,55 @@
+package tax
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+)
+
+type Service struct {
+	repo Repository
+	log  *zerolog.Logger
+}
+
+func NewService(repo Repository, l *zerolog.Logger) *Service {
+	logger := l.With().Str("service", "TaxService").Logger()
+
+	return &Service{repo: repo, log: &logger}
+}
+
+func (s *Service) ListTaxRates(ctx context.Context, limit, offset int) (*PaginationResult[*TaxRate], error) {
+	rates, total, err := s.repo.ListTaxRates(ctx, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PaginationResult[*TaxRate]{Items: rates, Limit: limit, Offset: offset, Total: total}, nil
+}
+
+func (s *Service) GetTaxRate(ctx context.Context, id int) (*TaxRate, error) {
+	return s.repo.GetTaxRate(ctx, id)
+}
+
+func (s *Service) CreateTaxRate(ctx context.Context, t *TaxRate) (*TaxRate, error) {
+	t.Normalize()
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+
+	return s.repo.CreateTaxRate(ctx, t)
+}
+
+// UpdateTaxRate replaces a rate and its exemptions. Orders already placed keep the rate
+// they were taxed at.
+func (s *Service) UpdateTaxRate(ctx context.Context, t *TaxRate) (*TaxRate, error) {
+	t.Normalize()
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+
+	return s.repo.UpdateTaxRate(ctx, t)
+}
+
+func (s *Service) DeleteTaxRate(ctx context.Context, id int) error {
+	return s.repo.DeleteTaxRate(ctx, id)
+}
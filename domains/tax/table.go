@@ -0,0 +1,46 @@
+package tax
+
+import (
+	"context"
+	"errors"
+
+	"github.com/rovilay/ecommerce-service/common/money"
+)
+
+// TableCalculator taxes orders with the rates kept in the tax_rates table. Addresses without
+// a rate are not taxed.
+type TableCalculator struct {
+	repo Repository
+}
+
+func NewTableCalculator(repo Repository) *TableCalculator {
+	return &TableCalculator{repo: repo}
+}
+
+func (c *TableCalculator) Calculate(ctx context.Context, addr Address, lines []Line) ([]LineTax, error) {
+	rate, err := c.repo.FindTaxRate(ctx, addr.Country, addr.State)
+	if errors.Is(err, ErrNotFound) {
+		rate = nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	taxes := make([]LineTax, len(lines))
+	for i, l := range lines {
+		taxes[i] = LineTax{Amount: money.New(0, l.Amount.Currency)}
+		if rate == nil {
+			continue
+		}
+
+		taxes[i].RateID = &rate.ID
+		taxes[i].Name = rate.Name
+		if rate.IsExempt(l.CategoryIDs) {
+			continue
+		}
+
+		taxes[i].Rate = rate.Rate
+		taxes[i].Amount = rate.Rate.Of(l.Amount)
+	}
+
+	return taxes, nil
+}
@@ -0,0 +1,220 @@
+package tax
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rovilay/ecommerce-service/common/money"
+)
+
+var ErrNotFound = errors.New("tax rate not found")
+var ErrDuplicateRate = errors.New("a tax rate for this country and state already exists")
+var ErrInvalidRate = errors.New("tax rate must be a percentage between 0 and 100 with at most four decimal places")
+var ErrInvalidCategory = errors.New("exempt category does not exist")
+
+// TaxCalculator works out the tax of order lines shipped to an address.
+type TaxCalculator interface {
+	Calculate(ctx context.Context, addr Address, lines []Line) ([]LineTax, error)
+}
+
+// Address is where an order is shipped, which decides how it is taxed.
+type Address struct {
+	Country    string
+	State      string
+	PostalCode string
+}
+
+// Line is an order line to tax. Amount is what the line costs after discounts, and
+// CategoryIDs holds the category of the product and all of its ancestors.
+type Line struct {
+	ProductID   int
+	CategoryIDs []int
+	Amount      money.Money
+}
+
+// LineTax is the tax of a line and the rate it was worked out with. RateID is nil when no
+// rate covers the address.
+type LineTax struct {
+	RateID *int
+	Name   string
+	Rate   Rate
+	Amount money.Money
+}
+
+// TaxRate is the sales tax of a country, or of a state of it. The rate of a state takes
+// precedence over the rate of its country. Products in ExemptCategoryIDs, or in one of their
+// subcategories, are not taxed.
+type TaxRate struct {
+	ID                int       `json:"id" db:"id"`
+	Country           string    `json:"country" db:"country" validate:"required,len=2"`
+	State             string    `json:"state" db:"state" validate:"max=100"`
+	Name              string    `json:"name" db:"name" validate:"required,max=100"`
+	Rate              Rate      `json:"rate" db:"rate"`
+	ExemptCategoryIDs []int     `json:"exempt_category_ids" db:"-"`
+	CreatedAt         time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at" db:"updated_at"`
+}
+
+func (t *TaxRate) FromJSON(r io.Reader) error {
+	return json.NewDecoder(r).Decode(t)
+}
+
+func (t *TaxRate) ToJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(t)
+}
+
+func (t *TaxRate) Validate() error {
+	v := validator.New()
+	if err := v.Struct(t); err != nil {
+		return err
+	}
+
+	if t.Rate < 0 || t.Rate > maxRate {
+		return ErrInvalidRate
+	}
+
+	return nil
+}
+
+// Normalize upper cases the country code and trims the state; states are matched
+// case-insensitively.
+func (t *TaxRate) Normalize() {
+	t.Country = strings.ToUpper(strings.TrimSpace(t.Country))
+	t.State = strings.TrimSpace(t.State)
+
+	if t.ExemptCategoryIDs == nil {
+		t.ExemptCategoryIDs = []int{}
+	}
+}
+
+// IsExempt reports whether a product in categoryIDs is exempt from the rate.
+func (t *TaxRate) IsExempt(categoryIDs []int) bool {
+	for _, exempt := range t.ExemptCategoryIDs {
+		for _, id := range categoryIDs {
+			if id == exempt {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Rate is a tax rate in millionths of the taxed amount, so 7.25% is 72500. It is written as
+// a percentage with up to four decimal places, e.g. "7.25".
+type Rate int64
+
+const (
+	ratePrecision = 10_000 // per percent
+	maxRate       = 100 * ratePrecision
+)
+
+// ParseRate reads a percentage such as "7.25".
+func ParseRate(s string) (Rate, error) {
+	s = strings.TrimSpace(s)
+	whole, frac, _ := strings.Cut(s, ".")
+	frac = strings.TrimRight(frac, "0")
+	if whole == "" || len(frac) > 4 || !isDigits(whole) || !isDigits(frac) {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidRate, s)
+	}
+	frac += strings.Repeat("0", 4-len(frac))
+
+	w, err := strconv.ParseInt(whole, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidRate, s)
+	}
+	f, err := strconv.ParseInt(frac, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidRate, s)
+	}
+
+	return Rate(w*ratePrecision + f), nil
+}
+
+// isDigits reports whether s holds nothing but ASCII digits; signs are not digits.
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Of returns the tax on amount at the rate, rounded half up to the minor unit.
+func (r Rate) Of(amount money.Money) money.Money {
+	return money.New((amount.Amount*int64(r)+500_000)/1_000_000, amount.Currency)
+}
+
+func (r Rate) String() string {
+	s := fmt.Sprintf("%d.%04d", r/ratePrecision, r%ratePrecision)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+// UnmarshalJSON accepts the percentage as a string or a number.
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidRate, data)
+	}
+
+	parsed, err := ParseRate(n.String())
+	if err != nil {
+		return err
+	}
+
+	*r = parsed
+	return nil
+}
+
+func (r *Rate) Scan(src any) error {
+	var s string
+	switch v := src.(type) {
+	case nil:
+		*r = 0
+		return nil
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	case int64:
+		s = strconv.FormatInt(v, 10)
+	case float64:
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Errorf("%w: cannot scan %T", ErrInvalidRate, src)
+	}
+
+	parsed, err := ParseRate(s)
+	if err != nil {
+		return err
+	}
+
+	*r = parsed
+	return nil
+}
+
+func (r Rate) Value() (driver.Value, error) {
+	return r.String(), nil
+}
+
+type PaginationResult[T any] struct {
+	Items  []T `json:"items"`
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+	Total  int `json:"total"`
+}
@@ -0,0 +1,210 @@
+package tax
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/rovilay/ecommerce-service/common/money"
+)
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Rate
+		wantErr bool
+	}{
+		{in: "7.25", want: 72500},
+		{in: "7.2500", want: 72500},
+		{in: " 7.25 ", want: 72500},
+		{in: "7", want: 70000},
+		{in: "7.", want: 70000},
+		{in: "0", want: 0},
+		{in: "0.0001", want: 1},
+		{in: "1.23450", want: 12345},
+		{in: "100", want: maxRate},
+		{in: "-1", wantErr: true},
+		{in: "+1", wantErr: true},
+		{in: "7.-5", wantErr: true},
+		{in: "7.+5", wantErr: true},
+		{in: "1.23456", wantErr: true},
+		{in: "", wantErr: true},
+		{in: ".5", wantErr: true},
+		{in: "abc", wantErr: true},
+		{in: "1e2", wantErr: true},
+		{in: "7,25", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseRate(tt.in)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidRate) {
+					t.Fatalf("ParseRate(%q) = %d, %v, want %v", tt.in, got, err, ErrInvalidRate)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseRate(%q) unexpected error: %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseRate(%q) = %d, want %d", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateString(t *testing.T) {
+	tests := []struct {
+		rate Rate
+		want string
+	}{
+		{72500, "7.25"},
+		{70000, "7"},
+		{1, "0.0001"},
+		{0, "0"},
+		{maxRate, "100"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.rate.String(); got != tt.want {
+			t.Errorf("Rate(%d).String() = %q, want %q", tt.rate, got, tt.want)
+		}
+	}
+}
+
+func TestRateOf(t *testing.T) {
+	tests := []struct {
+		rate   string
+		amount int64
+		want   int64
+	}{
+		{rate: "10", amount: 1000, want: 100},
+		{rate: "7.25", amount: 1000, want: 73},   // 72.5 rounds up
+		{rate: "7.25", amount: 200, want: 15},    // 14.5 rounds up
+		{rate: "7.25", amount: 100, want: 7},     // 7.25 rounds down
+		{rate: "8.875", amount: 1999, want: 177}, // 177.41125
+		{rate: "0.01", amount: 4999, want: 0},    // 0.4999
+		{rate: "0.01", amount: 5000, want: 1},    // 0.5
+		{rate: "0", amount: 1000, want: 0},
+		{rate: "100", amount: 1234, want: 1234},
+	}
+
+	for _, tt := range tests {
+		rate, err := ParseRate(tt.rate)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := rate.Of(money.New(tt.amount, "EUR"))
+		if got != money.New(tt.want, "EUR") {
+			t.Errorf("%s%% of %d = %v, want %d EUR", tt.rate, tt.amount, got, tt.want)
+		}
+	}
+}
+
+func TestRateScan(t *testing.T) {
+	tests := []struct {
+		src     any
+		want    Rate
+		wantErr bool
+	}{
+		{src: "7.2500", want: 72500},
+		{src: []byte("8.875"), want: 88750},
+		{src: int64(5), want: 50000},
+		{src: 6.5, want: 65000},
+		{src: nil, want: 0},
+		{src: "-1", wantErr: true},
+		{src: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		r := Rate(1)
+		err := r.Scan(tt.src)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidRate) {
+				t.Errorf("Scan(%v) error = %v, want %v", tt.src, err, ErrInvalidRate)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Scan(%v) unexpected error: %v", tt.src, err)
+		}
+		if r != tt.want {
+			t.Errorf("Scan(%v) = %d, want %d", tt.src, r, tt.want)
+		}
+	}
+}
+
+func TestRateJSON(t *testing.T) {
+	for _, in := range []string{`"7.25"`, `7.25`, `"7.2500"`} {
+		var r Rate
+		if err := json.Unmarshal([]byte(in), &r); err != nil || r != 72500 {
+			t.Errorf("Unmarshal(%s) = %d, %v, want 72500", in, r, err)
+		}
+	}
+
+	var r Rate
+	if err := json.Unmarshal([]byte(`"-1"`), &r); !errors.Is(err, ErrInvalidRate) {
+		t.Errorf("Unmarshal(\"-1\") error = %v, want %v", err, ErrInvalidRate)
+	}
+
+	b, err := json.Marshal(Rate(72500))
+	if err != nil || string(b) != `"7.25"` {
+		t.Errorf("Marshal(72500) = %s, %v, want \"7.25\"", b, err)
+	}
+}
+
+// rateRepository answers FindTaxRate with a fixed rate, or ErrNotFound when it has none.
+type rateRepository struct {
+	Repository
+	rate *TaxRate
+}
+
+func (r *rateRepository) FindTaxRate(ctx context.Context, country, state string) (*TaxRate, error) {
+	if r.rate == nil {
+		return nil, ErrNotFound
+	}
+	return r.rate, nil
+}
+
+func TestTableCalculator(t *testing.T) {
+	rate := &TaxRate{ID: 3, Country: "US", State: "CA", Name: "CA sales tax", Rate: 72500, ExemptCategoryIDs: []int{10}}
+	lines := []Line{
+		{ProductID: 1, CategoryIDs: []int{4, 2}, Amount: money.New(1000, "USD")},
+		// a subcategory of the exempt category 10
+		{ProductID: 2, CategoryIDs: []int{11, 10}, Amount: money.New(1000, "USD")},
+		{ProductID: 3, Amount: money.New(200, "USD")},
+	}
+	addr := Address{Country: "US", State: "CA"}
+
+	taxes, err := NewTableCalculator(&rateRepository{rate: rate}).Calculate(context.Background(), addr, lines)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		rate   Rate
+		amount int64
+	}{{72500, 73}, {0, 0}, {72500, 15}}
+	for i, tax := range taxes {
+		if tax.RateID == nil || *tax.RateID != 3 || tax.Name != "CA sales tax" {
+			t.Errorf("line %d taxed with %v %q, want rate 3", i, tax.RateID, tax.Name)
+		}
+		if tax.Rate != want[i].rate || tax.Amount != money.New(want[i].amount, "USD") {
+			t.Errorf("line %d tax = %s%% %v, want %s%% %d USD", i, tax.Rate, tax.Amount, want[i].rate, want[i].amount)
+		}
+	}
+
+	// addresses without a rate are not taxed
+	taxes, err = NewTableCalculator(&rateRepository{}).Calculate(context.Background(), Address{Country: "NG"}, lines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, tax := range taxes {
+		if tax.RateID != nil || tax.Amount != money.New(0, "USD") {
+			t.Errorf("line %d without a rate = %+v, want no tax", i, tax)
+		}
+	}
+}
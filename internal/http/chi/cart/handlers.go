@@ -137,6 +137,33 @@ func (h *CartHandler) ClearCart(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+func (h *CartHandler) GetUserCart(w http.ResponseWriter, r *http.Request) {
+	log := h.log.With().Str("method", "GetUserCart").Logger()
+
+	cart, err := h.service.GetUserCart(r.Context(), chi.URLParam(r, "userID"))
+	if err != nil {
+		h.sendError(w, err, "", 0, &log)
+		return
+	}
+
+	if err := cart.ToJSON(w); err != nil {
+		h.sendError(w, err, "failed to marshal", 0, &log)
+		return
+	}
+}
+
+func (h *CartHandler) ClearUserCart(w http.ResponseWriter, r *http.Request) {
+	log := h.log.With().Str("method", "ClearUserCart").Logger()
+
+	err := h.service.ClearUserCart(r.Context(), chi.URLParam(r, "userID"))
+	if err != nil {
+		h.sendError(w, err, "", 0, &log)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (h *CartHandler) sendError(w http.ResponseWriter, err error, errMsg string, statusCode int, log *zerolog.Logger) {
 	log.Err(err)
 	if errMsg == "" {
@@ -52,6 +52,27 @@ func (h *CartHandler) MiddlewareAuth(next http.Handler) http.Handler {
 	})
 }
 
+// MiddlewareRequireService only lets requests through that carry a valid service token addressed to this service.
+func (a *CartApp) MiddlewareRequireService(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenString := r.Header.Get(utils.ServiceTokenHeader)
+		if tokenString == "" {
+			ErrUnauthorized(w, utils.ErrMissingServiceToken)
+			return
+		}
+
+		claims, err := utils.ValidateServiceToken(tokenString, []byte(a.config.ServiceAuthSecret), utils.ServiceCart)
+		if err != nil {
+			a.log.Err(err).Msg("error validating service token")
+			ErrUnauthorized(w, utils.ErrInvalidServiceToken)
+			return
+		}
+
+		a.log.Debug().Str("caller", claims.Issuer).Msg("service request")
+		next.ServeHTTP(w, r)
+	})
+}
+
 // ErrUnauthorized is a helper for consistent unauthorized responses
 func ErrUnauthorized(w http.ResponseWriter, err error) {
 	errRes := fmt.Sprintf(`{"error": "%v"}`, err.Error())
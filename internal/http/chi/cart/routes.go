@@ -34,6 +34,7 @@ func (a *CartApp) loadRoutes() {
 	})
 
 	router.Route("/api/v1/cart", a.loadCartRoutes)
+	router.Route("/api/v1/internal/carts", a.loadInternalCartRoutes)
 
 	// CORS configuration
 	corsRouter := cors.Default().Handler(router)
@@ -58,3 +59,14 @@ func (a *CartApp) loadCartRoutes(router chi.Router) {
 		r.Put("/items/{id}", h.UpdateCartItemQuantity)
 	})
 }
+
+// loadInternalCartRoutes serves other services acting on behalf of a user.
+func (a *CartApp) loadInternalCartRoutes(router chi.Router) {
+	h := NewCartHandler(a.service, a.log)
+
+	router.Group(func(r chi.Router) {
+		r.Use(a.MiddlewareRequireService)
+		r.Get("/{userID}", h.GetUserCart)
+		r.Delete("/{userID}", h.ClearUserCart)
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/rovilay/ecommerce-service/config"
+	"github.com/rovilay/ecommerce-service/domains/auth"
 	"github.com/rovilay/ecommerce-service/domains/inventory/service"
 	"github.com/rs/zerolog"
 )
@@ -15,16 +16,18 @@ type InventoryApp struct {
 	router  http.Handler
 	config  *config.InventoryConfig
 	log     *zerolog.Logger
+	auth    auth.AuthService
 	service *service.InventoryService
 }
 
-func NewInventoryApp(s *service.InventoryService, c *config.InventoryConfig, log *zerolog.Logger) *InventoryApp {
+func NewInventoryApp(s *service.InventoryService, a auth.AuthService, c *config.InventoryConfig, log *zerolog.Logger) *InventoryApp {
 	logger := log.With().Str("package:inventory", "InventoryApp").Logger()
 
 	app := &InventoryApp{
 		log:     &logger,
 		config:  c,
 		service: s,
+		auth:    a,
 	}
 
 	app.loadRoutes()
@@ -9,6 +9,7 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/rovilay/ecommerce-service/domains/inventory"
+	"github.com/rovilay/ecommerce-service/domains/inventory/model"
 	"github.com/rovilay/ecommerce-service/domains/inventory/service"
 	"github.com/rs/zerolog"
 )
@@ -42,7 +43,13 @@ func (h *InventoryHandler) GetInventory(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	ivntry, err := h.service.GetInventoryByProductID(r.Context(), productID)
+	variantID, err := variantIDParam(r)
+	if err != nil {
+		h.sendError(w, err, "failed to convert variant_id value", http.StatusBadRequest, &log)
+		return
+	}
+
+	ivntry, err := h.service.GetInventoryByProductID(r.Context(), productID, variantID)
 	if err != nil {
 		h.sendError(w, err, "", 0, &log)
 		return
@@ -80,7 +87,13 @@ func (h *InventoryHandler) CheckAvailability(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	available, err := h.service.CheckAvailability(r.Context(), productID, uint(quantity))
+	variantID, err := variantIDParam(r)
+	if err != nil {
+		h.sendError(w, err, "failed to convert variant_id value", http.StatusBadRequest, &log)
+		return
+	}
+
+	available, err := h.service.CheckAvailability(r.Context(), productID, variantID, uint(quantity))
 	if err != nil {
 		h.sendError(w, err, "", 0, &log)
 		return
@@ -104,7 +117,8 @@ func (h *InventoryHandler) DecrementInventory(w http.ResponseWriter, r *http.Req
 	}
 
 	var payload struct {
-		Quantity int `json:"quantity"`
+		VariantID int `json:"variant_id"`
+		Quantity  int `json:"quantity"`
 	}
 
 	if err = json.NewDecoder(r.Body).Decode(&payload); err != nil {
@@ -112,7 +126,7 @@ func (h *InventoryHandler) DecrementInventory(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	err = h.service.DecrementInventory(r.Context(), productID, uint(payload.Quantity))
+	err = h.service.DecrementInventory(r.Context(), productID, payload.VariantID, uint(payload.Quantity))
 	if err != nil {
 		h.sendError(w, err, "", 0, &log)
 		return
@@ -134,7 +148,8 @@ func (h *InventoryHandler) IncrementInventory(w http.ResponseWriter, r *http.Req
 	}
 
 	var payload struct {
-		Quantity int `json:"quantity"`
+		VariantID int `json:"variant_id"`
+		Quantity  int `json:"quantity"`
 	}
 
 	if err = json.NewDecoder(r.Body).Decode(&payload); err != nil {
@@ -142,7 +157,7 @@ func (h *InventoryHandler) IncrementInventory(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	err = h.service.IncrementInventory(r.Context(), productID, uint(payload.Quantity))
+	err = h.service.IncrementInventory(r.Context(), productID, payload.VariantID, uint(payload.Quantity))
 	if err != nil {
 		h.sendError(w, err, "", 0, &log)
 		return
@@ -154,6 +169,145 @@ func (h *InventoryHandler) IncrementInventory(w http.ResponseWriter, r *http.Req
 	}
 }
 
+func (h *InventoryHandler) CheckAvailabilities(w http.ResponseWriter, r *http.Request) {
+	log := h.log.With().Str("method", "CheckAvailabilities").Logger()
+	data := r.Context().Value(AvailabilityCTXKey).(*model.AvailabilityRequest)
+
+	res, err := h.service.CheckAvailabilities(r.Context(), data.Items)
+	if err != nil {
+		h.sendError(w, err, "", 0, &log)
+		return
+	}
+
+	if err = json.NewEncoder(w).Encode(res); err != nil {
+		h.sendError(w, err, "failed to marshal", 0, &log)
+		return
+	}
+}
+
+// maxReferenceLength is the longest adjustment reference that can be recorded.
+const maxReferenceLength = 100
+
+func (h *InventoryHandler) AdjustInventory(w http.ResponseWriter, r *http.Request) {
+	log := h.log.With().Str("method", "AdjustInventory").Logger()
+	data := r.Context().Value(AdjustmentCTXKey).(*model.AdjustmentRequest)
+
+	// retried requests carry the same key, which makes them safe to replay
+	reference := data.Reference
+	if reference == "" {
+		reference = r.Header.Get("Idempotency-Key")
+	}
+	if len(reference) > maxReferenceLength {
+		h.sendError(w, errors.New("reference is too long"), "", http.StatusBadRequest, &log)
+		return
+	}
+
+	items, err := h.service.AdjustInventory(r.Context(), reference, data.Items)
+	if err != nil {
+		h.sendError(w, err, "", 0, &log)
+		return
+	}
+
+	if err = json.NewEncoder(w).Encode(items); err != nil {
+		h.sendError(w, err, "failed to marshal", 0, &log)
+		return
+	}
+}
+
+func (h *InventoryHandler) ReverseAdjustment(w http.ResponseWriter, r *http.Request) {
+	log := h.log.With().Str("method", "ReverseAdjustment").Logger()
+
+	reference := chi.URLParam(r, "reference")
+	if len(reference) > maxReferenceLength {
+		h.sendError(w, errors.New("reference is too long"), "", http.StatusBadRequest, &log)
+		return
+	}
+
+	items, err := h.service.ReverseAdjustment(r.Context(), reference)
+	if err != nil {
+		h.sendError(w, err, "", 0, &log)
+		return
+	}
+
+	if err = json.NewEncoder(w).Encode(items); err != nil {
+		h.sendError(w, err, "failed to marshal", 0, &log)
+		return
+	}
+}
+
+func (h *InventoryHandler) ReserveInventory(w http.ResponseWriter, r *http.Request) {
+	log := h.log.With().Str("method", "ReserveInventory").Logger()
+	data := r.Context().Value(ReservationCTXKey).(*model.ReservationRequest)
+
+	reservations, err := h.service.ReserveInventory(r.Context(), data)
+	if err != nil {
+		h.sendError(w, err, "", 0, &log)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+
+	if err = json.NewEncoder(w).Encode(reservations); err != nil {
+		h.sendError(w, err, "failed to marshal", 0, &log)
+		return
+	}
+}
+
+func (h *InventoryHandler) GetReservations(w http.ResponseWriter, r *http.Request) {
+	log := h.log.With().Str("method", "GetReservations").Logger()
+
+	reservations, err := h.service.GetReservations(r.Context(), chi.URLParam(r, "reference"))
+	if err != nil {
+		h.sendError(w, err, "", 0, &log)
+		return
+	}
+
+	if err = json.NewEncoder(w).Encode(reservations); err != nil {
+		h.sendError(w, err, "failed to marshal", 0, &log)
+		return
+	}
+}
+
+func (h *InventoryHandler) CommitReservation(w http.ResponseWriter, r *http.Request) {
+	log := h.log.With().Str("method", "CommitReservation").Logger()
+
+	reservations, err := h.service.CommitReservation(r.Context(), chi.URLParam(r, "reference"))
+	if err != nil {
+		h.sendError(w, err, "", 0, &log)
+		return
+	}
+
+	if err = json.NewEncoder(w).Encode(reservations); err != nil {
+		h.sendError(w, err, "failed to marshal", 0, &log)
+		return
+	}
+}
+
+func (h *InventoryHandler) ReleaseReservation(w http.ResponseWriter, r *http.Request) {
+	log := h.log.With().Str("method", "ReleaseReservation").Logger()
+
+	reservations, err := h.service.ReleaseReservation(r.Context(), chi.URLParam(r, "reference"))
+	if err != nil {
+		h.sendError(w, err, "", 0, &log)
+		return
+	}
+
+	if err = json.NewEncoder(w).Encode(reservations); err != nil {
+		h.sendError(w, err, "failed to marshal", 0, &log)
+		return
+	}
+}
+
+// variantIDParam reads the optional variant_id query param; 0 is the product without a variant.
+func variantIDParam(r *http.Request) (int, error) {
+	v := r.URL.Query().Get("variant_id")
+	if v == "" {
+		return 0, nil
+	}
+
+	return strconv.Atoi(v)
+}
+
 func (h *InventoryHandler) sendError(w http.ResponseWriter, err error, errMsg string, statusCode int, log *zerolog.Logger) {
 	log.Err(err)
 
@@ -165,15 +319,21 @@ func (h *InventoryHandler) sendError(w http.ResponseWriter, err error, errMsg st
 		statusCode = http.StatusInternalServerError
 	}
 	errRes := fmt.Sprintf(`{"error": "%v"}`, errMsg)
+	if errors.Is(err, inventory.ErrInsufficientStock) {
+		errRes = fmt.Sprintf(`{"error": "%v", "code": "%s"}`, errMsg, inventory.CodeInsufficientStock)
+	}
 
 	if errors.Is(err, inventory.ErrInvalidProduct) || errors.Is(err, inventory.ErrInsufficientStock) ||
 		errors.Is(err, inventory.ErrInvalidQuantity) || errors.Is(err, inventory.ErrDuplicateEntry) ||
 		errors.Is(err, inventory.ErrForeignKeyViolation) {
 		http.Error(w, errRes, http.StatusBadRequest)
 		return
-	} else if errors.Is(err, inventory.ErrNotFound) {
+	} else if errors.Is(err, inventory.ErrNotFound) || errors.Is(err, inventory.ErrReservationNotFound) {
 		http.Error(w, errRes, http.StatusNotFound)
 		return
+	} else if errors.Is(err, inventory.ErrReservationExpired) {
+		http.Error(w, errRes, http.StatusConflict)
+		return
 	} else if err != nil {
 		http.Error(w, errRes, statusCode)
 		return
@@ -5,12 +5,16 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/rovilay/ecommerce-service/common/utils"
 	"github.com/rovilay/ecommerce-service/domains/inventory/model"
 )
 
 type contextKey string
 
 const InvCTXKey contextKey = "inventory_payload"
+const ReservationCTXKey contextKey = "reservation_payload"
+const AvailabilityCTXKey contextKey = "availability_payload"
+const AdjustmentCTXKey contextKey = "adjustment_payload"
 
 func (h *InventoryHandler) MiddlewareValidateInventory(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -42,3 +46,158 @@ func (h *InventoryHandler) MiddlewareValidateInventory(next http.Handler) http.H
 		next.ServeHTTP(w, r)
 	})
 }
+
+func (h *InventoryHandler) MiddlewareValidateReservation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		req := &model.ReservationRequest{}
+
+		err := req.FromJSON(r.Body)
+		if err != nil {
+			h.log.Println("[ERROR] deserializing reservation", err)
+			http.Error(w, `{"error": "failed to read payload"}`, http.StatusBadRequest)
+			return
+		}
+
+		err = req.Validate()
+		if err != nil {
+			h.log.Println("[ERROR] validating reservation", err)
+			http.Error(
+				w, fmt.Sprintf(`{"error": "Error validating reservation: %s"}`, err),
+				http.StatusBadRequest,
+			)
+			return
+		}
+
+		// add validated data
+		ctx := context.WithValue(r.Context(), ReservationCTXKey, req)
+		r = r.WithContext(ctx)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *InventoryHandler) MiddlewareValidateAvailability(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		req := &model.AvailabilityRequest{}
+
+		err := req.FromJSON(r.Body)
+		if err != nil {
+			h.log.Println("[ERROR] deserializing availability request", err)
+			http.Error(w, `{"error": "failed to read payload"}`, http.StatusBadRequest)
+			return
+		}
+
+		err = req.Validate()
+		if err != nil {
+			h.log.Println("[ERROR] validating availability request", err)
+			http.Error(
+				w, fmt.Sprintf(`{"error": "Error validating availability request: %s"}`, err),
+				http.StatusBadRequest,
+			)
+			return
+		}
+
+		// add validated data
+		ctx := context.WithValue(r.Context(), AvailabilityCTXKey, req)
+		r = r.WithContext(ctx)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *InventoryHandler) MiddlewareValidateAdjustment(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		req := &model.AdjustmentRequest{}
+
+		err := req.FromJSON(r.Body)
+		if err != nil {
+			h.log.Println("[ERROR] deserializing adjustments", err)
+			http.Error(w, `{"error": "failed to read payload"}`, http.StatusBadRequest)
+			return
+		}
+
+		err = req.Validate()
+		if err != nil {
+			h.log.Println("[ERROR] validating adjustments", err)
+			http.Error(
+				w, fmt.Sprintf(`{"error": "Error validating adjustments: %s"}`, err),
+				http.StatusBadRequest,
+			)
+			return
+		}
+
+		// add validated data
+		ctx := context.WithValue(r.Context(), AdjustmentCTXKey, req)
+		r = r.WithContext(ctx)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// MiddlewareRequireRoles only lets requests through whose bearer token carries one of roles.
+// Trusted services may call these endpoints with a service token instead.
+func (a *InventoryApp) MiddlewareRequireRoles(roles ...string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get(utils.ServiceTokenHeader) != "" {
+				a.MiddlewareRequireService(next).ServeHTTP(w, r)
+				return
+			}
+
+			authString := r.Header.Get("Authorization")
+			if authString == "" {
+				sendAuthError(w, utils.ErrMissingAuthToken, http.StatusUnauthorized)
+				return
+			}
+
+			tokenString, err := utils.ExtractToken(authString)
+			if err != nil {
+				sendAuthError(w, err, http.StatusUnauthorized)
+				return
+			}
+
+			claims, err := a.auth.ValidateJWT(r.Context(), tokenString)
+			if err != nil {
+				a.log.Err(err).Msg("error validating token")
+				sendAuthError(w, utils.ErrInvalidAuthToken, http.StatusUnauthorized)
+				return
+			}
+
+			if !claims.HasRole(roles...) {
+				sendAuthError(w, utils.ErrForbidden, http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// MiddlewareRequireService only lets requests through that carry a valid service token addressed to this service.
+func (a *InventoryApp) MiddlewareRequireService(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenString := r.Header.Get(utils.ServiceTokenHeader)
+		if tokenString == "" {
+			sendAuthError(w, utils.ErrMissingServiceToken, http.StatusUnauthorized)
+			return
+		}
+
+		claims, err := utils.ValidateServiceToken(tokenString, []byte(a.config.ServiceAuthSecret), utils.ServiceInventory)
+		if err != nil {
+			a.log.Err(err).Msg("error validating service token")
+			sendAuthError(w, utils.ErrInvalidServiceToken, http.StatusUnauthorized)
+			return
+		}
+
+		a.log.Debug().Str("caller", claims.Issuer).Msg("service request")
+		next.ServeHTTP(w, r)
+	})
+}
+
+func sendAuthError(w http.ResponseWriter, err error, statusCode int) {
+	errRes := fmt.Sprintf(`{"error": "%v"}`, err.Error())
+	http.Error(w, errRes, statusCode)
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rovilay/ecommerce-service/common/utils"
 	"github.com/rs/cors"
 )
 
@@ -47,6 +48,21 @@ func (a *InventoryApp) loadInventoryRoutes(router chi.Router) {
 	router.Get("/products/{id}", h.GetInventory)
 	router.Get("/products/{id}/available", h.CheckAvailability)
 
-	router.Put("/products/{id}/increase", h.IncrementInventory)
-	router.Put("/products/{id}/decrease", h.DecrementInventory)
+	router.With(h.MiddlewareValidateAvailability).Post("/availability", h.CheckAvailabilities)
+
+	router.Group(func(r chi.Router) {
+		r.Use(a.MiddlewareRequireRoles(utils.RoleAdmin, utils.RoleFulfillment))
+		r.Put("/products/{id}/increase", h.IncrementInventory)
+		r.Put("/products/{id}/decrease", h.DecrementInventory)
+		r.With(h.MiddlewareValidateAdjustment).Post("/adjustments", h.AdjustInventory)
+		r.Post("/adjustments/{reference}/reverse", h.ReverseAdjustment)
+	})
+
+	router.Route("/reservations", func(r chi.Router) {
+		r.Use(a.MiddlewareRequireService)
+		r.With(h.MiddlewareValidateReservation).Post("/", h.ReserveInventory)
+		r.Get("/{reference}", h.GetReservations)
+		r.Post("/{reference}/commit", h.CommitReservation)
+		r.Post("/{reference}/release", h.ReleaseReservation)
+	})
 }
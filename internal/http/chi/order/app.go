@@ -7,24 +7,38 @@ import (
 	"time"
 
 	"github.com/rovilay/ecommerce-service/config"
+	"github.com/rovilay/ecommerce-service/domains/auth"
 	"github.com/rovilay/ecommerce-service/domains/order/service"
+	"github.com/rovilay/ecommerce-service/domains/promotion"
+	"github.com/rovilay/ecommerce-service/domains/shipping"
+	"github.com/rovilay/ecommerce-service/domains/tax"
 	"github.com/rs/zerolog"
 )
 
 type OrderApp struct {
-	router  http.Handler
-	config  *config.OrderConfig
-	log     *zerolog.Logger
-	service *service.OrderService
+	router     http.Handler
+	config     *config.OrderConfig
+	log        *zerolog.Logger
+	service    *service.OrderService
+	promotions *promotion.Service
+	taxes      *tax.Service
+	shipping   *shipping.Service
+	auth       auth.AuthService
 }
 
-func NewOrderApp(s *service.OrderService, c *config.OrderConfig, log *zerolog.Logger) *OrderApp {
+func NewOrderApp(s *service.OrderService, p *promotion.Service, t *tax.Service, sh *shipping.Service, a auth.AuthService, c *config.OrderConfig,
+	log *zerolog.Logger,
+) *OrderApp {
 	logger := log.With().Str("app:order", "OrderApp").Logger()
 
 	app := &OrderApp{
-		log:     &logger,
-		config:  c,
-		service: s,
+		log:        &logger,
+		config:     c,
+		service:    s,
+		promotions: p,
+		taxes:      t,
+		shipping:   sh,
+		auth:       a,
 	}
 
 	app.loadRoutes()
@@ -4,13 +4,18 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/rovilay/ecommerce-service/common/httpclient"
 	"github.com/rovilay/ecommerce-service/domains/order"
 	"github.com/rovilay/ecommerce-service/domains/order/models"
 	"github.com/rovilay/ecommerce-service/domains/order/service"
+	"github.com/rovilay/ecommerce-service/domains/payment"
+	"github.com/rovilay/ecommerce-service/domains/promotion"
+	"github.com/rovilay/ecommerce-service/domains/shipping"
 	"github.com/rs/zerolog"
 )
 
@@ -54,6 +59,24 @@ func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (h *OrderHandler) QuoteOrder(w http.ResponseWriter, r *http.Request) {
+	log := h.log.With().Str("method", "QuoteOrder").Logger()
+	authToken := r.Context().Value(AuthCTXKey).(string)
+	data := r.Context().Value(OrderCTXKey).(*models.Order)
+	fromCart := len(data.OrderItems) == 0
+
+	quote, err := h.service.QuoteOrder(r.Context(), authToken, data, fromCart)
+	if err != nil {
+		h.sendError(w, err, "", 0, &log)
+		return
+	}
+
+	if err = json.NewEncoder(w).Encode(quote); err != nil {
+		h.sendError(w, err, "failed to marshal", 0, &log)
+		return
+	}
+}
+
 func (h *OrderHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
 	log := h.log.With().Str("method", "GetOrder").Logger()
 	authToken := r.Context().Value(AuthCTXKey).(string)
@@ -115,6 +138,9 @@ func (h *OrderHandler) UpdateOrderStatus(w http.ResponseWriter, r *http.Request)
 
 	var payload struct {
 		Status string `json:"status"`
+		// set when the order is shipped
+		Carrier        string `json:"carrier"`
+		TrackingNumber string `json:"tracking_number"`
 	}
 
 	if err = json.NewDecoder(r.Body).Decode(&payload); err != nil {
@@ -125,7 +151,16 @@ func (h *OrderHandler) UpdateOrderStatus(w http.ResponseWriter, r *http.Request)
 
 	status := models.OrderStatus(payload.Status)
 
-	err = h.service.UpdateOrderStatus(r.Context(), authToken, orderID, status)
+	var shipment *models.Shipment
+	if payload.Carrier != "" || payload.TrackingNumber != "" {
+		shipment = &models.Shipment{Carrier: payload.Carrier, TrackingNumber: payload.TrackingNumber}
+		if err = shipment.Validate(); err != nil {
+			h.sendError(w, err, "", http.StatusBadRequest, &log)
+			return
+		}
+	}
+
+	err = h.service.UpdateOrderStatus(r.Context(), authToken, orderID, status, shipment)
 	if err != nil {
 		h.sendError(w, err, "", http.StatusBadRequest, &log)
 		return
@@ -137,6 +172,76 @@ func (h *OrderHandler) UpdateOrderStatus(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+func (h *OrderHandler) GetOrderStatusHistory(w http.ResponseWriter, r *http.Request) {
+	log := h.log.With().Str("method", "GetOrderStatusHistory").Logger()
+	authToken := r.Context().Value(AuthCTXKey).(string)
+
+	orderID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.sendError(w, err, "failed to convert order ID param", http.StatusBadRequest, &log)
+		return
+	}
+
+	history, err := h.service.GetOrderStatusHistory(r.Context(), authToken, orderID)
+	if err != nil {
+		h.sendError(w, err, "", 0, &log)
+		return
+	}
+
+	if err = json.NewEncoder(w).Encode(&history); err != nil {
+		h.sendError(w, err, "failed to marshal", 0, &log)
+		return
+	}
+}
+
+func (h *OrderHandler) GetOrderPayment(w http.ResponseWriter, r *http.Request) {
+	log := h.log.With().Str("method", "GetOrderPayment").Logger()
+	authToken := r.Context().Value(AuthCTXKey).(string)
+
+	orderID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.sendError(w, err, "failed to convert order ID param", http.StatusBadRequest, &log)
+		return
+	}
+
+	p, err := h.service.GetOrderPayment(r.Context(), authToken, orderID)
+	if err != nil {
+		h.sendError(w, err, "", 0, &log)
+		return
+	}
+
+	if err = p.ToJSON(w); err != nil {
+		h.sendError(w, err, "failed to marshal", 0, &log)
+		return
+	}
+}
+
+// maxWebhookBody bounds the size of a payment webhook.
+const maxWebhookBody = 1 << 16
+
+// PaymentWebhook serves POST /payments/webhook. The gateway authenticates with the signature
+// in payment.SignatureHeader rather than a user token.
+func (h *OrderHandler) PaymentWebhook(w http.ResponseWriter, r *http.Request) {
+	log := h.log.With().Str("method", "PaymentWebhook").Logger()
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+	if err != nil {
+		h.sendError(w, err, "failed to read payload", http.StatusBadRequest, &log)
+		return
+	}
+
+	err = h.service.HandlePaymentWebhook(r.Context(), body, r.Header.Get(payment.SignatureHeader))
+	if err != nil {
+		h.sendError(w, err, "", 0, &log)
+		return
+	}
+
+	if err = json.NewEncoder(w).Encode(defaultSuccessRes); err != nil {
+		h.sendError(w, err, "failed to marshal", 0, &log)
+		return
+	}
+}
+
 func (h *OrderHandler) sendError(w http.ResponseWriter, err error, errMsg string, statusCode int, log *zerolog.Logger) {
 	log.Err(err)
 	if errMsg == "" {
@@ -150,13 +255,30 @@ func (h *OrderHandler) sendError(w http.ResponseWriter, err error, errMsg string
 
 	if errors.Is(err, order.ErrInvalidProduct) || errors.Is(err, order.ErrInsufficientStock) ||
 		errors.Is(err, order.ErrInvalidQuantity) || errors.Is(err, order.ErrDuplicateEntry) ||
-		errors.Is(err, order.ErrForeignKeyViolation) {
+		errors.Is(err, order.ErrForeignKeyViolation) || errors.Is(err, order.ErrInvalidStatus) ||
+		errors.Is(err, order.ErrMixedCurrencies) || errors.Is(err, promotion.ErrInvalidCoupon) ||
+		errors.Is(err, promotion.ErrCouponNotApplicable) || errors.Is(err, shipping.ErrUnavailable) ||
+		errors.Is(err, order.ErrInvalidShipment) || errors.Is(err, payment.ErrTokenRequired) ||
+		errors.Is(err, payment.ErrInvalidEvent) {
 		http.Error(w, errRes, http.StatusBadRequest)
 		return
-	} else if errors.Is(err, order.ErrInvalidJWToken) {
+	} else if errors.Is(err, payment.ErrDeclined) {
+		http.Error(w, errRes, http.StatusPaymentRequired)
+		return
+	} else if errors.Is(err, order.ErrInvalidStatusTransition) || errors.Is(err, promotion.ErrUsageLimitReached) ||
+		errors.Is(err, order.ErrAwaitingPayment) || errors.Is(err, payment.ErrInvalidState) {
+		http.Error(w, errRes, http.StatusConflict)
+		return
+	} else if errors.Is(err, order.ErrInvalidJWToken) || errors.Is(err, payment.ErrInvalidSignature) {
 		http.Error(w, errRes, http.StatusUnauthorized)
 		return
-	} else if errors.Is(err, order.ErrNotFound) || errors.Is(err, order.ErrItemNotFound) {
+	} else if errors.Is(err, httpclient.ErrCircuitOpen) {
+		http.Error(w, errRes, http.StatusServiceUnavailable)
+		return
+	} else if errors.Is(err, order.ErrForbidden) {
+		http.Error(w, errRes, http.StatusForbidden)
+		return
+	} else if errors.Is(err, order.ErrNotFound) || errors.Is(err, order.ErrItemNotFound) || errors.Is(err, payment.ErrNotFound) {
 		http.Error(w, errRes, http.StatusNotFound)
 		return
 	} else if err != nil {
@@ -69,3 +69,36 @@ func ErrUnauthorized(w http.ResponseWriter, err error) {
 	errRes := fmt.Sprintf(`{"error": "%v"}`, err.Error())
 	http.Error(w, errRes, http.StatusUnauthorized)
 }
+
+// MiddlewareRequireRoles only lets requests through whose bearer token carries one of roles.
+func (a *OrderApp) MiddlewareRequireRoles(roles ...string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authString := r.Header.Get("Authorization")
+			if authString == "" {
+				ErrUnauthorized(w, utils.ErrMissingAuthToken)
+				return
+			}
+
+			tokenString, err := utils.ExtractToken(authString)
+			if err != nil {
+				ErrUnauthorized(w, err)
+				return
+			}
+
+			claims, err := a.auth.ValidateJWT(r.Context(), tokenString)
+			if err != nil {
+				a.log.Err(err).Msg("error validating token")
+				ErrUnauthorized(w, utils.ErrInvalidAuthToken)
+				return
+			}
+
+			if !claims.HasRole(roles...) {
+				http.Error(w, fmt.Sprintf(`{"error": "%v"}`, utils.ErrForbidden), http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
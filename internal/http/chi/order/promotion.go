@@ -0,0 +1,159 @@
+package order
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rovilay/ecommerce-service/domains/promotion"
+	"github.com/rs/zerolog"
+)
+
+const PromotionCTXKey contextKey = "promotion_payload"
+
+type PromotionHandler struct {
+	service *promotion.Service
+	log     *zerolog.Logger
+}
+
+func NewPromotionHandler(s *promotion.Service, l *zerolog.Logger) *PromotionHandler {
+	logger := l.With().Str("component", "PromotionHandler").Logger()
+
+	return &PromotionHandler{
+		service: s,
+		log:     &logger,
+	}
+}
+
+func (h *PromotionHandler) ListPromotions(w http.ResponseWriter, r *http.Request) {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 50
+	}
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	res, err := h.service.ListPromotions(r.Context(), limit, offset)
+	if err != nil {
+		h.sendError(w, err, "failed to list promotions")
+		return
+	}
+
+	if err = json.NewEncoder(w).Encode(res); err != nil {
+		h.sendError(w, err, "failed to marshal")
+	}
+}
+
+func (h *PromotionHandler) GetPromotion(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, `{"error": "failed to convert promotion ID param"}`, http.StatusBadRequest)
+		return
+	}
+
+	p, err := h.service.GetPromotion(r.Context(), id)
+	if err != nil {
+		h.sendError(w, err, "failed to get promotion")
+		return
+	}
+
+	if err = p.ToJSON(w); err != nil {
+		h.sendError(w, err, "failed to marshal")
+	}
+}
+
+func (h *PromotionHandler) CreatePromotion(w http.ResponseWriter, r *http.Request) {
+	data := r.Context().Value(PromotionCTXKey).(*promotion.Promotion)
+
+	p, err := h.service.CreatePromotion(r.Context(), data)
+	if err != nil {
+		h.sendError(w, err, "failed to create promotion")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	if err = p.ToJSON(w); err != nil {
+		h.sendError(w, err, "failed to marshal")
+	}
+}
+
+func (h *PromotionHandler) UpdatePromotion(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, `{"error": "failed to convert promotion ID param"}`, http.StatusBadRequest)
+		return
+	}
+
+	data := r.Context().Value(PromotionCTXKey).(*promotion.Promotion)
+	data.ID = id
+
+	p, err := h.service.UpdatePromotion(r.Context(), data)
+	if err != nil {
+		h.sendError(w, err, "failed to update promotion")
+		return
+	}
+
+	if err = p.ToJSON(w); err != nil {
+		h.sendError(w, err, "failed to marshal")
+	}
+}
+
+// DeactivatePromotion serves DELETE /promotions/{id}. The promotion stays on record for the
+// orders it was applied to, it just stops applying to new ones.
+func (h *PromotionHandler) DeactivatePromotion(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, `{"error": "failed to convert promotion ID param"}`, http.StatusBadRequest)
+		return
+	}
+
+	if err = h.service.DeactivatePromotion(r.Context(), id); err != nil {
+		h.sendError(w, err, "failed to deactivate promotion")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *PromotionHandler) MiddlewareValidatePromotion(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := &promotion.Promotion{Active: true}
+
+		if err := p.FromJSON(r.Body); err != nil {
+			h.log.Println("[ERROR] deserializing promotion", err)
+			http.Error(w, `{"error": "failed to read promotion"}`, http.StatusBadRequest)
+			return
+		}
+
+		p.Normalize()
+		if err := p.Validate(); err != nil {
+			h.log.Println("[ERROR] validating promotion", err)
+			quoted, _ := json.Marshal(err.Error())
+			http.Error(w, fmt.Sprintf(`{"error": %s}`, quoted), http.StatusBadRequest)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), PromotionCTXKey, p)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (h *PromotionHandler) sendError(w http.ResponseWriter, err error, msg string) {
+	if errors.Is(err, promotion.ErrNotFound) {
+		http.Error(w, `{"error": "promotion not found"}`, http.StatusNotFound)
+	} else if errors.Is(err, promotion.ErrDuplicateCode) {
+		http.Error(w, fmt.Sprintf(`{"error": "%v"}`, err), http.StatusConflict)
+	} else if errors.Is(err, promotion.ErrInvalidRule) {
+		quoted, _ := json.Marshal(err.Error())
+		http.Error(w, fmt.Sprintf(`{"error": %s}`, quoted), http.StatusBadRequest)
+	} else {
+		h.log.Println(msg+": ", err)
+		http.Error(w, fmt.Sprintf(`{"error": "%s"}`, msg), http.StatusInternalServerError)
+	}
+}
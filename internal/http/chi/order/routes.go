@@ -2,11 +2,13 @@ package order
 
 import (
 	"encoding/json"
+	"expvar"
 	"fmt"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rovilay/ecommerce-service/common/utils"
 	"github.com/rs/cors"
 )
 
@@ -34,6 +36,17 @@ func (a *OrderApp) loadRoutes() {
 	})
 
 	router.Route("/api/v1/orders", a.loadOrderRoutes)
+	router.Route("/api/v1/promotions", a.loadPromotionRoutes)
+	router.Route("/api/v1/tax-rates", a.loadTaxRateRoutes)
+	router.Route("/api/v1/shipping-methods", a.loadShippingMethodRoutes)
+	router.Route("/api/v1/payments", a.loadPaymentRoutes)
+
+	// exposes the external service clients' circuit breaker metrics, along with the process'
+	// command line and memory stats, so admins only
+	router.Group(func(r chi.Router) {
+		r.Use(a.MiddlewareRequireRoles(utils.RoleAdmin))
+		r.Handle("/debug/vars", expvar.Handler())
+	})
 
 	// CORS configuration
 	corsRouter := cors.Default().Handler(router)
@@ -49,11 +62,71 @@ func (a *OrderApp) loadOrderRoutes(router chi.Router) {
 		r.Get("/", h.GetOrders)
 		r.Get("/{id}", h.GetOrder)
 		r.Put("/{id}/status", h.UpdateOrderStatus)
+		r.Get("/{id}/history", h.GetOrderStatusHistory)
+		r.Get("/{id}/payment", h.GetOrderPayment)
 	})
 
 	router.Group(func(r chi.Router) {
 		r.Use(h.MiddlewareAuth)
 		r.Use(h.MiddlewareValidateOrderItems)
 		r.Post("/", h.CreateOrder)
+		r.Post("/quote", h.QuoteOrder)
+	})
+}
+
+func (a *OrderApp) loadPaymentRoutes(router chi.Router) {
+	h := NewOrderHandler(a.service, a.log)
+
+	router.Post("/webhook", h.PaymentWebhook)
+}
+
+func (a *OrderApp) loadPromotionRoutes(router chi.Router) {
+	h := NewPromotionHandler(a.promotions, a.log)
+
+	router.Group(func(r chi.Router) {
+		r.Use(a.MiddlewareRequireRoles(utils.RoleAdmin))
+		r.Get("/", h.ListPromotions)
+		r.Get("/{id}", h.GetPromotion)
+		r.Delete("/{id}", h.DeactivatePromotion)
+
+		r.Group(func(r chi.Router) {
+			r.Use(h.MiddlewareValidatePromotion)
+			r.Post("/", h.CreatePromotion)
+			r.Put("/{id}", h.UpdatePromotion)
+		})
+	})
+}
+
+func (a *OrderApp) loadTaxRateRoutes(router chi.Router) {
+	h := NewTaxRateHandler(a.taxes, a.log)
+
+	router.Group(func(r chi.Router) {
+		r.Use(a.MiddlewareRequireRoles(utils.RoleAdmin))
+		r.Get("/", h.ListTaxRates)
+		r.Get("/{id}", h.GetTaxRate)
+		r.Delete("/{id}", h.DeleteTaxRate)
+
+		r.Group(func(r chi.Router) {
+			r.Use(h.MiddlewareValidateTaxRate)
+			r.Post("/", h.CreateTaxRate)
+			r.Put("/{id}", h.UpdateTaxRate)
+		})
+	})
+}
+
+func (a *OrderApp) loadShippingMethodRoutes(router chi.Router) {
+	h := NewShippingMethodHandler(a.shipping, a.log)
+
+	router.Group(func(r chi.Router) {
+		r.Use(a.MiddlewareRequireRoles(utils.RoleAdmin))
+		r.Get("/", h.ListShippingMethods)
+		r.Get("/{id}", h.GetShippingMethod)
+		r.Delete("/{id}", h.DeactivateShippingMethod)
+
+		r.Group(func(r chi.Router) {
+			r.Use(h.MiddlewareValidateShippingMethod)
+			r.Post("/", h.CreateShippingMethod)
+			r.Put("/{id}", h.UpdateShippingMethod)
+		})
 	})
 }
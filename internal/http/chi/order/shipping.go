@@ -0,0 +1,158 @@
+package order
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rovilay/ecommerce-service/domains/shipping"
+	"github.com/rs/zerolog"
+)
+
+const ShippingMethodCTXKey contextKey = "shipping_method_payload"
+
+type ShippingMethodHandler struct {
+	service *shipping.Service
+	log     *zerolog.Logger
+}
+
+func NewShippingMethodHandler(s *shipping.Service, l *zerolog.Logger) *ShippingMethodHandler {
+	logger := l.With().Str("component", "ShippingMethodHandler").Logger()
+
+	return &ShippingMethodHandler{
+		service: s,
+		log:     &logger,
+	}
+}
+
+func (h *ShippingMethodHandler) ListShippingMethods(w http.ResponseWriter, r *http.Request) {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 50
+	}
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	res, err := h.service.ListMethods(r.Context(), limit, offset)
+	if err != nil {
+		h.sendError(w, err, "failed to list shipping methods")
+		return
+	}
+
+	if err = json.NewEncoder(w).Encode(res); err != nil {
+		h.sendError(w, err, "failed to marshal")
+	}
+}
+
+func (h *ShippingMethodHandler) GetShippingMethod(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, `{"error": "failed to convert shipping method ID param"}`, http.StatusBadRequest)
+		return
+	}
+
+	m, err := h.service.GetMethod(r.Context(), id)
+	if err != nil {
+		h.sendError(w, err, "failed to get shipping method")
+		return
+	}
+
+	if err = m.ToJSON(w); err != nil {
+		h.sendError(w, err, "failed to marshal")
+	}
+}
+
+func (h *ShippingMethodHandler) CreateShippingMethod(w http.ResponseWriter, r *http.Request) {
+	data := r.Context().Value(ShippingMethodCTXKey).(*shipping.Method)
+
+	m, err := h.service.CreateMethod(r.Context(), data)
+	if err != nil {
+		h.sendError(w, err, "failed to create shipping method")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	if err = m.ToJSON(w); err != nil {
+		h.sendError(w, err, "failed to marshal")
+	}
+}
+
+func (h *ShippingMethodHandler) UpdateShippingMethod(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, `{"error": "failed to convert shipping method ID param"}`, http.StatusBadRequest)
+		return
+	}
+
+	data := r.Context().Value(ShippingMethodCTXKey).(*shipping.Method)
+	data.ID = id
+
+	m, err := h.service.UpdateMethod(r.Context(), data)
+	if err != nil {
+		h.sendError(w, err, "failed to update shipping method")
+		return
+	}
+
+	if err = m.ToJSON(w); err != nil {
+		h.sendError(w, err, "failed to marshal")
+	}
+}
+
+// DeactivateShippingMethod serves DELETE /shipping-methods/{id}. Orders placed with the
+// method keep it; it is just no longer offered.
+func (h *ShippingMethodHandler) DeactivateShippingMethod(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, `{"error": "failed to convert shipping method ID param"}`, http.StatusBadRequest)
+		return
+	}
+
+	if err = h.service.DeactivateMethod(r.Context(), id); err != nil {
+		h.sendError(w, err, "failed to deactivate shipping method")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ShippingMethodHandler) MiddlewareValidateShippingMethod(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m := &shipping.Method{Active: true}
+
+		if err := m.FromJSON(r.Body); err != nil {
+			h.log.Println("[ERROR] deserializing shipping method", err)
+			quoted, _ := json.Marshal(fmt.Sprintf("failed to read shipping method: %v", err))
+			http.Error(w, fmt.Sprintf(`{"error": %s}`, quoted), http.StatusBadRequest)
+			return
+		}
+
+		m.Normalize()
+		if err := m.Validate(); err != nil {
+			h.log.Println("[ERROR] validating shipping method", err)
+			quoted, _ := json.Marshal(err.Error())
+			http.Error(w, fmt.Sprintf(`{"error": %s}`, quoted), http.StatusBadRequest)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ShippingMethodCTXKey, m)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (h *ShippingMethodHandler) sendError(w http.ResponseWriter, err error, msg string) {
+	if errors.Is(err, shipping.ErrNotFound) {
+		http.Error(w, `{"error": "shipping method not found"}`, http.StatusNotFound)
+	} else if errors.Is(err, shipping.ErrInvalidMethod) {
+		quoted, _ := json.Marshal(err.Error())
+		http.Error(w, fmt.Sprintf(`{"error": %s}`, quoted), http.StatusBadRequest)
+	} else {
+		h.log.Println(msg+": ", err)
+		http.Error(w, fmt.Sprintf(`{"error": "%s"}`, msg), http.StatusInternalServerError)
+	}
+}
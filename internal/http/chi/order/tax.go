@@ -0,0 +1,157 @@
+package order
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rovilay/ecommerce-service/domains/tax"
+	"github.com/rs/zerolog"
+)
+
+const TaxRateCTXKey contextKey = "tax_rate_payload"
+
+type TaxRateHandler struct {
+	service *tax.Service
+	log     *zerolog.Logger
+}
+
+func NewTaxRateHandler(s *tax.Service, l *zerolog.Logger) *TaxRateHandler {
+	logger := l.With().Str("component", "TaxRateHandler").Logger()
+
+	return &TaxRateHandler{
+		service: s,
+		log:     &logger,
+	}
+}
+
+func (h *TaxRateHandler) ListTaxRates(w http.ResponseWriter, r *http.Request) {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 50
+	}
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	res, err := h.service.ListTaxRates(r.Context(), limit, offset)
+	if err != nil {
+		h.sendError(w, err, "failed to list tax rates")
+		return
+	}
+
+	if err = json.NewEncoder(w).Encode(res); err != nil {
+		h.sendError(w, err, "failed to marshal")
+	}
+}
+
+func (h *TaxRateHandler) GetTaxRate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, `{"error": "failed to convert tax rate ID param"}`, http.StatusBadRequest)
+		return
+	}
+
+	t, err := h.service.GetTaxRate(r.Context(), id)
+	if err != nil {
+		h.sendError(w, err, "failed to get tax rate")
+		return
+	}
+
+	if err = t.ToJSON(w); err != nil {
+		h.sendError(w, err, "failed to marshal")
+	}
+}
+
+func (h *TaxRateHandler) CreateTaxRate(w http.ResponseWriter, r *http.Request) {
+	data := r.Context().Value(TaxRateCTXKey).(*tax.TaxRate)
+
+	t, err := h.service.CreateTaxRate(r.Context(), data)
+	if err != nil {
+		h.sendError(w, err, "failed to create tax rate")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	if err = t.ToJSON(w); err != nil {
+		h.sendError(w, err, "failed to marshal")
+	}
+}
+
+func (h *TaxRateHandler) UpdateTaxRate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, `{"error": "failed to convert tax rate ID param"}`, http.StatusBadRequest)
+		return
+	}
+
+	data := r.Context().Value(TaxRateCTXKey).(*tax.TaxRate)
+	data.ID = id
+
+	t, err := h.service.UpdateTaxRate(r.Context(), data)
+	if err != nil {
+		h.sendError(w, err, "failed to update tax rate")
+		return
+	}
+
+	if err = t.ToJSON(w); err != nil {
+		h.sendError(w, err, "failed to marshal")
+	}
+}
+
+func (h *TaxRateHandler) DeleteTaxRate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, `{"error": "failed to convert tax rate ID param"}`, http.StatusBadRequest)
+		return
+	}
+
+	if err = h.service.DeleteTaxRate(r.Context(), id); err != nil {
+		h.sendError(w, err, "failed to delete tax rate")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *TaxRateHandler) MiddlewareValidateTaxRate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t := &tax.TaxRate{}
+
+		if err := t.FromJSON(r.Body); err != nil {
+			h.log.Println("[ERROR] deserializing tax rate", err)
+			quoted, _ := json.Marshal(fmt.Sprintf("failed to read tax rate: %v", err))
+			http.Error(w, fmt.Sprintf(`{"error": %s}`, quoted), http.StatusBadRequest)
+			return
+		}
+
+		t.Normalize()
+		if err := t.Validate(); err != nil {
+			h.log.Println("[ERROR] validating tax rate", err)
+			quoted, _ := json.Marshal(err.Error())
+			http.Error(w, fmt.Sprintf(`{"error": %s}`, quoted), http.StatusBadRequest)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), TaxRateCTXKey, t)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (h *TaxRateHandler) sendError(w http.ResponseWriter, err error, msg string) {
+	if errors.Is(err, tax.ErrNotFound) {
+		http.Error(w, `{"error": "tax rate not found"}`, http.StatusNotFound)
+	} else if errors.Is(err, tax.ErrDuplicateRate) {
+		http.Error(w, fmt.Sprintf(`{"error": "%v"}`, err), http.StatusConflict)
+	} else if errors.Is(err, tax.ErrInvalidCategory) || errors.Is(err, tax.ErrInvalidRate) {
+		http.Error(w, fmt.Sprintf(`{"error": "%v"}`, err), http.StatusBadRequest)
+	} else {
+		h.log.Println(msg+": ", err)
+		http.Error(w, fmt.Sprintf(`{"error": "%s"}`, msg), http.StatusInternalServerError)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/rovilay/ecommerce-service/config"
+	"github.com/rovilay/ecommerce-service/domains/auth"
 	"github.com/rovilay/ecommerce-service/domains/product"
 	"github.com/rs/zerolog"
 )
@@ -15,16 +16,18 @@ type ProductApp struct {
 	router  http.Handler
 	config  *config.ProductConfig
 	log     *zerolog.Logger
+	auth    auth.AuthService
 	service *product.Service
 }
 
-func NewProductApp(s *product.Service, c *config.ProductConfig, log *zerolog.Logger) *ProductApp {
+func NewProductApp(s *product.Service, a auth.AuthService, c *config.ProductConfig, log *zerolog.Logger) *ProductApp {
 	appLogger := log.With().Str("package", "productApp").Logger()
 
 	app := &ProductApp{
 		config:  c,
 		log:     &appLogger,
 		service: s,
+		auth:    a,
 	}
 
 	app.loadRoutes()
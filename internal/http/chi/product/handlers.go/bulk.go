@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/rovilay/ecommerce-service/domains/product"
+)
+
+// ImportProducts serves POST /products/import. The body is CSV or NDJSON, picked by the format
+// query param or else the Content-Type, and is read as it streams in.
+func (h *ProductHandler) ImportProducts(w http.ResponseWriter, r *http.Request) {
+	format := bulkFormat(r, r.Header.Get("Content-Type"))
+	if !format.IsValid() {
+		http.Error(w, fmt.Sprintf(`{"error": "%v"}`, product.ErrUnsupportedFormat), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	pr, err := product.NewProductReader(format, r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error": "%v"}`, err), http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.service.ImportProducts(r.Context(), pr)
+	if err != nil {
+		h.log.Println("import stopped: ", err)
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		h.log.Println("failed to marshal: ", err)
+		http.Error(w, `{"error": "failed to marshal"}`, http.StatusInternalServerError)
+		return
+	}
+}
+
+// ExportProducts serves GET /products/export as CSV or NDJSON, picked by the format query param
+// or else the Accept header, and defaulting to NDJSON. It takes the filters of the product listing.
+func (h *ProductHandler) ExportProducts(w http.ResponseWriter, r *http.Request) {
+	format := bulkFormat(r, r.Header.Get("Accept"))
+	if format == "" {
+		format = product.FormatNDJSON
+	}
+	if !format.IsValid() {
+		http.Error(w, fmt.Sprintf(`{"error": "%v"}`, product.ErrUnsupportedFormat), http.StatusBadRequest)
+		return
+	}
+
+	q, err := parseProductListQuery(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error": "%v"}`, err), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="products.%s"`, format))
+
+	pw, err := product.NewProductWriter(format, w)
+	if err != nil {
+		h.log.Println("failed to start export: ", err)
+		http.Error(w, `{"error": "failed to export products"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// the response is already streaming, so a failure can only cut it short
+	if err = h.service.ExportProducts(r.Context(), q.Filter, pw); err != nil && !errors.Is(err, r.Context().Err()) {
+		h.log.Println("export stopped: ", err)
+	}
+}
+
+// bulkFormat reads the format query param, falling back to the given media type header.
+// It returns "" when neither names a format.
+func bulkFormat(r *http.Request, header string) product.BulkFormat {
+	if f := r.URL.Query().Get("format"); f != "" {
+		return product.BulkFormat(strings.ToLower(f))
+	}
+
+	for _, part := range strings.Split(header, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		switch mediaType {
+		case "text/csv":
+			return product.FormatCSV
+		case "application/x-ndjson", "application/jsonl", "application/json":
+			return product.FormatNDJSON
+		}
+	}
+
+	return ""
+}
@@ -3,6 +3,7 @@ package handler
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 
@@ -65,7 +66,10 @@ func (h *CategoryHandler) CreateCategory(w http.ResponseWriter, r *http.Request)
 	data := r.Context().Value(CategoryCTXKey).(*product.Category)
 
 	newPrd, err := h.service.CreateCategory(r.Context(), data)
-	if err != nil {
+	if errors.Is(err, product.ErrInvalidParentCategory) {
+		http.Error(w, fmt.Sprintf(`{"error": "%v"}`, err), http.StatusBadRequest)
+		return
+	} else if err != nil {
 		h.log.Println("failed to create category: ", err)
 		http.Error(w, `{"error": "failed to create category"}`, http.StatusInternalServerError)
 		return
@@ -105,6 +109,69 @@ func (h *CategoryHandler) UpdateCategory(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+func (h *CategoryHandler) GetCategoryTree(w http.ResponseWriter, r *http.Request) {
+	tree, err := h.service.GetCategoryTree(r.Context())
+	if err != nil {
+		h.log.Println("failed to get category tree: ", err)
+		http.Error(w, `{"error": "failed to get category tree"}`, http.StatusInternalServerError)
+		return
+	}
+
+	var response struct {
+		Result []*product.Category `json:"result"`
+	}
+
+	response.Result = tree
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.log.Println("failed to marshal: ", err)
+		http.Error(w, `{"error": "failed to marshal"}`, http.StatusInternalServerError)
+		return
+	}
+}
+
+// MoveCategory moves a category and its subcategories under another parent, or to the root
+// when parent_category_id is null.
+func (h *CategoryHandler) MoveCategory(w http.ResponseWriter, r *http.Request) {
+	ctgryID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.log.Println("bad ID param: ", err)
+		http.Error(w, `{"error": "failed to convert category ID param"}`, http.StatusBadRequest)
+		return
+	}
+
+	var body struct {
+		ParentCategoryID *int `json:"parent_category_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		h.log.Println("[ERROR] deserializing category parent", err)
+		http.Error(w, `{"error": "failed to read category parent"}`, http.StatusBadRequest)
+		return
+	}
+
+	c, err := h.service.MoveCategory(r.Context(), ctgryID, body.ParentCategoryID)
+	if errors.Is(err, product.ErrNotExist) {
+		http.Error(w, `{"error": "category resource not found"}`, http.StatusNotFound)
+		return
+	} else if errors.Is(err, product.ErrInvalidParentCategory) {
+		http.Error(w, fmt.Sprintf(`{"error": "%v"}`, err), http.StatusBadRequest)
+		return
+	} else if errors.Is(err, product.ErrCategoryCycle) {
+		http.Error(w, fmt.Sprintf(`{"error": "%v"}`, err), http.StatusConflict)
+		return
+	} else if err != nil {
+		h.log.Println("failed to move category: ", err)
+		http.Error(w, `{"error": "failed to move category"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if err := c.ToJSON(w); err != nil {
+		h.log.Println("failed to marshal: ", err)
+		http.Error(w, `{"error": "failed to marshal"}`, http.StatusInternalServerError)
+		return
+	}
+}
+
 func (h *CategoryHandler) SearchCategories(w http.ResponseWriter, r *http.Request) {
 	searchTerm := r.URL.Query().Get("q")
 	if searchTerm == "" {
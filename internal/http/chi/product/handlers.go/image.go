@@ -0,0 +1,206 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rovilay/ecommerce-service/common/blobstore"
+	"github.com/rovilay/ecommerce-service/domains/product"
+)
+
+func (h *ProductHandler) ListProductImages(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.log.Println("bad ID param: ", err)
+		http.Error(w, `{"error": "failed to convert product ID param"}`, http.StatusBadRequest)
+		return
+	}
+
+	images, err := h.service.GetProductImages(r.Context(), productID)
+	if err != nil {
+		h.sendImageError(w, err, "failed to list images")
+		return
+	}
+
+	h.writeImages(w, images)
+}
+
+// UploadProductImages serves POST /products/{id}/images. The body is multipart/form-data with
+// one or more image files; each file part is read up to the size limit and sniffed, whatever
+// its declared type.
+func (h *ProductHandler) UploadProductImages(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.log.Println("bad ID param: ", err)
+		http.Error(w, `{"error": "failed to convert product ID param"}`, http.StatusBadRequest)
+		return
+	}
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, `{"error": "body must be multipart/form-data"}`, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	var uploads []product.ImageUpload
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			http.Error(w, `{"error": "failed to read multipart body"}`, http.StatusBadRequest)
+			return
+		}
+
+		// form fields other than files are ignored
+		if part.FileName() == "" {
+			part.Close()
+			continue
+		}
+
+		if len(uploads) == product.MaxProductImages {
+			part.Close()
+			h.sendImageError(w, product.ErrTooManyImages, "")
+			return
+		}
+
+		data, err := io.ReadAll(io.LimitReader(part, product.MaxImageBytes+1))
+		part.Close()
+		if err != nil {
+			http.Error(w, `{"error": "failed to read multipart body"}`, http.StatusBadRequest)
+			return
+		}
+
+		if len(data) > product.MaxImageBytes {
+			h.sendImageError(w, fmt.Errorf("%s: %w", part.FileName(), product.ErrImageTooLarge), "")
+			return
+		}
+
+		uploads = append(uploads, product.ImageUpload{Filename: part.FileName(), Data: data})
+	}
+
+	images, err := h.service.AddProductImages(r.Context(), productID, uploads)
+	if err != nil {
+		h.sendImageError(w, err, "failed to upload images")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	h.writeImages(w, images)
+}
+
+// ReorderProductImages serves PUT /products/{id}/images/order. The body lists every image ID
+// of the product in its new order.
+func (h *ProductHandler) ReorderProductImages(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.log.Println("bad ID param: ", err)
+		http.Error(w, `{"error": "failed to convert product ID param"}`, http.StatusBadRequest)
+		return
+	}
+
+	var body struct {
+		ImageIDs []int `json:"image_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, `{"error": "failed to decode request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	images, err := h.service.ReorderProductImages(r.Context(), productID, body.ImageIDs)
+	if err != nil {
+		h.sendImageError(w, err, "failed to reorder images")
+		return
+	}
+
+	h.writeImages(w, images)
+}
+
+func (h *ProductHandler) DeleteProductImage(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.log.Println("bad ID param: ", err)
+		http.Error(w, `{"error": "failed to convert product ID param"}`, http.StatusBadRequest)
+		return
+	}
+
+	imageID, err := strconv.Atoi(chi.URLParam(r, "imageID"))
+	if err != nil {
+		h.log.Println("bad image ID param: ", err)
+		http.Error(w, `{"error": "failed to convert image ID param"}`, http.StatusBadRequest)
+		return
+	}
+
+	err = h.service.DeleteProductImage(r.Context(), productID, imageID)
+	if err != nil {
+		h.sendImageError(w, err, "failed to delete image")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ServeMedia serves GET /products/media/*, the stored images and thumbnails. Blob keys are
+// never reused, so responses can be cached for good.
+func (h *ProductHandler) ServeMedia(w http.ResponseWriter, r *http.Request) {
+	blob, err := h.service.OpenMedia(r.Context(), chi.URLParam(r, "*"))
+	if errors.Is(err, blobstore.ErrNotFound) || errors.Is(err, blobstore.ErrInvalidKey) {
+		http.Error(w, `{"error": "media not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		h.log.Println("failed to open media: ", err)
+		http.Error(w, `{"error": "failed to open media"}`, http.StatusInternalServerError)
+		return
+	}
+	defer blob.Body.Close()
+
+	w.Header().Set("Content-Type", blob.ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	if blob.Size >= 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(blob.Size, 10))
+	}
+
+	if _, err := io.Copy(w, blob.Body); err != nil && !errors.Is(err, r.Context().Err()) {
+		h.log.Println("failed to send media: ", err)
+	}
+}
+
+func (h *ProductHandler) writeImages(w http.ResponseWriter, images []*product.ProductImage) {
+	var response struct {
+		Result []*product.ProductImage `json:"result"`
+	}
+
+	response.Result = images
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.log.Println("failed to marshal: ", err)
+		http.Error(w, `{"error": "failed to marshal"}`, http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *ProductHandler) sendImageError(w http.ResponseWriter, err error, msg string) {
+	// messages may carry the uploaded file name, so they are encoded rather than formatted in
+	quoted, _ := json.Marshal(err.Error())
+
+	if errors.Is(err, product.ErrNotExist) {
+		http.Error(w, `{"error": "product or image not found"}`, http.StatusNotFound)
+	} else if errors.Is(err, product.ErrImageTooLarge) {
+		http.Error(w, fmt.Sprintf(`{"error": %s}`, quoted), http.StatusRequestEntityTooLarge)
+	} else if errors.Is(err, product.ErrInvalidImage) {
+		http.Error(w, fmt.Sprintf(`{"error": %s}`, quoted), http.StatusUnsupportedMediaType)
+	} else if errors.Is(err, product.ErrTooManyImages) {
+		http.Error(w, fmt.Sprintf(`{"error": "%v (at most %d)"}`, err, product.MaxProductImages), http.StatusConflict)
+	} else if errors.Is(err, product.ErrInvalidImageOrder) {
+		http.Error(w, fmt.Sprintf(`{"error": "%v"}`, err), http.StatusBadRequest)
+	} else {
+		h.log.Println(msg+": ", err)
+		http.Error(w, fmt.Sprintf(`{"error": "%s"}`, msg), http.StatusInternalServerError)
+	}
+}
@@ -12,6 +12,7 @@ type contextKey string
 
 const PrdCTXKey contextKey = "product"
 const CategoryCTXKey contextKey = "category"
+const VariantCTXKey contextKey = "variant"
 
 func (h *ProductHandler) MiddlewareValidateProduct(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -72,3 +73,32 @@ func (h *CategoryHandler) MiddlewareValidateCategory(next http.Handler) http.Han
 		next.ServeHTTP(w, r)
 	})
 }
+
+func (h *ProductHandler) MiddlewareValidateVariant(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v := &product.Variant{}
+
+		err := v.FromJSON(r.Body)
+		if err != nil {
+			h.log.Println("[ERROR] deserializing variant", err)
+			http.Error(w, `{"error": "failed to read variant"}`, http.StatusBadRequest)
+			return
+		}
+
+		err = v.Validate()
+		if err != nil {
+			h.log.Println("[ERROR] validating variant", err)
+			http.Error(
+				w, fmt.Sprintf(`{"error": "Error valdating variant: %s"}`, err),
+				http.StatusBadRequest,
+			)
+			return
+		}
+
+		// add validated data
+		ctx := context.WithValue(r.Context(), VariantCTXKey, v)
+		r = r.WithContext(ctx)
+
+		next.ServeHTTP(w, r)
+	})
+}
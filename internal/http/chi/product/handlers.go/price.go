@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rovilay/ecommerce-service/domains/product"
+)
+
+// ListProductPrices serves GET /products/{id}/prices, the price history of a product with its
+// scheduled prices.
+func (h *ProductHandler) ListProductPrices(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.log.Println("bad ID param: ", err)
+		http.Error(w, `{"error": "failed to convert product ID param"}`, http.StatusBadRequest)
+		return
+	}
+
+	prices, err := h.service.GetProductPrices(r.Context(), productID)
+	if err != nil {
+		h.sendPriceError(w, err, "failed to list prices")
+		return
+	}
+
+	var response struct {
+		Result []*product.ProductPrice `json:"result"`
+	}
+
+	response.Result = prices
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.log.Println("failed to marshal: ", err)
+		http.Error(w, `{"error": "failed to marshal"}`, http.StatusInternalServerError)
+		return
+	}
+}
+
+// SchedulePrice serves POST /products/{id}/prices. The body has the price and optionally
+// starts_at and, for a sale, ends_at.
+func (h *ProductHandler) SchedulePrice(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.log.Println("bad ID param: ", err)
+		http.Error(w, `{"error": "failed to convert product ID param"}`, http.StatusBadRequest)
+		return
+	}
+
+	data := &product.ProductPrice{}
+	if err := data.FromJSON(r.Body); err != nil {
+		h.log.Println("[ERROR] deserializing price", err)
+		http.Error(w, `{"error": "failed to read price"}`, http.StatusBadRequest)
+		return
+	}
+
+	if err := data.Validate(); err != nil {
+		http.Error(w, fmt.Sprintf(`{"error": "Error valdating price: %s"}`, err), http.StatusBadRequest)
+		return
+	}
+
+	p, err := h.service.SchedulePrice(r.Context(), productID, data)
+	if err != nil {
+		h.sendPriceError(w, err, "failed to schedule price")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+
+	if err := p.ToJSON(w); err != nil {
+		h.log.Println("failed to marshal: ", err)
+		http.Error(w, `{"error": "failed to marshal"}`, http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *ProductHandler) CancelPrice(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.log.Println("bad ID param: ", err)
+		http.Error(w, `{"error": "failed to convert product ID param"}`, http.StatusBadRequest)
+		return
+	}
+
+	priceID, err := strconv.Atoi(chi.URLParam(r, "priceID"))
+	if err != nil {
+		h.log.Println("bad price ID param: ", err)
+		http.Error(w, `{"error": "failed to convert price ID param"}`, http.StatusBadRequest)
+		return
+	}
+
+	err = h.service.CancelPrice(r.Context(), productID, priceID)
+	if err != nil {
+		h.sendPriceError(w, err, "failed to cancel price")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ProductHandler) sendPriceError(w http.ResponseWriter, err error, msg string) {
+	if errors.Is(err, product.ErrNotExist) {
+		http.Error(w, `{"error": "product or price not found"}`, http.StatusNotFound)
+	} else if errors.Is(err, product.ErrPriceCurrency) || errors.Is(err, product.ErrPriceInPast) ||
+		errors.Is(err, product.ErrInvalidPriceWindow) {
+		http.Error(w, fmt.Sprintf(`{"error": "%v"}`, err), http.StatusBadRequest)
+	} else if errors.Is(err, product.ErrPriceStarted) {
+		http.Error(w, fmt.Sprintf(`{"error": "%v"}`, err), http.StatusConflict)
+	} else {
+		h.log.Println(msg+": ", err)
+		http.Error(w, fmt.Sprintf(`{"error": "%s"}`, msg), http.StatusInternalServerError)
+	}
+}
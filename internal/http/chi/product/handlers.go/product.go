@@ -3,10 +3,13 @@ package handler
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/rovilay/ecommerce-service/common/money"
 	"github.com/rovilay/ecommerce-service/domains/product"
 )
 
@@ -36,19 +39,22 @@ func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *ProductHandler) ListProducts(w http.ResponseWriter, r *http.Request) {
-	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
-	if err != nil {
-		h.log.Err(err)
-		limit = 50
+	if ids := r.URL.Query().Get("ids"); ids != "" {
+		h.lookupProducts(w, r, ids)
+		return
 	}
-	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+
+	q, err := parseProductListQuery(r)
 	if err != nil {
-		h.log.Err(err)
-		offset = 0
+		http.Error(w, fmt.Sprintf(`{"error": "%v"}`, err), http.StatusBadRequest)
+		return
 	}
 
-	res, err := h.service.ListProducts(r.Context(), limit, offset)
-	if err != nil {
+	res, err := h.service.ListProducts(r.Context(), q)
+	if errors.Is(err, product.ErrInvalidCursor) || errors.Is(err, product.ErrInvalidSort) {
+		http.Error(w, fmt.Sprintf(`{"error": "%v"}`, err), http.StatusBadRequest)
+		return
+	} else if err != nil {
 		h.log.Println("failed to list products: ", err)
 		http.Error(w, `{"error": "failed to list products"}`, http.StatusInternalServerError)
 		return
@@ -61,11 +67,130 @@ func (h *ProductHandler) ListProducts(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+const maxListLimit = 100
+
+// parseProductListQuery reads the filters, sorting and pagination of GET /products.
+func parseProductListQuery(r *http.Request) (product.ProductListQuery, error) {
+	params := r.URL.Query()
+	q := product.ProductListQuery{
+		Sort:   product.ProductSort(params.Get("sort")),
+		Limit:  50,
+		Cursor: params.Get("cursor"),
+	}
+
+	if limit, err := strconv.Atoi(params.Get("limit")); err == nil && limit > 0 {
+		q.Limit = min(limit, maxListLimit)
+	}
+
+	if offset, err := strconv.Atoi(params.Get("offset")); err == nil && offset > 0 {
+		q.Offset = offset
+	}
+
+	if q.Sort == "" {
+		q.Sort = product.SortByCreatedAt
+	} else if !q.Sort.IsValid() {
+		return q, product.ErrInvalidSort
+	}
+
+	switch params.Get("order") {
+	case "", "asc":
+	case "desc":
+		q.Descending = true
+	default:
+		return q, errors.New("order must be asc or desc")
+	}
+
+	if rawIDs := params.Get("category_id"); rawIDs != "" {
+		for _, rawID := range strings.Split(rawIDs, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(rawID))
+			if err != nil {
+				return q, errors.New("failed to convert category_id param")
+			}
+			q.Filter.CategoryIDs = append(q.Filter.CategoryIDs, id)
+		}
+
+		q.Filter.IncludeDescendants = true
+		if raw := params.Get("include_descendants"); raw != "" {
+			include, err := strconv.ParseBool(raw)
+			if err != nil {
+				return q, errors.New("include_descendants must be true or false")
+			}
+			q.Filter.IncludeDescendants = include
+		}
+	}
+
+	q.Filter.Currency = strings.ToUpper(params.Get("currency"))
+	currency := q.Filter.Currency
+	if currency == "" {
+		currency = money.DefaultCurrency
+	}
+
+	for param, dst := range map[string]**money.Money{"min_price": &q.Filter.MinPrice, "max_price": &q.Filter.MaxPrice} {
+		if raw := params.Get(param); raw != "" {
+			price, err := money.Parse(raw, currency)
+			if err != nil {
+				return q, fmt.Errorf("invalid %s param", param)
+			}
+			*dst = &price
+		}
+	}
+
+	q.Filter.SKUPrefix = params.Get("sku")
+
+	if raw := params.Get("in_stock"); raw != "" {
+		inStock, err := strconv.ParseBool(raw)
+		if err != nil {
+			return q, errors.New("in_stock must be true or false")
+		}
+		q.Filter.InStock = &inStock
+	}
+
+	return q, nil
+}
+
+// lookupProducts serves GET /products?ids=1,2,3, including deleted products.
+func (h *ProductHandler) lookupProducts(w http.ResponseWriter, r *http.Request, rawIDs string) {
+	var ids []int
+	for _, rawID := range strings.Split(rawIDs, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(rawID))
+		if err != nil {
+			h.log.Println("bad ids param: ", err)
+			http.Error(w, `{"error": "failed to convert ids param"}`, http.StatusBadRequest)
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	prds, err := h.service.LookupProducts(r.Context(), ids)
+	if errors.Is(err, product.ErrTooManyIDs) {
+		http.Error(w, fmt.Sprintf(`{"error": "%v"}`, err), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		h.log.Println("failed to look up products: ", err)
+		http.Error(w, `{"error": "failed to look up products"}`, http.StatusInternalServerError)
+		return
+	}
+
+	var res struct {
+		Items []*product.ProductSummary `json:"items"`
+	}
+	res.Items = prds
+
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		h.log.Println("failed to marshal: ", err)
+		http.Error(w, `{"error": "failed to marshal"}`, http.StatusInternalServerError)
+		return
+	}
+}
+
 func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 	data := r.Context().Value(PrdCTXKey).(*product.Product)
 
 	newPrd, err := h.service.CreateProduct(r.Context(), data)
-	if err != nil {
+	if errors.Is(err, product.ErrDuplicateVariant) {
+		http.Error(w, fmt.Sprintf(`{"error": "%v"}`, err), http.StatusConflict)
+		return
+	} else if err != nil {
 		h.log.Println("failed to create product: ", err)
 		http.Error(w, `{"error": "failed to create product"}`, http.StatusInternalServerError)
 		return
@@ -126,26 +251,87 @@ func (h *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// ListDeletedProducts serves GET /products/trash, the soft-deleted products that can still be
+// restored.
+func (h *ProductHandler) ListDeletedProducts(w http.ResponseWriter, r *http.Request) {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 50
+	}
+	limit = min(limit, maxListLimit)
+
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	res, err := h.service.ListDeletedProducts(r.Context(), limit, offset)
+	if err != nil {
+		h.log.Println("failed to list deleted products: ", err)
+		http.Error(w, `{"error": "failed to list deleted products"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		h.log.Println("failed to marshal: ", err)
+		http.Error(w, `{"error": "failed to marshal"}`, http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *ProductHandler) RestoreProduct(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.log.Println("bad ID param: ", err)
+		http.Error(w, `{"error": "failed to convert product ID param"}`, http.StatusBadRequest)
+		return
+	}
+
+	p, err := h.service.RestoreProduct(r.Context(), productID)
+	if errors.Is(err, product.ErrNotExist) {
+		http.Error(w, `{"error": "product not found"}`, http.StatusNotFound)
+		return
+	} else if errors.Is(err, product.ErrNotDeleted) {
+		http.Error(w, fmt.Sprintf(`{"error": "%v"}`, err), http.StatusConflict)
+		return
+	} else if err != nil {
+		h.log.Println("failed to restore product: ", err)
+		http.Error(w, `{"error": "failed to restore product"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if err := p.ToJSON(w); err != nil {
+		h.log.Println("failed to marshal: ", err)
+		http.Error(w, `{"error": "failed to marshal"}`, http.StatusInternalServerError)
+		return
+	}
+}
+
 func (h *ProductHandler) SearchProducts(w http.ResponseWriter, r *http.Request) {
 	searchTerm := r.URL.Query().Get("q")
 	if searchTerm == "" {
 		http.Error(w, `{"error": "search term empty"}`, http.StatusBadRequest)
 		return
 	}
-	res, err := h.service.SearchProductsByName(r.Context(), searchTerm)
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+	limit = min(limit, maxListLimit)
+
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	res, err := h.service.SearchProducts(r.Context(), searchTerm, limit, offset)
 	if err != nil {
 		h.log.Println("product search failed: ", err)
 		http.Error(w, `{"error": "product search failed"}`, http.StatusInternalServerError)
 		return
 	}
 
-	var response struct {
-		Result []*product.Product `json:"result"`
-	}
-
-	response.Result = res
-
-	if err := json.NewEncoder(w).Encode(response); err != nil {
+	if err := json.NewEncoder(w).Encode(res); err != nil {
 		h.log.Println("failed to marshal: ", err)
 		http.Error(w, `{"error": "failed to marshal"}`, http.StatusInternalServerError)
 		return
@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rovilay/ecommerce-service/domains/product"
+)
+
+func (h *ProductHandler) ListVariants(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.log.Println("bad ID param: ", err)
+		http.Error(w, `{"error": "failed to convert product ID param"}`, http.StatusBadRequest)
+		return
+	}
+
+	variants, err := h.service.GetProductVariants(r.Context(), productID)
+	if errors.Is(err, product.ErrNotExist) {
+		http.Error(w, `{"error": "product not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		h.log.Println("failed to list variants: ", err)
+		http.Error(w, `{"error": "failed to list variants"}`, http.StatusInternalServerError)
+		return
+	}
+
+	var response struct {
+		Result []*product.Variant `json:"result"`
+	}
+
+	response.Result = variants
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.log.Println("failed to marshal: ", err)
+		http.Error(w, `{"error": "failed to marshal"}`, http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *ProductHandler) CreateVariant(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.log.Println("bad ID param: ", err)
+		http.Error(w, `{"error": "failed to convert product ID param"}`, http.StatusBadRequest)
+		return
+	}
+
+	data := r.Context().Value(VariantCTXKey).(*product.Variant)
+
+	v, err := h.service.CreateVariant(r.Context(), productID, data)
+	if err != nil {
+		h.sendVariantError(w, err, "failed to create variant")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+
+	if err := v.ToJSON(w); err != nil {
+		h.log.Println("failed to marshal: ", err)
+		http.Error(w, `{"error": "failed to marshal"}`, http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *ProductHandler) UpdateVariant(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.log.Println("bad ID param: ", err)
+		http.Error(w, `{"error": "failed to convert product ID param"}`, http.StatusBadRequest)
+		return
+	}
+
+	variantID, err := strconv.Atoi(chi.URLParam(r, "variantID"))
+	if err != nil {
+		h.log.Println("bad variant ID param: ", err)
+		http.Error(w, `{"error": "failed to convert variant ID param"}`, http.StatusBadRequest)
+		return
+	}
+
+	data := r.Context().Value(VariantCTXKey).(*product.Variant)
+
+	v, err := h.service.UpdateVariant(r.Context(), productID, variantID, data)
+	if err != nil {
+		h.sendVariantError(w, err, "failed to update variant")
+		return
+	}
+
+	if err := v.ToJSON(w); err != nil {
+		h.log.Println("failed to marshal: ", err)
+		http.Error(w, `{"error": "failed to marshal"}`, http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *ProductHandler) DeleteVariant(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.log.Println("bad ID param: ", err)
+		http.Error(w, `{"error": "failed to convert product ID param"}`, http.StatusBadRequest)
+		return
+	}
+
+	variantID, err := strconv.Atoi(chi.URLParam(r, "variantID"))
+	if err != nil {
+		h.log.Println("bad variant ID param: ", err)
+		http.Error(w, `{"error": "failed to convert variant ID param"}`, http.StatusBadRequest)
+		return
+	}
+
+	err = h.service.DeleteVariant(r.Context(), productID, variantID)
+	if err != nil {
+		h.sendVariantError(w, err, "failed to delete variant")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ProductHandler) sendVariantError(w http.ResponseWriter, err error, msg string) {
+	if errors.Is(err, product.ErrNotExist) {
+		http.Error(w, `{"error": "product or variant not found"}`, http.StatusNotFound)
+	} else if errors.Is(err, product.ErrVariantCurrency) {
+		http.Error(w, fmt.Sprintf(`{"error": "%v"}`, err), http.StatusBadRequest)
+	} else if errors.Is(err, product.ErrDuplicateVariant) {
+		http.Error(w, fmt.Sprintf(`{"error": "%v"}`, err), http.StatusConflict)
+	} else {
+		h.log.Println(msg+": ", err)
+		http.Error(w, fmt.Sprintf(`{"error": "%s"}`, msg), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,46 @@
+package product
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/rovilay/ecommerce-service/common/utils"
+)
+
+// MiddlewareRequireRoles only lets requests through whose bearer token carries one of roles.
+func (a *ProductApp) MiddlewareRequireRoles(roles ...string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authString := r.Header.Get("Authorization")
+			if authString == "" {
+				sendAuthError(w, utils.ErrMissingAuthToken, http.StatusUnauthorized)
+				return
+			}
+
+			tokenString, err := utils.ExtractToken(authString)
+			if err != nil {
+				sendAuthError(w, err, http.StatusUnauthorized)
+				return
+			}
+
+			claims, err := a.auth.ValidateJWT(r.Context(), tokenString)
+			if err != nil {
+				a.log.Err(err).Msg("error validating token")
+				sendAuthError(w, utils.ErrInvalidAuthToken, http.StatusUnauthorized)
+				return
+			}
+
+			if !claims.HasRole(roles...) {
+				sendAuthError(w, utils.ErrForbidden, http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func sendAuthError(w http.ResponseWriter, err error, statusCode int) {
+	errRes := fmt.Sprintf(`{"error": "%v"}`, err.Error())
+	http.Error(w, errRes, statusCode)
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rovilay/ecommerce-service/common/utils"
 	handler "github.com/rovilay/ecommerce-service/internal/http/chi/product/handlers.go"
 	"github.com/rs/cors"
 )
@@ -48,28 +49,58 @@ func (a *ProductApp) loadProductRoutes(router chi.Router) {
 
 	router.Get("/", prdHandler.ListProducts)
 	router.Get("/{id}", prdHandler.GetProduct)
+	router.Get("/{id}/variants", prdHandler.ListVariants)
+	router.Get("/{id}/images", prdHandler.ListProductImages)
+	router.Get("/search", prdHandler.SearchProducts)
+	router.Get("/media/*", prdHandler.ServeMedia)
 
 	router.Group(func(r chi.Router) {
-		r.Use(prdHandler.MiddlewareValidateProduct)
-		r.Post("/", prdHandler.CreateProduct)
-		r.Put("/{id}", prdHandler.UpdateProduct)
-	})
+		r.Use(a.MiddlewareRequireRoles(utils.RoleAdmin))
 
-	router.Delete("/{id}", prdHandler.DeleteProduct)
-	router.Get("/search", prdHandler.SearchProducts)
+		r.Group(func(r chi.Router) {
+			r.Use(prdHandler.MiddlewareValidateProduct)
+			r.Post("/", prdHandler.CreateProduct)
+			r.Put("/{id}", prdHandler.UpdateProduct)
+		})
+
+		r.Delete("/{id}", prdHandler.DeleteProduct)
+		r.Get("/trash", prdHandler.ListDeletedProducts)
+		r.Post("/{id}/restore", prdHandler.RestoreProduct)
+
+		r.Post("/import", prdHandler.ImportProducts)
+		r.Get("/export", prdHandler.ExportProducts)
+
+		r.With(prdHandler.MiddlewareValidateVariant).Post("/{id}/variants", prdHandler.CreateVariant)
+		r.With(prdHandler.MiddlewareValidateVariant).Put("/{id}/variants/{variantID}", prdHandler.UpdateVariant)
+		r.Delete("/{id}/variants/{variantID}", prdHandler.DeleteVariant)
+
+		r.Post("/{id}/images", prdHandler.UploadProductImages)
+		r.Put("/{id}/images/order", prdHandler.ReorderProductImages)
+		r.Delete("/{id}/images/{imageID}", prdHandler.DeleteProductImage)
+
+		r.Get("/{id}/prices", prdHandler.ListProductPrices)
+		r.Post("/{id}/prices", prdHandler.SchedulePrice)
+		r.Delete("/{id}/prices/{priceID}", prdHandler.CancelPrice)
+	})
 }
 
 func (a *ProductApp) loadCategoryRoutes(router chi.Router) {
 	prdHandler := handler.NewCategoryHandler(a.service, a.log)
 
 	router.Get("/", prdHandler.ListCategories)
+	router.Get("/tree", prdHandler.GetCategoryTree)
 	router.Get("/{id}", prdHandler.GetCategory)
+	router.Get("/search", prdHandler.SearchCategories)
 
 	router.Group(func(r chi.Router) {
-		r.Use(prdHandler.MiddlewareValidateCategory)
-		r.Post("/", prdHandler.CreateCategory)
-		r.Put("/{id}", prdHandler.UpdateCategory)
-	})
+		r.Use(a.MiddlewareRequireRoles(utils.RoleAdmin))
 
-	router.Get("/search", prdHandler.SearchCategories)
+		r.Group(func(r chi.Router) {
+			r.Use(prdHandler.MiddlewareValidateCategory)
+			r.Post("/", prdHandler.CreateCategory)
+			r.Put("/{id}", prdHandler.UpdateCategory)
+		})
+
+		r.Put("/{id}/parent", prdHandler.MoveCategory)
+	})
 }